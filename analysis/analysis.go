@@ -0,0 +1,345 @@
+// Package analysis serves position analysis over HTTP.
+//
+// Clients POST a [Request] as JSON to a [Server] and receive a [Response]
+// with the best move and principal variations. Clients that accept
+// text/event-stream instead receive Server-Sent Events: an "info" event with
+// a [Line] for each update as the search deepens, then a "result" event with
+// the [Response], or an "error" event.
+//
+// Searches run on a bounded pool of UCI engine processes, and stop when the
+// client goes away.
+package analysis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"mime"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/clfs/lento/core"
+	"github.com/clfs/lento/encoding/fen"
+	"github.com/clfs/lento/encoding/san"
+	"github.com/clfs/lento/uci"
+)
+
+// A Config configures a [Server].
+type Config struct {
+	// Path is the UCI engine executable.
+	Path string
+	// Args are passed to the engine executable.
+	Args []string
+	// Options are set with "setoption" when an engine starts.
+	Options map[string]string
+	// Engines is the number of engine processes, and so the number of
+	// searches that can run at once. Further requests wait for an engine.
+	Engines int
+	// MoveTime is the search time for requests without limits.
+	MoveTime time.Duration
+	// MaxMoveTime, if positive, bounds the search time of every request.
+	MaxMoveTime time.Duration
+	// MaxMultiPV, if positive, bounds the number of lines per request.
+	MaxMultiPV int
+}
+
+// A Request asks for analysis of a position. At least one of Depth, Nodes and
+// MoveTime should be set; otherwise, the server's default search time is
+// used.
+type Request struct {
+	// FEN is the position to analyze.
+	FEN      string `json:"fen"`
+	Depth    int    `json:"depth,omitempty"`
+	Nodes    int    `json:"nodes,omitempty"`
+	MoveTime int    `json:"movetime,omitempty"` // in milliseconds
+	// MultiPV is the number of lines to report. The default is 1.
+	MultiPV int `json:"multipv,omitempty"`
+}
+
+// A Score is an evaluation from the side to move's point of view. Exactly one
+// field is set.
+type Score struct {
+	CP   *int `json:"cp,omitempty"`   // in centipawns
+	Mate *int `json:"mate,omitempty"` // in moves; negative if getting mated
+}
+
+// A Line is one principal variation.
+type Line struct {
+	MultiPV int   `json:"multipv"`
+	Depth   int   `json:"depth"`
+	Score   Score `json:"score"`
+	Nodes   int   `json:"nodes,omitempty"`
+	Time    int   `json:"time,omitempty"` // in milliseconds
+	// PV is the variation in UCI notation, and SAN in SAN.
+	PV  []string `json:"pv"`
+	SAN []string `json:"san"`
+}
+
+// A Response is the result of a search.
+type Response struct {
+	FEN string `json:"fen"`
+	// BestMove is the move to play in UCI notation, or empty if there are no
+	// legal moves.
+	BestMove string `json:"bestmove"`
+	Ponder   string `json:"ponder,omitempty"`
+	// Lines are the final principal variations, best first.
+	Lines []Line `json:"lines"`
+}
+
+// A Server is an [http.Handler] that serves analysis.
+type Server struct {
+	cfg  Config
+	pool *pool
+}
+
+// NewServer returns a new server. Engines are started when first needed.
+func NewServer(cfg Config) *Server {
+	if cfg.MoveTime <= 0 {
+		cfg.MoveTime = time.Second
+	}
+	return &Server{cfg: cfg, pool: newPool(cfg)}
+}
+
+// Close stops the server's engines. Searches in progress finish first.
+func (s *Server) Close() {
+	s.pool.close()
+}
+
+// An httpError is an error with an HTTP status.
+type httpError struct {
+	status int
+	err    error
+}
+
+func (e *httpError) Error() string {
+	return e.err.Error()
+}
+
+func badRequest(format string, args ...any) error {
+	return &httpError{http.StatusBadRequest, fmt.Errorf(format, args...)}
+}
+
+// acceptsEventStream returns true if the Accept header values list
+// text/event-stream, without a zero quality. Malformed media types are
+// ignored.
+func acceptsEventStream(accept []string) bool {
+	for _, v := range accept {
+		for _, t := range strings.Split(v, ",") {
+			mediaType, params, err := mime.ParseMediaType(t)
+			if err == nil && mediaType == "text/event-stream" && !isZero(params["q"]) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// isZero returns true if q is a zero quality value.
+func isZero(q string) bool {
+	f, err := strconv.ParseFloat(q, 64)
+	return err == nil && f == 0
+}
+
+// ServeHTTP serves a [Request].
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeError(w, &httpError{http.StatusMethodNotAllowed, errors.New("method not allowed")})
+		return
+	}
+
+	var req Request
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<16)).Decode(&req); err != nil {
+		writeError(w, badRequest("bad request: %v", err))
+		return
+	}
+
+	stream := acceptsEventStream(r.Header.Values("Accept"))
+	var flusher http.Flusher
+	if stream {
+		var ok bool
+		if flusher, ok = w.(http.Flusher); !ok {
+			stream = false
+		}
+	}
+
+	if !stream {
+		res, err := s.analyze(r.Context(), req, nil)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(res)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	send := func(event string, v any) {
+		data, _ := json.Marshal(v)
+		fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, data)
+		flusher.Flush()
+	}
+	res, err := s.analyze(r.Context(), req, func(l Line) {
+		send("info", l)
+	})
+	if err != nil {
+		send("error", errorBody{err.Error()})
+		return
+	}
+	send("result", res)
+}
+
+type errorBody struct {
+	Error string `json:"error"`
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	var he *httpError
+	switch {
+	case errors.As(err, &he):
+		status = he.status
+	case errors.Is(err, errClosed):
+		status = http.StatusServiceUnavailable
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(errorBody{err.Error()})
+}
+
+// limits returns the search limits for req.
+func (s *Server) limits(req Request) (uci.Limits, error) {
+	if req.Depth < 0 || req.Nodes < 0 || req.MoveTime < 0 {
+		return uci.Limits{}, badRequest("negative limit")
+	}
+	l := uci.Limits{
+		Depth:    req.Depth,
+		Nodes:    req.Nodes,
+		MoveTime: time.Duration(req.MoveTime) * time.Millisecond,
+	}
+	if l.Depth == 0 && l.Nodes == 0 && l.MoveTime == 0 {
+		l.MoveTime = s.cfg.MoveTime
+	}
+	if limit := s.cfg.MaxMoveTime; limit > 0 && (l.MoveTime == 0 || l.MoveTime > limit) {
+		l.MoveTime = limit
+	}
+	return l, nil
+}
+
+// analyze runs a search, calling update, if not nil, for each new line.
+func (s *Server) analyze(ctx context.Context, req Request, update func(Line)) (Response, error) {
+	p, err := fen.Decode(req.FEN)
+	if err != nil {
+		return Response{}, badRequest("bad fen: %v", err)
+	}
+	l, err := s.limits(req)
+	if err != nil {
+		return Response{}, err
+	}
+	multiPV := max(req.MultiPV, 1)
+	if s.cfg.MaxMultiPV > 0 && multiPV > s.cfg.MaxMultiPV {
+		return Response{}, badRequest("multipv over %d", s.cfg.MaxMultiPV)
+	}
+
+	c, err := s.pool.get(ctx)
+	if err != nil {
+		return Response{}, err
+	}
+	failed := true
+	defer func() { s.pool.put(c, failed) }()
+
+	if _, ok := c.Option("MultiPV"); ok {
+		if err := c.SetOption("MultiPV", strconv.Itoa(multiPV)); err != nil {
+			failed = false
+			return Response{}, badRequest("%v", err)
+		}
+	} else if multiPV > 1 {
+		failed = false
+		return Response{}, badRequest("engine doesn't support multipv")
+	}
+
+	if err := c.SetPosition(p, nil); err != nil {
+		return Response{}, err
+	}
+	search, err := c.Go(ctx, l)
+	if err != nil {
+		return Response{}, err
+	}
+
+	lines := make(map[int]Line)
+	for info := range search.Info {
+		line, ok := newLine(p, info)
+		if !ok || line.MultiPV > multiPV {
+			continue
+		}
+		lines[line.MultiPV] = line
+		if update != nil {
+			update(line)
+		}
+	}
+	res, err := search.Wait()
+	if err != nil {
+		return Response{}, err
+	}
+	failed = false
+
+	if err := ctx.Err(); err != nil {
+		return Response{}, err
+	}
+
+	resp := Response{FEN: fen.Encode(p), Lines: []Line{}}
+	if res.BestMove != (core.Move{}) {
+		resp.BestMove = res.BestMove.String()
+	}
+	if res.Ponder != (core.Move{}) {
+		resp.Ponder = res.Ponder.String()
+	}
+	for _, n := range slices.Sorted(maps.Keys(lines)) {
+		resp.Lines = append(resp.Lines, lines[n])
+	}
+	return resp, nil
+}
+
+// newLine converts info to a line, returning false if info doesn't describe a
+// principal variation. The variation is cut short at the first illegal move.
+func newLine(p core.Position, info uci.Info) (Line, bool) {
+	if info.Score == nil || len(info.PV) == 0 {
+		return Line{}, false
+	}
+
+	l := Line{
+		MultiPV: max(info.MultiPV, 1),
+		Depth:   info.Depth,
+		Nodes:   info.Nodes,
+		Time:    int(info.Time.Milliseconds()),
+		PV:      []string{},
+		SAN:     []string{},
+	}
+	if info.Score.Mate != 0 {
+		mate := info.Score.Mate
+		l.Score.Mate = &mate
+	} else {
+		cp := info.Score.CP
+		l.Score.CP = &cp
+	}
+
+	for _, m := range info.PV {
+		if !p.IsLegal(m) {
+			break
+		}
+		l.PV = append(l.PV, m.String())
+		l.SAN = append(l.SAN, san.Encode(p, m))
+		p.Move(m)
+	}
+	return l, len(l.PV) > 0
+}
@@ -0,0 +1,352 @@
+package analysis
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/clfs/lento/core"
+	"github.com/clfs/lento/encoding/fen"
+)
+
+// If LENTO_FAKE_ENGINE is set, the test binary acts as a UCI engine instead,
+// with its behavior chosen by its first argument.
+func TestMain(m *testing.M) {
+	if os.Getenv("LENTO_FAKE_ENGINE") != "" {
+		fakeEngine(os.Args[1])
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// fakeEngine searches to depth 3, reporting the alphabetically first legal
+// moves as its lines, with scores decreasing by 10 per line. Searches with a
+// movetime of a minute or more run until stopped. Modes:
+//
+//   - "multipv" supports the MultiPV option.
+//   - "single" doesn't.
+func fakeEngine(mode string) {
+	var (
+		p       = core.NewPosition()
+		multiPV = 1
+		stop    = make(chan struct{}, 1)
+		done    = make(chan struct{})
+	)
+	close(done)
+
+	s := bufio.NewScanner(os.Stdin)
+	for s.Scan() {
+		fields := strings.Fields(s.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		switch fields[0] {
+		case "uci":
+			fmt.Printf("id name %s\n", mode)
+			if mode == "multipv" {
+				fmt.Println("option name MultiPV type spin default 1 min 1 max 500")
+			}
+			fmt.Println("uciok")
+		case "isready":
+			fmt.Println("readyok")
+		case "setoption":
+			fmt.Sscanf(s.Text(), "setoption name MultiPV value %d", &multiPV)
+		case "position":
+			p = core.NewPosition()
+			if fields[1] == "fen" {
+				p = fen.MustDecode(strings.Join(fields[2:8], " "))
+			}
+		case "go":
+			var movetime int
+			if i := slices.Index(fields, "movetime"); i > 0 {
+				fmt.Sscan(fields[i+1], &movetime)
+			}
+			done = make(chan struct{})
+			go fakeSearch(p, multiPV, movetime >= 60000, stop, done)
+		case "stop":
+			select {
+			case stop <- struct{}{}:
+			default:
+			}
+		case "quit":
+			<-done
+			return
+		}
+	}
+}
+
+func fakeSearch(p core.Position, multiPV int, wait bool, stop <-chan struct{}, done chan<- struct{}) {
+	defer close(done)
+
+	moves := p.LegalMoves()
+	slices.SortFunc(moves, func(a, b core.Move) int {
+		return strings.Compare(a.String(), b.String())
+	})
+	for depth := 1; depth <= 3; depth++ {
+		for i, m := range moves[:min(multiPV, len(moves))] {
+			fmt.Printf("info depth %d multipv %d score cp %d nodes %d time %d pv %v\n",
+				depth, i+1, 100-10*(i+1), 1000*depth, depth, m)
+		}
+	}
+	if wait {
+		<-stop
+	}
+	if len(moves) == 0 {
+		fmt.Println("bestmove (none)")
+		return
+	}
+	fmt.Printf("bestmove %v\n", moves[0])
+}
+
+func newTestServer(t *testing.T, mode string, cfg Config) *httptest.Server {
+	t.Helper()
+	t.Setenv("LENTO_FAKE_ENGINE", "1")
+
+	cfg.Path, cfg.Args = os.Args[0], []string{mode}
+	s := NewServer(cfg)
+	ts := httptest.NewServer(s)
+	t.Cleanup(func() {
+		ts.Close()
+		s.Close()
+	})
+	return ts
+}
+
+func post(t *testing.T, ctx context.Context, url string, body any, accept string) (*http.Response, error) {
+	t.Helper()
+	data, err := json.Marshal(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	return http.DefaultClient.Do(req)
+}
+
+const startFEN = "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1"
+
+func TestServer_JSON(t *testing.T) {
+	ts := newTestServer(t, "multipv", Config{})
+
+	resp, err := post(t, context.Background(), ts.URL, Request{FEN: startFEN, Depth: 3, MultiPV: 2}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("got status %d", resp.StatusCode)
+	}
+
+	var got Response
+	if err := json.NewDecoder(resp.Body).Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+
+	if got.FEN != startFEN || got.BestMove != "a2a3" || got.Ponder != "" {
+		t.Errorf("bad response: %+v", got)
+	}
+	if len(got.Lines) != 2 {
+		t.Fatalf("want 2 lines, got %d", len(got.Lines))
+	}
+	for i, want := range []struct {
+		cp       int
+		pv, san  string
+		multiPV  int
+		depth    int
+		nodes    int
+		timeInMS int
+	}{
+		{90, "a2a3", "a3", 1, 3, 3000, 3},
+		{80, "a2a4", "a4", 2, 3, 3000, 3},
+	} {
+		l := got.Lines[i]
+		if l.Score.CP == nil || *l.Score.CP != want.cp || l.Score.Mate != nil ||
+			!slices.Equal(l.PV, []string{want.pv}) || !slices.Equal(l.SAN, []string{want.san}) ||
+			l.MultiPV != want.multiPV || l.Depth != want.depth || l.Nodes != want.nodes || l.Time != want.timeInMS {
+			t.Errorf("line %d: bad line: %+v", i, l)
+		}
+	}
+}
+
+func TestServer_SSE(t *testing.T) {
+	ts := newTestServer(t, "single", Config{})
+
+	resp, err := post(t, context.Background(), ts.URL, Request{FEN: startFEN, Depth: 3}, "text/event-stream")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("got content type %q", ct)
+	}
+
+	var events []string
+	var result Response
+	s := bufio.NewScanner(resp.Body)
+	for s.Scan() {
+		event, ok := strings.CutPrefix(s.Text(), "event: ")
+		if !ok {
+			continue
+		}
+		events = append(events, event)
+		s.Scan()
+		data, _ := strings.CutPrefix(s.Text(), "data: ")
+		switch event {
+		case "info":
+			var l Line
+			if err := json.Unmarshal([]byte(data), &l); err != nil {
+				t.Fatal(err)
+			}
+			if l.Depth != len(events) {
+				t.Errorf("want depth %d, got %d", len(events), l.Depth)
+			}
+		case "result":
+			if err := json.Unmarshal([]byte(data), &result); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	if want := []string{"info", "info", "info", "result"}; !slices.Equal(want, events) {
+		t.Errorf("want events %q, got %q", want, events)
+	}
+	if result.BestMove != "a2a3" {
+		t.Errorf("want best move a2a3, got %q", result.BestMove)
+	}
+}
+
+func TestServer_Accept(t *testing.T) {
+	ts := newTestServer(t, "single", Config{})
+
+	for accept, want := range map[string]string{
+		"text/event-stream, */*":                    "text/event-stream",
+		"text/event-stream;q=1":                     "text/event-stream",
+		"application/json, text/event-stream;q=0.5": "text/event-stream",
+		"text/event-stream;q=0":                     "application/json",
+		"*/*":                                       "application/json",
+		"":                                          "application/json",
+	} {
+		resp, err := post(t, context.Background(), ts.URL, Request{FEN: startFEN, Depth: 1}, accept)
+		if err != nil {
+			t.Fatal(err)
+		}
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+		if ct := resp.Header.Get("Content-Type"); ct != want {
+			t.Errorf("%q: want content type %q, got %q", accept, want, ct)
+		}
+	}
+}
+
+func TestServer_NoMoves(t *testing.T) {
+	ts := newTestServer(t, "single", Config{})
+
+	resp, err := post(t, context.Background(), ts.URL, Request{FEN: "7k/6Q1/6K1/8/8/8/8/8 b - - 0 1", Depth: 1}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	var got Response
+	if err := json.NewDecoder(resp.Body).Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	if got.BestMove != "" || len(got.Lines) != 0 {
+		t.Errorf("bad response: %+v", got)
+	}
+}
+
+func TestServer_Errors(t *testing.T) {
+	ts := newTestServer(t, "single", Config{MaxMultiPV: 4})
+
+	resp, err := http.Get(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("GET: got status %d", resp.StatusCode)
+	}
+
+	resp, err = http.Post(ts.URL, "application/json", strings.NewReader("{"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("bad JSON: got status %d", resp.StatusCode)
+	}
+
+	for _, req := range []Request{
+		{FEN: "bad"},
+		{FEN: startFEN, Depth: -1},
+		{FEN: startFEN, MultiPV: 5},
+		{FEN: startFEN, MultiPV: 2}, // the engine doesn't support MultiPV
+	} {
+		resp, err := post(t, context.Background(), ts.URL, req, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		var body errorBody
+		json.NewDecoder(resp.Body).Decode(&body)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusBadRequest || body.Error == "" {
+			t.Errorf("%+v: got status %d, error %q", req, resp.StatusCode, body.Error)
+		}
+	}
+}
+
+func TestServer_Cancel(t *testing.T) {
+	ts := newTestServer(t, "single", Config{Engines: 1})
+
+	// The engine searches until stopped, which happens when the client goes
+	// away.
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	if _, err := post(t, ctx, ts.URL, Request{FEN: startFEN, MoveTime: 60000}, ""); err == nil {
+		t.Fatal("no error")
+	}
+
+	// The engine is free again.
+	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	resp, err := post(t, ctx, ts.URL, Request{FEN: startFEN, Depth: 1}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("got status %d", resp.StatusCode)
+	}
+}
+
+func TestServer_MaxMoveTime(t *testing.T) {
+	// A bound below a minute keeps the fake engine from waiting for stop.
+	ts := newTestServer(t, "single", Config{MaxMoveTime: time.Second})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	resp, err := post(t, ctx, ts.URL, Request{FEN: startFEN, MoveTime: 60000}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("got status %d", resp.StatusCode)
+	}
+}
@@ -0,0 +1,110 @@
+package analysis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/clfs/lento/uci/client"
+)
+
+// errClosed is returned when the pool has been closed.
+var errClosed = errors.New("server closed")
+
+// startTimeout bounds how long engines may take to start.
+const startTimeout = 10 * time.Second
+
+// A pool is a bounded set of engine processes, started on demand and reused
+// between searches.
+type pool struct {
+	cfg Config
+	sem chan struct{} // holds a token for each engine in use
+
+	mu     sync.Mutex
+	idle   []*client.Client
+	closed bool
+}
+
+func newPool(cfg Config) *pool {
+	return &pool{
+		cfg: cfg,
+		sem: make(chan struct{}, max(cfg.Engines, 1)),
+	}
+}
+
+// get returns an idle engine, starting one if needed. It waits for an engine
+// to be returned if all are in use.
+func (p *pool) get(ctx context.Context) (*client.Client, error) {
+	select {
+	case p.sem <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		<-p.sem
+		return nil, errClosed
+	}
+	if n := len(p.idle); n > 0 {
+		c := p.idle[n-1]
+		p.idle = p.idle[:n-1]
+		p.mu.Unlock()
+		return c, nil
+	}
+	p.mu.Unlock()
+
+	c, err := p.start(ctx)
+	if err != nil {
+		<-p.sem
+		return nil, err
+	}
+	return c, nil
+}
+
+func (p *pool) start(ctx context.Context) (*client.Client, error) {
+	ctx, cancel := context.WithTimeout(ctx, startTimeout)
+	defer cancel()
+
+	c, err := client.Start(ctx, p.cfg.Path, p.cfg.Args...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", p.cfg.Path, err)
+	}
+	for name, value := range p.cfg.Options {
+		if err := c.SetOption(name, value); err != nil {
+			c.Close()
+			return nil, fmt.Errorf("%s: %v", p.cfg.Path, err)
+		}
+	}
+	return c, nil
+}
+
+// put returns an engine to the pool. Engines that failed are closed, and
+// replaced when next needed.
+func (p *pool) put(c *client.Client, failed bool) {
+	defer func() { <-p.sem }()
+
+	p.mu.Lock()
+	if !failed && !p.closed {
+		p.idle = append(p.idle, c)
+		p.mu.Unlock()
+		return
+	}
+	p.mu.Unlock()
+	c.Close()
+}
+
+// close closes the idle engines. Engines in use are closed when returned.
+func (p *pool) close() {
+	p.mu.Lock()
+	idle := p.idle
+	p.idle, p.closed = nil, true
+	p.mu.Unlock()
+
+	for _, c := range idle {
+		c.Close()
+	}
+}
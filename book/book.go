@@ -0,0 +1,223 @@
+// Package book reads, writes and builds opening books in the Polyglot format.
+//
+// A Polyglot book is a sequence of 16-byte big-endian entries sorted by key.
+// Each entry holds a position's key, a move, a weight, and learning data.
+// Moves with a higher weight are played more often.
+package book
+
+import (
+	"bufio"
+	"cmp"
+	"encoding/binary"
+	"errors"
+	"io"
+	"slices"
+
+	"github.com/clfs/lento/core"
+)
+
+// entrySize is the size of an encoded [Entry] in bytes.
+const entrySize = 16
+
+// Key returns the Polyglot key of p.
+//
+// The en passant file contributes to the key only if a pawn of the side to
+// move stands next to the double-pushed pawn, legal capture or not.
+func Key(p core.Position) uint64 {
+	var (
+		k   uint64
+		b   = p.Board()
+		stm = p.SideToMove()
+	)
+
+	for s := core.A1; s <= core.H8; s++ {
+		piece, ok := b.Get(s)
+		if !ok {
+			continue
+		}
+		kind := 2 * int(piece.Type())
+		if piece.Color() == core.White {
+			kind++
+		}
+		k ^= random64[64*kind+8*int(s.Rank())+int(s.File())]
+	}
+
+	cr := p.CastlingRights()
+	for i, ok := range []bool{cr.GetWhiteOO(), cr.GetWhiteOOO(), cr.GetBlackOO(), cr.GetBlackOOO()} {
+		if ok {
+			k ^= random64[768+i]
+		}
+	}
+
+	ep := p.EnPassantTarget()
+	if target, ok := ep.Get(); ok {
+		// Find the rank of the pawn that was pushed.
+		r := target.Rank()
+		if stm == core.White {
+			r = r.Below()
+		} else {
+			r = r.Above()
+		}
+		pawn := core.NewPiece(stm, core.Pawn)
+		f := target.File()
+		for _, adj := range []core.File{f - 1, f + 1} {
+			if adj > core.FileH { // also catches FileA - 1, which wraps
+				continue
+			}
+			if piece, ok := b.Get(core.NewSquare(adj, r)); ok && piece == pawn {
+				k ^= random64[772+int(f)]
+				break
+			}
+		}
+	}
+
+	if stm == core.White {
+		k ^= random64[780]
+	}
+
+	return k
+}
+
+// An Entry is a book entry.
+type Entry struct {
+	Key uint64
+	// Move is the move in Polyglot's encoding; see [EncodeMove].
+	Move   uint16
+	Weight uint16
+	Learn  uint32
+}
+
+// EncodeMove encodes a legal move in p to Polyglot's encoding. Castling is
+// encoded as the king capturing its own rook, like "e1h1".
+func EncodeMove(p core.Position, m core.Move) uint16 {
+	from, to := m.From(), m.To()
+
+	b := p.Board()
+	if piece, _ := b.Get(from); piece.Type() == core.King {
+		switch {
+		case from.File() == core.FileE && to.File() == core.FileG:
+			to = core.NewSquare(core.FileH, to.Rank())
+		case from.File() == core.FileE && to.File() == core.FileC:
+			to = core.NewSquare(core.FileA, to.Rank())
+		}
+	}
+
+	res := uint16(to) | uint16(from)<<6
+	if pt, ok := m.Promotion(); ok {
+		// Knight is 1, bishop 2, rook 3 and queen 4.
+		res |= uint16(pt-core.Knight+1) << 12
+	}
+	return res
+}
+
+// DecodeMove decodes a move in p from Polyglot's encoding. The result may be
+// illegal.
+func DecodeMove(p core.Position, raw uint16) core.Move {
+	to := core.Square(raw & 0x3f)
+	from := core.Square(raw >> 6 & 0x3f)
+
+	if promo := raw >> 12 & 0x7; promo != 0 {
+		return core.NewPromotionMove(from, to, core.Knight+core.PieceType(promo-1))
+	}
+
+	b := p.Board()
+	if piece, _ := b.Get(from); piece.Type() == core.King && from.File() == core.FileE {
+		switch {
+		case to.File() == core.FileH && to.Rank() == from.Rank():
+			to = core.NewSquare(core.FileG, to.Rank())
+		case to.File() == core.FileA && to.Rank() == from.Rank():
+			to = core.NewSquare(core.FileC, to.Rank())
+		}
+	}
+	return core.NewMove(from, to)
+}
+
+// A Book is an opening book.
+type Book struct {
+	entries []Entry // sorted by key, then by weight, heaviest first
+}
+
+// New returns a book with the given entries.
+func New(entries []Entry) *Book {
+	entries = slices.Clone(entries)
+	slices.SortStableFunc(entries, compareEntries)
+	return &Book{entries: entries}
+}
+
+func compareEntries(a, b Entry) int {
+	if c := cmp.Compare(a.Key, b.Key); c != 0 {
+		return c
+	}
+	return cmp.Compare(b.Weight, a.Weight)
+}
+
+// Read reads a book in the Polyglot format.
+func Read(r io.Reader) (*Book, error) {
+	var (
+		entries []Entry
+		buf     [entrySize]byte
+		br      = bufio.NewReader(r)
+	)
+	for {
+		_, err := io.ReadFull(br, buf[:])
+		if err == io.EOF {
+			break
+		}
+		if err == io.ErrUnexpectedEOF {
+			return nil, errors.New("truncated entry")
+		}
+		if err != nil {
+			return nil, err
+		}
+		e := Entry{
+			Key:    binary.BigEndian.Uint64(buf[0:]),
+			Move:   binary.BigEndian.Uint16(buf[8:]),
+			Weight: binary.BigEndian.Uint16(buf[10:]),
+			Learn:  binary.BigEndian.Uint32(buf[12:]),
+		}
+		if n := len(entries); n > 0 && entries[n-1].Key > e.Key {
+			return nil, errors.New("entries not sorted by key")
+		}
+		entries = append(entries, e)
+	}
+	return &Book{entries: entries}, nil
+}
+
+// Write writes the book in the Polyglot format.
+func (b *Book) Write(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	var buf [entrySize]byte
+	for _, e := range b.entries {
+		binary.BigEndian.PutUint64(buf[0:], e.Key)
+		binary.BigEndian.PutUint16(buf[8:], e.Move)
+		binary.BigEndian.PutUint16(buf[10:], e.Weight)
+		binary.BigEndian.PutUint32(buf[12:], e.Learn)
+		if _, err := bw.Write(buf[:]); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// Entries returns the book's entries, sorted by key.
+func (b *Book) Entries() []Entry {
+	return slices.Clone(b.entries)
+}
+
+// Lookup returns the entries for p with legal moves, heaviest first.
+func (b *Book) Lookup(p core.Position) []Entry {
+	key := Key(p)
+	i, _ := slices.BinarySearchFunc(b.entries, key, func(e Entry, k uint64) int {
+		return cmp.Compare(e.Key, k)
+	})
+
+	var res []Entry
+	for ; i < len(b.entries) && b.entries[i].Key == key; i++ {
+		e := b.entries[i]
+		if p.IsLegal(DecodeMove(p, e.Move)) {
+			res = append(res, e)
+		}
+	}
+	slices.SortStableFunc(res, compareEntries)
+	return res
+}
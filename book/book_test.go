@@ -0,0 +1,199 @@
+package book
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/clfs/lento/core"
+	"github.com/clfs/lento/encoding/fen"
+	"github.com/clfs/lento/encoding/pgn"
+)
+
+// Test vectors from the Polyglot book format specification.
+func TestKey(t *testing.T) {
+	cases := []struct {
+		fen  string
+		want uint64
+	}{
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", 0x463b96181691fc9c},
+		{"rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 1", 0x823c9b50fd114196},
+		{"rnbqkbnr/ppp1pppp/8/3p4/4P3/8/PPPP1PPP/RNBQKBNR w KQkq d6 0 2", 0x0756b94461c50fb0},
+		{"rnbqkbnr/ppp1pppp/8/3pP3/8/8/PPPP1PPP/RNBQKBNR b KQkq - 0 2", 0x662fafb965db29d4},
+		{"rnbqkbnr/ppp1p1pp/8/3pPp2/8/8/PPPP1PPP/RNBQKBNR w KQkq f6 0 3", 0x22a48b5a8e47ff78},
+		{"rnbqkbnr/ppp1p1pp/8/3pPp2/8/8/PPPPKPPP/RNBQ1BNR b kq - 0 3", 0x652a607ca3f242c1},
+		{"rnbq1bnr/ppp1pkpp/8/3pPp2/8/8/PPPPKPPP/RNBQ1BNR w - - 0 4", 0x00fdd303c946bdd9},
+		{"rnbqkbnr/p1pppppp/8/8/PpP4P/8/1P1PPPP1/RNBQKBNR b KQkq c3 0 3", 0x3c8123ea7b067637},
+		{"rnbqkbnr/p1pppppp/8/8/P6P/R1p5/1P1PPPP1/1NBQKBNR b Kkq - 0 4", 0x5c3f9b829b279560},
+	}
+	for _, tc := range cases {
+		if got := Key(fen.MustDecode(tc.fen)); tc.want != got {
+			t.Errorf("%s: want %016x, got %016x", tc.fen, tc.want, got)
+		}
+	}
+}
+
+func TestMove_RoundTrip(t *testing.T) {
+	cases := []struct {
+		fen, move string
+		raw       uint16
+	}{
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", "e2e4", 12<<6 | 28},
+		{"r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1", "e1g1", 4<<6 | 7},
+		{"r3k2r/8/8/8/8/8/8/R3K2R b KQkq - 0 1", "e8c8", 60<<6 | 56},
+		{"8/P6k/8/8/8/8/8/K7 w - - 0 1", "a7a8n", 1<<12 | 48<<6 | 56},
+		{"8/P6k/8/8/8/8/8/K7 w - - 0 1", "a7a8q", 4<<12 | 48<<6 | 56},
+	}
+	for _, tc := range cases {
+		p := fen.MustDecode(tc.fen)
+		m, err := core.ParseMove(tc.move)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := EncodeMove(p, m); tc.raw != got {
+			t.Errorf("%s: want raw %#x, got %#x", tc.move, tc.raw, got)
+		}
+		if got := DecodeMove(p, tc.raw); m != got {
+			t.Errorf("%#x: want %v, got %v", tc.raw, m, got)
+		}
+	}
+}
+
+func TestReadWrite(t *testing.T) {
+	b := New([]Entry{
+		{Key: 2, Move: 3, Weight: 4, Learn: 5},
+		{Key: 1, Move: 1, Weight: 1},
+		{Key: 1, Move: 2, Weight: 9},
+	})
+
+	var buf bytes.Buffer
+	if err := b.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if buf.Len() != 3*entrySize {
+		t.Fatalf("want %d bytes, got %d", 3*entrySize, buf.Len())
+	}
+
+	got, err := Read(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Entry{
+		{Key: 1, Move: 2, Weight: 9},
+		{Key: 1, Move: 1, Weight: 1},
+		{Key: 2, Move: 3, Weight: 4, Learn: 5},
+	}
+	if !reflect.DeepEqual(want, got.Entries()) {
+		t.Errorf("want %+v, got %+v", want, got.Entries())
+	}
+}
+
+func TestRead_Invalid(t *testing.T) {
+	if _, err := Read(bytes.NewReader(make([]byte, entrySize+1))); err == nil {
+		t.Error("truncated: no error")
+	}
+
+	var buf bytes.Buffer
+	(&Book{entries: []Entry{{Key: 2}, {Key: 1}}}).Write(&buf)
+	if _, err := Read(&buf); err == nil {
+		t.Error("unsorted: no error")
+	}
+}
+
+func mustGame(t *testing.T, s string) pgn.Game {
+	t.Helper()
+	g, err := pgn.Decode(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return g
+}
+
+func TestBuilder(t *testing.T) {
+	games := []string{
+		"[WhiteElo \"2500\"]\n[BlackElo \"2400\"]\n\n1. e4 e5 2. Nf3 1-0",
+		"[WhiteElo \"2500\"]\n[BlackElo \"2400\"]\n\n1. e4 c5 0-1",
+		"[WhiteElo \"2500\"]\n[BlackElo \"2400\"]\n\n1. d4 d5 1/2-1/2",
+		"[WhiteElo \"2500\"]\n[BlackElo \"2400\"]\n\n1. c4 *",
+		"[WhiteElo \"1500\"]\n[BlackElo \"2400\"]\n\n1. b3 1-0",
+		"1. g3 1-0",
+	}
+
+	start := core.NewPosition()
+	weights := func(b *Book, p core.Position) map[string]uint16 {
+		res := make(map[string]uint16)
+		for _, e := range b.Lookup(p) {
+			res[DecodeMove(p, e.Move).String()] = e.Weight
+		}
+		return res
+	}
+
+	cases := []struct {
+		cfg    BuildConfig
+		games  int
+		want   map[string]uint16
+		afterE map[string]uint16 // after 1. e4
+	}{
+		{
+			cfg:    BuildConfig{MinElo: 2000},
+			games:  3,
+			want:   map[string]uint16{"e2e4": 2, "d2d4": 1},
+			afterE: map[string]uint16{"c7c5": 2},
+		},
+		{
+			cfg:    BuildConfig{MinElo: 2000, Weighting: ByFrequency},
+			games:  3,
+			want:   map[string]uint16{"e2e4": 2, "d2d4": 1},
+			afterE: map[string]uint16{"e7e5": 1, "c7c5": 1},
+		},
+		{
+			cfg:    BuildConfig{Results: []core.Result{core.WhiteWins}, MaxPly: 1},
+			games:  3,
+			want:   map[string]uint16{"e2e4": 2, "b2b3": 2, "g2g3": 2},
+			afterE: map[string]uint16{},
+		},
+		{
+			cfg:    BuildConfig{MinGames: 2, Weighting: ByFrequency},
+			games:  5,
+			want:   map[string]uint16{"e2e4": 2},
+			afterE: map[string]uint16{},
+		},
+	}
+	for i, tc := range cases {
+		b := NewBuilder(tc.cfg)
+		for _, s := range games {
+			b.Add(mustGame(t, s))
+		}
+		if b.Games() != tc.games {
+			t.Errorf("%d: want %d games, got %d", i, tc.games, b.Games())
+		}
+
+		bk := b.Book()
+		if got := weights(bk, start); !reflect.DeepEqual(tc.want, got) {
+			t.Errorf("%d: want %v, got %v", i, tc.want, got)
+		}
+		p := start
+		p.Move(core.NewMove(core.E2, core.E4))
+		if got := weights(bk, p); !reflect.DeepEqual(tc.afterE, got) {
+			t.Errorf("%d: after e4: want %v, got %v", i, tc.afterE, got)
+		}
+	}
+}
+
+func TestBuilder_Scaling(t *testing.T) {
+	b := NewBuilder(BuildConfig{})
+	g := mustGame(t, "1. e4 1-0")
+	for range 40000 {
+		b.Add(g)
+	}
+	b.Add(mustGame(t, "1. d4 1-0"))
+
+	p := core.NewPosition()
+	entries := b.Book().Lookup(p)
+	if len(entries) != 2 {
+		t.Fatalf("want 2 entries, got %d", len(entries))
+	}
+	if entries[0].Weight != 0xffff || entries[1].Weight != 1 {
+		t.Errorf("want weights 65535 and 1, got %d and %d", entries[0].Weight, entries[1].Weight)
+	}
+}
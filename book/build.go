@@ -0,0 +1,165 @@
+package book
+
+import (
+	"slices"
+	"strconv"
+
+	"github.com/clfs/lento/core"
+	"github.com/clfs/lento/encoding/pgn"
+)
+
+// A Weighting is a way of weighting book moves.
+type Weighting uint8
+
+// [Weighting] constants.
+const (
+	// ByScore weights a move by its results for the side that played it: 2
+	// for each win and 1 for each draw. Moves that only lost are left out.
+	ByScore Weighting = iota
+	// ByFrequency weights a move by the number of games it was played in.
+	ByFrequency
+)
+
+// A BuildConfig chooses the games and moves that go into a book.
+type BuildConfig struct {
+	// MinElo, if positive, is the lowest "WhiteElo" and "BlackElo" a game
+	// may have. Games without both tags are skipped.
+	MinElo int
+	// Results, if not empty, are the results a game may have. Games without a
+	// result are always skipped.
+	Results []core.Result
+	// MaxPly, if positive, is the number of plies used from each game.
+	MaxPly int
+	// MinGames is the number of games a move must appear in. The default
+	// is 1.
+	MinGames int
+	// Weighting is how moves are weighted.
+	Weighting Weighting
+}
+
+// moveStats are a move's results for the side that played it.
+type moveStats struct {
+	wins, draws, losses int
+}
+
+func (s moveStats) games() int {
+	return s.wins + s.draws + s.losses
+}
+
+// A Builder accumulates games into a book.
+//
+// The zero value of Builder is not usable; use [NewBuilder].
+type Builder struct {
+	cfg   BuildConfig
+	stats map[uint64]map[uint16]*moveStats
+	games int
+}
+
+// NewBuilder returns a new builder.
+func NewBuilder(cfg BuildConfig) *Builder {
+	cfg.MinGames = max(cfg.MinGames, 1)
+	return &Builder{cfg: cfg, stats: make(map[uint64]map[uint16]*moveStats)}
+}
+
+// Add adds a game with legal moves to the book, returning false if the game
+// was filtered out.
+func (b *Builder) Add(g pgn.Game) bool {
+	if !b.keep(g) {
+		return false
+	}
+	b.games++
+
+	p := g.Position
+	for i, m := range g.Moves {
+		if b.cfg.MaxPly > 0 && i >= b.cfg.MaxPly {
+			break
+		}
+
+		key := Key(p)
+		moves, ok := b.stats[key]
+		if !ok {
+			moves = make(map[uint16]*moveStats)
+			b.stats[key] = moves
+		}
+		raw := EncodeMove(p, m)
+		s, ok := moves[raw]
+		if !ok {
+			s = new(moveStats)
+			moves[raw] = s
+		}
+
+		switch {
+		case g.Result == core.Draw:
+			s.draws++
+		case (g.Result == core.WhiteWins) == (p.SideToMove() == core.White):
+			s.wins++
+		default:
+			s.losses++
+		}
+
+		p.Move(m)
+	}
+	return true
+}
+
+func (b *Builder) keep(g pgn.Game) bool {
+	if g.Result == core.NoResult {
+		return false
+	}
+	if len(b.cfg.Results) > 0 && !slices.Contains(b.cfg.Results, g.Result) {
+		return false
+	}
+	if b.cfg.MinElo > 0 {
+		for _, tag := range []string{"WhiteElo", "BlackElo"} {
+			v, _ := g.Tag(tag)
+			if elo, err := strconv.Atoi(v); err != nil || elo < b.cfg.MinElo {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// Games returns the number of games added.
+func (b *Builder) Games() int {
+	return b.games
+}
+
+// Book returns a book of the moves added so far.
+//
+// Weights are scaled down, if needed, to fit in an entry.
+func (b *Builder) Book() *Book {
+	var entries []Entry
+	for key, moves := range b.stats {
+		weights := make(map[uint16]int)
+		heaviest := 0
+		for raw, s := range moves {
+			if s.games() < b.cfg.MinGames {
+				continue
+			}
+			w := s.games()
+			if b.cfg.Weighting == ByScore {
+				w = 2*s.wins + s.draws
+			}
+			if w > 0 {
+				weights[raw] = w
+				heaviest = max(heaviest, w)
+			}
+		}
+		for raw, w := range weights {
+			if heaviest > 0xffff {
+				w = max(w*0xffff/heaviest, 1)
+			}
+			entries = append(entries, Entry{Key: key, Move: raw, Weight: uint16(w)})
+		}
+	}
+
+	// Sort fully, so that equal books are written identically.
+	slices.SortFunc(entries, func(a, b Entry) int {
+		if c := compareEntries(a, b); c != 0 {
+			return c
+		}
+		return int(a.Move) - int(b.Move)
+	})
+	return &Book{entries: entries}
+}
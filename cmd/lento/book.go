@@ -0,0 +1,151 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/clfs/lento/book"
+	"github.com/clfs/lento/core"
+	"github.com/clfs/lento/encoding/fen"
+	"github.com/clfs/lento/encoding/pgn"
+	"github.com/clfs/lento/encoding/san"
+)
+
+func runBook(args []string) error {
+	if len(args) == 0 {
+		return errors.New("book: want a subcommand: build or probe")
+	}
+	switch args[0] {
+	case "build":
+		return runBookBuild(args[1:])
+	case "probe":
+		return runBookProbe(args[1:])
+	default:
+		return fmt.Errorf("book: unknown subcommand: %q", args[0])
+	}
+}
+
+func runBookBuild(args []string) error {
+	fs := flag.NewFlagSet("book build", flag.ExitOnError)
+	var (
+		cfg     book.BuildConfig
+		out     = fs.String("o", "book.bin", "write the book to this `file`")
+		results = fs.String("results", "", "comma-separated `results` to keep, like 1-0,0-1 (default all)")
+		weight  = fs.String("weight", "score", "weight moves by `score` or frequency")
+	)
+	fs.IntVar(&cfg.MinElo, "minelo", 0, "skip games unless both players are rated at least this")
+	fs.IntVar(&cfg.MaxPly, "maxply", 30, "use at most this many plies of each game, or 0 for all")
+	fs.IntVar(&cfg.MinGames, "mingames", 3, "leave out moves played in fewer games")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: lento book build [flags] file.pgn...")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return errors.New("book build: no PGN files")
+	}
+
+	if *results != "" {
+		for _, s := range strings.Split(*results, ",") {
+			switch s {
+			case "1-0":
+				cfg.Results = append(cfg.Results, core.WhiteWins)
+			case "0-1":
+				cfg.Results = append(cfg.Results, core.BlackWins)
+			case "1/2-1/2":
+				cfg.Results = append(cfg.Results, core.Draw)
+			default:
+				return fmt.Errorf("bad result: %q", s)
+			}
+		}
+	}
+
+	switch *weight {
+	case "score":
+		cfg.Weighting = book.ByScore
+	case "frequency":
+		cfg.Weighting = book.ByFrequency
+	default:
+		return fmt.Errorf("bad weight: %q", *weight)
+	}
+
+	b := book.NewBuilder(cfg)
+	read, bad, err := readGames(fs.Args(), func(g pgn.Game) error {
+		b.Add(g)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	bk := b.Book()
+	f, err := os.Create(*out)
+	if err != nil {
+		return err
+	}
+	if err := bk.Write(f); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	fmt.Printf("Read %d games (%d malformed), used %d\n", read, bad, b.Games())
+	fmt.Printf("Wrote %d entries to %s\n", len(bk.Entries()), *out)
+	return nil
+}
+
+func runBookProbe(args []string) error {
+	fs := flag.NewFlagSet("book probe", flag.ExitOnError)
+	bookFile := fs.String("book", "book.bin", "Polyglot book `file`")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: lento book probe [flags] [fen]")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	p := core.NewPosition()
+	if fs.NArg() > 0 {
+		var err error
+		if p, err = fen.Decode(strings.Join(fs.Args(), " ")); err != nil {
+			return err
+		}
+	}
+
+	f, err := os.Open(*bookFile)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	bk, err := book.Read(f)
+	if err != nil {
+		return fmt.Errorf("%s: %v", *bookFile, err)
+	}
+
+	entries := bk.Lookup(p)
+	fmt.Printf("Key %016x\n", book.Key(p))
+	if len(entries) == 0 {
+		fmt.Println("No book moves")
+		return nil
+	}
+
+	total := 0
+	for _, e := range entries {
+		total += int(e.Weight)
+	}
+	for _, e := range entries {
+		m := book.DecodeMove(p, e.Move)
+		pct := 0.0
+		if total > 0 {
+			pct = 100 * float64(e.Weight) / float64(total)
+		}
+		fmt.Printf("%-8s %-6v weight %5d  %5.1f%%\n", san.Encode(p, m), m, e.Weight, pct)
+	}
+	return nil
+}
@@ -0,0 +1,351 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"sync/atomic"
+	"time"
+
+	"github.com/clfs/lento/core"
+	"github.com/clfs/lento/nnue"
+	"github.com/clfs/lento/uci"
+)
+
+// A materialEngine is a [uci.Engine] that searches by counting material, or
+// with an NNUE network loaded with the EvalFile option.
+//
+// GUIs start lento without a subcommand and expect it to play, but lento has
+// no search of its own yet, so [runEngine] exposes this one over UCI and CECP.
+// It is a plain alpha-beta search with iterative deepening, without move
+// ordering or a transposition table, and is meant to be replaced.
+type materialEngine struct {
+	p       core.Position
+	history []core.Position // the positions before p, oldest first
+	stopped atomic.Bool
+	// deadline is when the running search must end, in Unix nanoseconds, or
+	// 0 if there's none. A ponder search gets one on ponderhit.
+	deadline atomic.Int64
+	// ponder are the limits of the running ponder search, if any.
+	ponder *uci.Limits
+	net    *nnue.Network // nil to count material
+}
+
+func (e *materialEngine) SetPosition(start core.Position, moves []core.Move) {
+	e.p, e.history = start, nil
+	for _, m := range moves {
+		e.history = append(e.history, e.p)
+		e.p.Move(m)
+	}
+}
+
+// SetOption sets the EvalFile option, loading the network in the named file,
+// or going back to counting material if the name is empty. Other options are
+// ignored.
+func (e *materialEngine) SetOption(name, value string) error {
+	if name != "EvalFile" {
+		return nil
+	}
+	if value == "" {
+		e.net = nil
+		return nil
+	}
+	f, err := os.Open(value)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	net, err := nnue.Decode(f)
+	if err != nil {
+		return fmt.Errorf("%s: %v", value, err)
+	}
+	e.net = net
+	return nil
+}
+
+func (e *materialEngine) Stop() {
+	e.stopped.Store(true)
+}
+
+// PonderHit turns the running ponder search into a normal search, timed from
+// now.
+func (e *materialEngine) PonderHit() {
+	if e.ponder == nil {
+		return
+	}
+	if d := budget(e.p.SideToMove(), *e.ponder); d > 0 {
+		e.deadline.Store(time.Now().Add(d).UnixNano())
+	}
+	e.ponder = nil
+}
+
+func (e *materialEngine) Go(l uci.Limits, info func(uci.Info)) <-chan uci.Result {
+	e.stopped.Store(false)
+	e.deadline.Store(0)
+	e.ponder = nil
+	if l.Ponder {
+		e.ponder = &l
+	} else if d := budget(e.p.SideToMove(), l); d > 0 {
+		e.deadline.Store(time.Now().Add(d).UnixNano())
+	}
+	results := make(chan uci.Result, 1)
+	go func(p core.Position) {
+		results <- e.search(p, l, info)
+	}(e.p)
+	return results
+}
+
+// mateScore is the score of being mated at the root. Mates further away score
+// closer to zero.
+const mateScore = 100_000
+
+// values are the piece values in centipawns, indexed by piece type.
+var values = [...]int{
+	core.Pawn:   100,
+	core.Knight: 300,
+	core.Bishop: 300,
+	core.Rook:   500,
+	core.Queen:  900,
+}
+
+// A searcher is the state of one search.
+type searcher struct {
+	e     *materialEngine
+	path  []core.Position // the game and search positions before the current one
+	pv    [][]core.Move   // the principal variation from each ply
+	ev    *nnue.Evaluator // nil to count material
+	nodes int
+}
+
+// search searches p by iterative deepening within l, reporting each finished
+// depth, and returns the best move of the deepest one. Infinite and ponder
+// searches may end early; the server holds their result until it may be
+// sent.
+func (e *materialEngine) search(p core.Position, l uci.Limits, info func(uci.Info)) uci.Result {
+	moves := p.LegalMoves()
+	if len(moves) == 0 {
+		return uci.Result{}
+	}
+
+	start := time.Now()
+	s := &searcher{e: e, path: slices.Clone(e.history)}
+	if e.net != nil {
+		s.ev = nnue.NewEvaluator(e.net, p.Board())
+	}
+	maxDepth := l.Depth
+	switch {
+	case maxDepth > 0:
+	case budget(p.SideToMove(), l) == 0 && !l.Infinite:
+		maxDepth = 4 // An unlimited search must still end.
+	default:
+		maxDepth = 64
+	}
+
+	pv := moves[:1]
+	for depth := 1; depth <= maxDepth; depth++ {
+		line, score, ok := s.root(p, moves, depth)
+		if !ok {
+			break
+		}
+		pv = line
+		info(uci.Info{
+			Depth: depth,
+			Score: scoreOf(score),
+			Nodes: s.nodes,
+			Time:  time.Since(start),
+			PV:    pv,
+		})
+		if score >= mateScore-depth || score <= -mateScore+depth {
+			break // Deeper searches can't improve on a forced mate.
+		}
+	}
+	res := uci.Result{BestMove: pv[0]}
+	if len(pv) > 1 {
+		res.Ponder = pv[1]
+	}
+	return res
+}
+
+// interrupted returns true if the running search must end.
+func (e *materialEngine) interrupted() bool {
+	if e.stopped.Load() {
+		return true
+	}
+	d := e.deadline.Load()
+	return d != 0 && time.Now().UnixNano() > d
+}
+
+// budget returns the time to spend on a move by the side c, or 0 if the
+// search has no time limit.
+func budget(c core.Color, l uci.Limits) time.Duration {
+	if l.MoveTime > 0 {
+		return l.MoveTime
+	}
+	left, inc := l.WTime, l.WInc
+	if c == core.Black {
+		left, inc = l.BTime, l.BInc
+	}
+	if left <= 0 {
+		return 0
+	}
+	togo := l.MovesToGo
+	if togo <= 0 {
+		togo = 30
+	}
+	return left/time.Duration(togo) + inc/2
+}
+
+// scoreOf converts a search score to a UCI score.
+func scoreOf(score int) *uci.Score {
+	switch {
+	case score > mateScore/2:
+		return &uci.Score{Mate: (mateScore - score + 1) / 2}
+	case score < -mateScore/2:
+		return &uci.Score{Mate: -(mateScore + score) / 2}
+	}
+	return &uci.Score{CP: score}
+}
+
+// root returns the principal variation of p searched to depth, starting with
+// one of moves, and its score. It returns false if the search was
+// interrupted.
+func (s *searcher) root(p core.Position, moves []core.Move, depth int) ([]core.Move, int, bool) {
+	s.path = append(s.path, p)
+	defer func() { s.path = s.path[:len(s.path)-1] }()
+
+	s.clearPV(0)
+	alpha := -2 * mateScore
+	for _, m := range moves {
+		q := p
+		s.makeMove(&q, m)
+		score, ok := s.negamax(q, depth-1, 1, -2*mateScore, -alpha)
+		s.unmakeMove()
+		if !ok {
+			return nil, 0, false
+		}
+		if score = -score; score > alpha {
+			alpha = score
+			s.setPV(0, m)
+		}
+	}
+	return slices.Clone(s.pv[0]), alpha, true
+}
+
+// negamax returns the score of p for the side to move, searching depth plies
+// deep at ply plies from the root, or false if the search was interrupted.
+func (s *searcher) negamax(p core.Position, depth, ply, alpha, beta int) (int, bool) {
+	s.nodes++
+	s.clearPV(ply)
+	if s.nodes%1024 == 0 && s.e.interrupted() {
+		return 0, false
+	}
+
+	switch r := p.VariantResult(); {
+	case r == core.Draw:
+		return 0, true
+	case r == core.WhiteWins && p.SideToMove() == core.White,
+		r == core.BlackWins && p.SideToMove() == core.Black:
+		return mateScore - ply, true
+	case r != core.NoResult:
+		return -mateScore + ply, true
+	}
+
+	moves := p.LegalMoves()
+	switch {
+	case len(moves) == 0 && p.InCheck():
+		return -mateScore + ply, true
+	case len(moves) == 0, p.HalfmoveClock() >= 100, s.repeated(p):
+		return 0, true
+	case depth == 0:
+		return s.evaluate(p), true
+	}
+
+	s.path = append(s.path, p)
+	defer func() { s.path = s.path[:len(s.path)-1] }()
+	for _, m := range moves {
+		q := p
+		s.makeMove(&q, m)
+		score, ok := s.negamax(q, depth-1, ply+1, -beta, -alpha)
+		s.unmakeMove()
+		if !ok {
+			return 0, false
+		}
+		if -score > alpha {
+			alpha = -score
+			s.setPV(ply, m)
+		}
+		if alpha >= beta {
+			break
+		}
+	}
+	return alpha, true
+}
+
+// makeMove makes m on p, keeping the evaluator in step.
+func (s *searcher) makeMove(p *core.Position, m core.Move) {
+	if s.ev == nil {
+		p.Move(m)
+		return
+	}
+	s.ev.Move(p, m)
+}
+
+// unmakeMove reverts the evaluator's update for the last move made.
+func (s *searcher) unmakeMove() {
+	if s.ev != nil {
+		s.ev.Pop()
+	}
+}
+
+// evaluate returns the evaluation of p for the side to move.
+func (s *searcher) evaluate(p core.Position) int {
+	if s.ev != nil {
+		return s.ev.Evaluate(p.SideToMove())
+	}
+	return material(p)
+}
+
+// clearPV clears the principal variation from ply.
+func (s *searcher) clearPV(ply int) {
+	for len(s.pv) <= ply+1 {
+		s.pv = append(s.pv, nil)
+	}
+	s.pv[ply] = s.pv[ply][:0]
+}
+
+// setPV sets the principal variation from ply to m, followed by the one from
+// the next ply.
+func (s *searcher) setPV(ply int, m core.Move) {
+	s.pv[ply] = append(append(s.pv[ply][:0], m), s.pv[ply+1]...)
+}
+
+// repeated returns true if p repeats an earlier position of the game or the
+// search. A search that repeats once can repeat again, so that's scored as a
+// draw.
+func (s *searcher) repeated(p core.Position) bool {
+	// Only positions since the last capture or pawn move can repeat.
+	for i := len(s.path) - 2; i >= max(0, len(s.path)-p.HalfmoveClock()); i -= 2 {
+		q := &s.path[i]
+		if q.Board() == p.Board() && q.SideToMove() == p.SideToMove() &&
+			q.CastlingRights() == p.CastlingRights() && q.EnPassantTarget() == p.EnPassantTarget() &&
+			q.Pocket(core.White) == p.Pocket(core.White) && q.Pocket(core.Black) == p.Pocket(core.Black) &&
+			q.ChecksGiven(core.White) == p.ChecksGiven(core.White) &&
+			q.ChecksGiven(core.Black) == p.ChecksGiven(core.Black) {
+			return true
+		}
+	}
+	return false
+}
+
+// material returns the material balance of p in centipawns, for the side to
+// move.
+func material(p core.Position) int {
+	b := p.Board()
+	var score int
+	for pt := core.Pawn; pt < core.King; pt++ {
+		us := b.Bitboard(core.NewPiece(p.SideToMove(), pt)).Count()
+		them := b.Bitboard(core.NewPiece(p.SideToMove().Other(), pt)).Count()
+		score += values[pt] * (us - them)
+	}
+	return score
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/clfs/lento/core"
+	"github.com/clfs/lento/encoding/epd"
+	"github.com/clfs/lento/encoding/san"
+	"github.com/clfs/lento/uci"
+	"github.com/clfs/lento/uci/client"
+)
+
+func runEPD(args []string) error {
+	fs := flag.NewFlagSet("epd", flag.ExitOnError)
+	var (
+		limits     uci.Limits
+		enginePath = fs.String("engine", "", "UCI engine `executable` to run the suite with")
+		options    = optionFlag(fs)
+	)
+	fs.DurationVar(&limits.MoveTime, "movetime", time.Second, "search time per position, unless -depth is set")
+	fs.IntVar(&limits.Depth, "depth", 0, "search each position to this depth instead")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: lento epd [flags] file.epd...")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if *enginePath == "" {
+		return errors.New("epd: -engine is required")
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return errors.New("epd: no EPD files")
+	}
+	if limits.Depth > 0 {
+		limits.MoveTime = 0
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	e, err := startEngine(ctx, *enginePath, options)
+	if err != nil {
+		return err
+	}
+	defer e.Close()
+
+	solved, total, err := runSuite(ctx, e, limits, fs.Args(), os.Stdout)
+	if total > 0 {
+		fmt.Printf("solved %d of %d (%.1f%%)\n", solved, total, 100*float64(solved)/float64(total))
+	}
+	return err
+}
+
+// runSuite searches the records of the named EPD files that have "bm" or "am"
+// operations, and writes whether the engine solved each to w. A record is
+// solved if the engine's move is one of its best moves and none of its
+// avoided moves. Malformed records are logged and skipped.
+func runSuite(ctx context.Context, e *client.Client, l uci.Limits, names []string, w io.Writer) (solved, total int, err error) {
+	for _, name := range names {
+		s, t, err := runSuiteFile(ctx, e, l, name, w)
+		solved += s
+		total += t
+		if err != nil {
+			return solved, total, err
+		}
+	}
+	return solved, total, nil
+}
+
+// runSuiteFile is [runSuite] for a single file.
+func runSuiteFile(ctx context.Context, e *client.Client, l uci.Limits, name string, w io.Writer) (solved, total int, err error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer f.Close()
+
+	sc := bufio.NewScanner(f)
+	for n := 1; sc.Scan(); n++ {
+		if strings.TrimSpace(sc.Text()) == "" {
+			continue
+		}
+		r, err := epd.Decode(sc.Text())
+		if err != nil {
+			log.Printf("%s:%d: %v", name, n, err)
+			continue
+		}
+		bm, _ := r.Moves("bm")
+		am, _ := r.Moves("am")
+		if len(bm) == 0 && len(am) == 0 {
+			continue
+		}
+
+		m, err := bestMove(ctx, e, r.Position, l)
+		if err != nil {
+			return solved, total, err
+		}
+		ok := (len(bm) == 0 || slices.Contains(bm, m)) && !slices.Contains(am, m)
+		total++
+		if ok {
+			solved++
+		}
+
+		id := r.ID()
+		if id == "" {
+			id = fmt.Sprintf("%s:%d", name, n)
+		}
+		status := "ok"
+		if !ok {
+			status = "FAIL"
+		}
+		fmt.Fprintf(w, "%-4s %s: played %s", status, id, sanMove(r.Position, m))
+		for _, want := range []struct {
+			opcode string
+			moves  []core.Move
+		}{{"bm", bm}, {"am", am}} {
+			if len(want.moves) > 0 {
+				fmt.Fprintf(w, ", %s %s", want.opcode, sanMoves(r.Position, want.moves))
+			}
+		}
+		fmt.Fprintln(w)
+	}
+	if err := sc.Err(); err != nil {
+		return solved, total, fmt.Errorf("%s: %v", name, err)
+	}
+	return solved, total, nil
+}
+
+// bestMove returns the engine's move in p, searched within l.
+func bestMove(ctx context.Context, e *client.Client, p core.Position, l uci.Limits) (core.Move, error) {
+	if err := e.NewGame(ctx); err != nil {
+		return core.Move{}, err
+	}
+	if err := e.SetPosition(p, nil); err != nil {
+		return core.Move{}, err
+	}
+	s, err := e.Go(ctx, l)
+	if err != nil {
+		return core.Move{}, err
+	}
+	res, err := s.Wait()
+	if err != nil {
+		return core.Move{}, err
+	}
+	return res.BestMove, ctx.Err()
+}
+
+// sanMove returns m in SAN, or in UCI notation if it isn't legal in p.
+func sanMove(p core.Position, m core.Move) string {
+	if !p.IsLegal(m) {
+		return m.String()
+	}
+	return san.Encode(p, m)
+}
+
+// sanMoves returns moves in SAN, separated by spaces.
+func sanMoves(p core.Position, moves []core.Move) string {
+	res := make([]string, len(moves))
+	for i, m := range moves {
+		res[i] = sanMove(p, m)
+	}
+	return strings.Join(res, " ")
+}
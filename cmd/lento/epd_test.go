@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/clfs/lento/uci"
+	"github.com/clfs/lento/uci/client"
+)
+
+func TestRunSuite(t *testing.T) {
+	t.Setenv("LENTO_FAKE_ENGINE", "1")
+	e, err := client.Start(context.Background(), os.Args[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { e.Close() })
+
+	// The fake engine plays a3 from the starting position.
+	const start = "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq -"
+	name := filepath.Join(t.TempDir(), "suite.epd")
+	suite := strings.Join([]string{
+		start + ` bm a3; id "best";`,
+		start + ` bm e4 d4; id "other";`,
+		start + ` am a3;`,
+		start + ` id "no answer";`,
+		start + ` bm Ke2;`,
+		"",
+	}, "\n")
+	if err := os.WriteFile(name, []byte(suite), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var out strings.Builder
+	solved, total, err := runSuite(context.Background(), e, uci.Limits{Depth: 1}, []string{name}, &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if solved != 1 || total != 3 {
+		t.Errorf("want 1 of 3 solved, got %d of %d", solved, total)
+	}
+	want := "ok   best: played a3, bm a3\n" +
+		"FAIL other: played a3, bm e4 d4\n" +
+		"FAIL " + name + ":3: played a3, am a3\n"
+	if got := out.String(); got != want {
+		t.Errorf("want output\n%s\ngot\n%s", want, got)
+	}
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/clfs/lento/encoding/pgn"
+)
+
+// readGames calls fn for each game in the named PGN files, in order. Malformed
+// games are logged and skipped. It stops at the first error reading a file or
+// returned by fn, and returns the number of games read and how many of them
+// were malformed.
+func readGames(names []string, fn func(pgn.Game) error) (read, bad int, err error) {
+	for _, name := range names {
+		f, err := os.Open(name)
+		if err != nil {
+			return read, bad, err
+		}
+		d := pgn.NewDecoder(bufio.NewReader(f))
+		for n := 1; ; n++ {
+			g, err := d.Decode()
+			if err == io.EOF {
+				break
+			}
+			var re *pgn.ReadError
+			if errors.As(err, &re) {
+				f.Close()
+				return read, bad, fmt.Errorf("%s: %v", name, err)
+			}
+			read++
+			if err != nil {
+				log.Printf("%s: game %d: %v", name, n, err)
+				bad++
+				continue
+			}
+			if err := fn(g); err != nil {
+				f.Close()
+				return read, bad, err
+			}
+		}
+		f.Close()
+	}
+	return read, bad, nil
+}
@@ -1,7 +1,81 @@
 package main
 
-import "fmt"
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/clfs/lento/uci"
+	"github.com/clfs/lento/xboard"
+)
+
+// commands maps subcommand names to their entry points, which receive the
+// arguments after the subcommand name.
+var commands = map[string]func(args []string) error{
+	"bench":   runBench,
+	"book":    runBook,
+	"epd":     runEPD,
+	"match":   runMatch,
+	"mate":    runMate,
+	"play":    runPlay,
+	"puzzles": runPuzzles,
+	"serve":   runServe,
+}
 
 func main() {
-	fmt.Println("hello, world")
+	log.SetFlags(0)
+	log.SetPrefix("lento: ")
+
+	if len(os.Args) < 2 {
+		if err := runEngine(os.Stdin, os.Stdout); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	cmd, ok := commands[os.Args[1]]
+	if !ok {
+		log.Fatalf("unknown command: %q", os.Args[1])
+	}
+	if err := cmd(os.Args[2:]); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// evalFileOption names the NNUE network file the built-in engine evaluates
+// with. If it's empty, the engine counts material.
+var evalFileOption = uci.Option{Name: "EvalFile", Type: uci.String}
+
+// runEngine runs lento as an engine for a GUI, speaking UCI or CECP as chosen
+// by the GUI's first command: "uci" or "xboard".
+func runEngine(r io.Reader, w io.Writer) error {
+	br := bufio.NewReader(r)
+	first, err := br.ReadString('\n')
+	if first == "" {
+		if err == io.EOF {
+			return nil
+		}
+		return err
+	}
+	// The server sees the first command too.
+	r = io.MultiReader(strings.NewReader(first), br)
+
+	e := new(materialEngine)
+	switch cmd, _, _ := strings.Cut(strings.TrimSpace(first), " "); cmd {
+	case "uci":
+		s := &uci.Server{
+			Engine:  e,
+			Name:    "lento",
+			Author:  "the lento authors",
+			Options: []uci.Option{{Name: "Ponder", Type: uci.Check, Default: "false"}, evalFileOption},
+		}
+		return s.Serve(r, w)
+	case "xboard":
+		s := &xboard.Server{Engine: e, Name: "lento", Options: []uci.Option{evalFileOption}}
+		return s.Serve(r, w)
+	}
+	return fmt.Errorf("unknown protocol: %q", strings.TrimSpace(first))
 }
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/clfs/lento/core"
+	"github.com/clfs/lento/encoding/fen"
+	"github.com/clfs/lento/nnue"
+	"github.com/clfs/lento/uci"
+)
+
+func TestRunEngine(t *testing.T) {
+	cases := []struct {
+		in, want string
+	}{
+		{"uci\nisready\n", "option name Ponder type check default false\noption name EvalFile type string default <empty>\nuciok\nreadyok\n"},
+		{"xboard\nprotover 2\nping 1\n", "feature done=1\npong 1\n"},
+	}
+	for _, tc := range cases {
+		var out strings.Builder
+		if err := runEngine(strings.NewReader(tc.in), &out); err != nil {
+			t.Errorf("%q: %v", tc.in, err)
+			continue
+		}
+		if got := out.String(); !strings.HasSuffix(got, tc.want) {
+			t.Errorf("%q: want output ending in %q, got %q", tc.in, tc.want, got)
+		}
+	}
+}
+
+func TestRunEngine_UnknownProtocol(t *testing.T) {
+	var out strings.Builder
+	if err := runEngine(strings.NewReader("hello\n"), &out); err == nil {
+		t.Error("no error")
+	}
+}
+
+func TestMaterialEngine(t *testing.T) {
+	e := new(materialEngine)
+	e.SetPosition(fen.MustDecode("6k1/5ppp/8/8/8/8/8/R5K1 w - - 0 1"), nil)
+
+	var last uci.Info
+	res := <-e.Go(uci.Limits{Depth: 3}, func(info uci.Info) { last = info })
+	if want := core.NewMove(core.A1, core.A8); res.BestMove != want {
+		t.Errorf("want %v, got %v", want, res.BestMove)
+	}
+	if last.Score == nil || last.Score.Mate != 1 {
+		t.Errorf("want mate in 1, got %+v", last.Score)
+	}
+}
+
+func TestMaterialEngine_EvalFile(t *testing.T) {
+	// With no weights, the network scores every position as nnue.Scale for
+	// the side to move.
+	net := nnue.NewNetwork(4)
+	net.OutputBias = nnue.QA * nnue.QB
+	name := filepath.Join(t.TempDir(), "net.bin")
+	f, err := os.Create(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := nnue.Encode(f, net); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	e := new(materialEngine)
+	e.SetPosition(core.NewPosition(), nil)
+	score := func() int {
+		var last uci.Info
+		<-e.Go(uci.Limits{Depth: 1}, func(info uci.Info) { last = info })
+		return last.Score.CP
+	}
+
+	if err := e.SetOption("EvalFile", name); err != nil {
+		t.Fatal(err)
+	}
+	if got := score(); got != -nnue.Scale {
+		t.Errorf("with network: want %d, got %d", -nnue.Scale, got)
+	}
+	if err := e.SetOption("EvalFile", ""); err != nil {
+		t.Fatal(err)
+	}
+	if got := score(); got != 0 {
+		t.Errorf("without network: want 0, got %d", got)
+	}
+	if err := e.SetOption("EvalFile", filepath.Join(t.TempDir(), "missing.bin")); err == nil {
+		t.Error("missing file: no error")
+	}
+}
+
+func TestRunEngine_PonderHit(t *testing.T) {
+	inR, inW := io.Pipe()
+	outR, outW := io.Pipe()
+	go func() {
+		runEngine(inR, outW)
+		outW.Close()
+	}()
+	defer inW.Close()
+
+	lines := make(chan string)
+	go func() {
+		defer close(lines)
+		sc := bufio.NewScanner(outR)
+		for sc.Scan() {
+			lines <- sc.Text()
+		}
+	}()
+
+	go func() {
+		io.WriteString(inW, "uci\nposition startpos moves e2e4\ngo ponder wtime 1000 btime 1000\n")
+		time.Sleep(50 * time.Millisecond)
+		io.WriteString(inW, "ponderhit\n")
+	}()
+
+	// The search must end on its own clock, without "stop".
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case line, ok := <-lines:
+			if !ok {
+				t.Fatal("no bestmove")
+			}
+			if strings.HasPrefix(line, "bestmove ") {
+				return
+			}
+		case <-timeout:
+			t.Fatal("no bestmove after ponderhit")
+		}
+	}
+}
+
+func TestMaterialEngine_VariantResult(t *testing.T) {
+	// Taking the rook wins material, but stepping onto the hill wins the game.
+	p, err := fen.DecodeVariant("7k/8/8/8/8/1rK5/8/8 w - - 0 1", core.KingOfTheHill)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := new(materialEngine)
+	e.SetPosition(p, nil)
+
+	res := <-e.Go(uci.Limits{Depth: 2}, func(uci.Info) {})
+	if want := core.NewMove(core.C3, core.D4); res.BestMove != want {
+		t.Errorf("want %v, got %v", want, res.BestMove)
+	}
+}
+
+func TestSearcher_Repeated(t *testing.T) {
+	var moves []core.Move
+	for _, s := range []string{"g1f3", "g8f6", "f3g1", "f6g8", "g1f3"} {
+		m, err := core.ParseMove(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		moves = append(moves, m)
+	}
+	e := new(materialEngine)
+	e.SetPosition(core.NewPosition(), moves)
+	s := &searcher{e: e, path: append(e.history, e.p)}
+
+	for m, want := range map[core.Move]bool{
+		core.NewMove(core.G8, core.F6): true,
+		core.NewMove(core.B8, core.C6): false,
+	} {
+		q := e.p
+		q.Move(m)
+		if got := s.repeated(q); got != want {
+			t.Errorf("%v: want %t, got %t", m, want, got)
+		}
+	}
+}
+
+func TestMaterialEngine_Ponder(t *testing.T) {
+	e := new(materialEngine)
+	e.SetPosition(core.NewPosition(), nil)
+
+	var last uci.Info
+	res := <-e.Go(uci.Limits{Depth: 3}, func(info uci.Info) { last = info })
+	if len(last.PV) != 3 {
+		t.Fatalf("want a 3-move PV, got %v", last.PV)
+	}
+	if res.BestMove != last.PV[0] || res.Ponder != last.PV[1] {
+		t.Errorf("want %v ponder %v, got %+v", last.PV[0], last.PV[1], res)
+	}
+}
+
+func TestBench(t *testing.T) {
+	n := bench(2)
+	if n == 0 {
+		t.Fatal("no nodes")
+	}
+	if again := bench(2); again != n {
+		t.Errorf("node count changed from %d to %d", n, again)
+	}
+}
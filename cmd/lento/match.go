@@ -0,0 +1,206 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/clfs/lento/core"
+	"github.com/clfs/lento/encoding/epd"
+	"github.com/clfs/lento/encoding/pgn"
+	"github.com/clfs/lento/match"
+)
+
+func runMatch(args []string) error {
+	fs := flag.NewFlagSet("match", flag.ExitOnError)
+	var (
+		engines     [2]match.EngineConfig
+		openings    = fs.String("openings", "", "EPD or PGN `file` of openings")
+		plies       = fs.Int("plies", 0, "play openings from PGN for at most this many plies, or 0 for all")
+		games       = fs.Int("games", 100, "number of games")
+		tc          = fs.String("tc", "10+0.1", "time control in seconds, as base+increment")
+		timeMargin  = fs.Duration("timemargin", 50*time.Millisecond, "how far engines may go over their time on a move without forfeiting")
+		concurrency = fs.Int("concurrency", 1, "number of games to play at once")
+		pgnOut      = fs.String("pgn", "", "write games to this PGN `file`")
+		event       = fs.String("event", "lento match", "event name for PGN output")
+		sprtElo     = fs.String("sprt", "", "run an SPRT with these `elo0,elo1` bounds")
+		alpha       = fs.Float64("alpha", 0.05, "SPRT false positive rate")
+		beta        = fs.Float64("beta", 0.05, "SPRT false negative rate")
+	)
+	for i := range engines {
+		n := i + 1
+		e := &engines[i]
+		fs.StringVar(&e.Path, fmt.Sprintf("engine%d", n), "", fmt.Sprintf("engine %d executable", n))
+		fs.StringVar(&e.Name, fmt.Sprintf("name%d", n), "", fmt.Sprintf("engine %d name (default: reported by engine)", n))
+		fs.Func(fmt.Sprintf("option%d", n), fmt.Sprintf("set engine %d UCI option, as `name=value` (repeatable)", n), func(s string) error {
+			name, value, ok := strings.Cut(s, "=")
+			if !ok {
+				return fmt.Errorf("bad option: %q", s)
+			}
+			if e.Options == nil {
+				e.Options = make(map[string]string)
+			}
+			e.Options[name] = value
+			return nil
+		})
+	}
+	fs.Parse(args)
+
+	if engines[0].Path == "" || engines[1].Path == "" {
+		return errors.New("match: -engine1 and -engine2 are required")
+	}
+
+	timeControl, err := match.ParseTimeControl(*tc)
+	if err != nil {
+		return err
+	}
+
+	cfg := match.Config{
+		Engines:     engines,
+		Games:       *games,
+		TimeControl: timeControl,
+		TimeMargin:  *timeMargin,
+		Concurrency: *concurrency,
+		Event:       *event,
+	}
+
+	if *openings != "" {
+		cfg.Openings, err = readOpenings(*openings, *plies)
+		if err != nil {
+			return err
+		}
+	}
+
+	if *sprtElo != "" {
+		var sprt match.SPRT
+		if _, err := fmt.Sscanf(*sprtElo, "%g,%g", &sprt.Elo0, &sprt.Elo1); err != nil {
+			return fmt.Errorf("bad SPRT bounds: %q", *sprtElo)
+		}
+		sprt.Alpha, sprt.Beta = *alpha, *beta
+		cfg.SPRT = &sprt
+	}
+
+	var out *bufio.Writer
+	if *pgnOut != "" {
+		f, err := os.Create(*pgnOut)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		out = bufio.NewWriter(f)
+		defer out.Flush()
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	var names [2]string
+	report := func(g match.Game, s match.Stats) {
+		white, _ := g.PGN.Tag("White")
+		black, _ := g.PGN.Tag("Black")
+		if g.FirstIsWhite {
+			names = [2]string{white, black}
+		} else {
+			names = [2]string{black, white}
+		}
+
+		fmt.Printf("Finished game %d (%s vs %s): %v {%s}\n", g.Round, white, black, g.PGN.Result, g.Reason)
+		printStats(names, s, cfg.SPRT)
+
+		if out != nil {
+			out.WriteString(pgn.Encode(g.PGN))
+		}
+	}
+
+	stats, err := match.Run(ctx, cfg, report)
+	if err != nil {
+		return err
+	}
+
+	if cfg.SPRT != nil {
+		fmt.Printf("SPRT: %v\n", cfg.SPRT.Verdict(stats))
+	}
+
+	return nil
+}
+
+func printStats(names [2]string, s match.Stats, sprt *match.SPRT) {
+	fmt.Printf("Score of %s vs %s: %d - %d - %d [%.3f] %d\n",
+		names[0], names[1], s.Wins, s.Losses, s.Draws, s.Score(), s.Games())
+
+	elo, margin := s.Elo()
+	fmt.Printf("Elo difference: %.1f +/- %.1f, LOS: %.1f %%\n", elo, margin, 100*s.LOS())
+
+	if sprt != nil {
+		lower, upper := sprt.Bounds()
+		fmt.Printf("SPRT: llr %.3f, lbound %.3f, ubound %.3f\n", sprt.LLR(s), lower, upper)
+	}
+}
+
+// readOpenings reads starting positions from an EPD or PGN file. Each game
+// in a PGN file gives the position after its moves, or after the first plies
+// of them if plies is positive.
+func readOpenings(name string, plies int) ([]core.Position, error) {
+	if strings.EqualFold(filepath.Ext(name), ".pgn") {
+		return readPGNOpenings(name, plies)
+	}
+
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var res []core.Position
+	s := bufio.NewScanner(f)
+	for n := 1; s.Scan(); n++ {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		r, err := epd.Decode(line)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %v", name, n, err)
+		}
+		res = append(res, r.Position)
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	if len(res) == 0 {
+		return nil, fmt.Errorf("%s: no openings", name)
+	}
+
+	return res, nil
+}
+
+// readPGNOpenings reads starting positions from a PGN file, as described by
+// readOpenings.
+func readPGNOpenings(name string, plies int) ([]core.Position, error) {
+	var res []core.Position
+	_, _, err := readGames([]string{name}, func(g pgn.Game) error {
+		p := g.Position
+		for i, m := range g.Moves {
+			if plies > 0 && i >= plies {
+				break
+			}
+			p.Move(m)
+		}
+		res = append(res, p)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(res) == 0 {
+		return nil, fmt.Errorf("%s: no openings", name)
+	}
+	return res, nil
+}
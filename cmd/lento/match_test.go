@@ -0,0 +1,44 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/clfs/lento/encoding/fen"
+)
+
+func TestReadOpenings_PGN(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "openings.pgn")
+	pgn := "1. e4 e5 2. Nf3 *\n\n1. d4 {malformed} 2. Kd3 *\n\n1. c4 *\n"
+	if err := os.WriteFile(name, []byte(pgn), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		plies int
+		want  []string
+	}{
+		{0, []string{
+			"rnbqkbnr/pppp1ppp/8/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R b KQkq - 1 2",
+			"rnbqkbnr/pppppppp/8/8/2P5/8/PP1PPPPP/RNBQKBNR b KQkq c3 0 1",
+		}},
+		{1, []string{
+			"rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 1",
+			"rnbqkbnr/pppppppp/8/8/2P5/8/PP1PPPPP/RNBQKBNR b KQkq c3 0 1",
+		}},
+	} {
+		got, err := readOpenings(name, tc.plies)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(got) != len(tc.want) {
+			t.Fatalf("plies %d: want %d openings, got %d", tc.plies, len(tc.want), len(got))
+		}
+		for i, p := range got {
+			if s := fen.Encode(p); s != tc.want[i] {
+				t.Errorf("plies %d: opening %d: want %s, got %s", tc.plies, i, tc.want[i], s)
+			}
+		}
+	}
+}
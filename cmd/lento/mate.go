@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/clfs/lento/core"
+	"github.com/clfs/lento/encoding/fen"
+	"github.com/clfs/lento/encoding/san"
+	"github.com/clfs/lento/solver"
+)
+
+func runMate(args []string) error {
+	fs := flag.NewFlagSet("mate", flag.ExitOnError)
+	var (
+		cfg     solver.Config
+		variant = fs.String("variant", "chess", "the `variant` the position is from, like crazyhouse or atomic")
+		timeout = fs.Duration("timeout", 0, "give up after this long, or 0 to search until done")
+	)
+	fs.IntVar(&cfg.MaxNodes, "nodes", 10_000_000, "give up after searching this many positions, or 0 for no limit")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: lento mate [flags] fen [maxply]")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if n := fs.NArg(); n != 1 && n != 2 {
+		fs.Usage()
+		return errors.New("mate: want a FEN and an optional max ply")
+	}
+
+	v, err := core.ParseVariant(*variant)
+	if err != nil {
+		return err
+	}
+	p, err := fen.DecodeVariant(fs.Arg(0), v)
+	if err != nil {
+		return err
+	}
+
+	cfg.MaxPly = 9
+	if fs.NArg() == 2 {
+		if cfg.MaxPly, err = strconv.Atoi(fs.Arg(1)); err != nil || cfg.MaxPly <= 0 {
+			return fmt.Errorf("bad max ply: %q", fs.Arg(1))
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	if *timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *timeout)
+		defer cancel()
+	}
+
+	start := time.Now()
+	res, err := solver.Solve(ctx, p, cfg)
+	if err != nil {
+		return err
+	}
+	elapsed := time.Since(start).Round(time.Millisecond)
+
+	switch res.Outcome {
+	case solver.Proven:
+		end := p
+		for _, m := range res.Line {
+			end.Move(m)
+		}
+		kind := "Win"
+		if end.IsCheckmate() {
+			kind = "Mate"
+		}
+		fmt.Printf("%s in %d (%d plies, %d nodes, %v)\n", kind, (len(res.Line)+1)/2, len(res.Line), res.Nodes, elapsed)
+		fmt.Println(formatLine(p, res.Line))
+	case solver.Disproven:
+		fmt.Printf("No forced win within %d plies (%d nodes, %v)\n", cfg.MaxPly, res.Nodes, elapsed)
+	default:
+		fmt.Printf("Gave up after %d nodes (%v)\n", res.Nodes, elapsed)
+	}
+	return nil
+}
+
+// formatLine formats moves played from p in SAN with move numbers, like
+// "1. e4 e5 2. Nf3" or "1... e5 2. Nf3".
+func formatLine(p core.Position, moves []core.Move) string {
+	var tokens []string
+	for i, m := range moves {
+		switch {
+		case p.SideToMove() == core.White:
+			tokens = append(tokens, fmt.Sprintf("%d.", p.FullmoveNumber()))
+		case i == 0:
+			tokens = append(tokens, fmt.Sprintf("%d...", p.FullmoveNumber()))
+		}
+		tokens = append(tokens, san.Encode(p, m))
+		p.Move(m)
+	}
+	return strings.Join(tokens, " ")
+}
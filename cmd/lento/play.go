@@ -0,0 +1,371 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/clfs/lento/core"
+	"github.com/clfs/lento/encoding/fen"
+	"github.com/clfs/lento/encoding/san"
+	"github.com/clfs/lento/render"
+	"github.com/clfs/lento/uci"
+	"github.com/clfs/lento/uci/client"
+)
+
+const playHelp = `Enter a move in SAN (Nf3, exd5, O-O, e8=Q) or UCI notation (g1f3), or:
+  moves          list the legal moves
+  undo           take back a move, or two if the engine replied
+  switch         swap sides with the engine
+  flip           flip the board
+  hint           ask the engine for a move
+  go [seconds]   have the engine play the side to move
+  fen            print the position as FEN
+  new            start a new game
+  help           show this help
+  quit           leave`
+
+func runPlay(args []string) error {
+	fs := flag.NewFlagSet("play", flag.ExitOnError)
+	var (
+		enginePath = fs.String("engine", "", "UCI engine `executable` for hints and replies")
+		movetime   = fs.Duration("movetime", time.Second, "engine thinking time per move")
+		start      = fs.String("fen", "", "start from this `FEN` instead of the standard position")
+		ascii      = fs.Bool("ascii", false, "draw the board with letters instead of Unicode glyphs")
+		ansi       = fs.Bool("ansi", false, "colour the board with ANSI escape sequences")
+		color      = fs.String("color", "white", "the `side` you play when using an engine: white or black")
+	)
+	fs.Parse(args)
+
+	s := &playSession{
+		start:    core.NewPosition(),
+		movetime: *movetime,
+		ascii:    *ascii,
+		ansi:     *ansi,
+	}
+
+	if *start != "" {
+		p, err := fen.Decode(*start)
+		if err != nil {
+			return err
+		}
+		s.start = p
+	}
+
+	switch *color {
+	case "white":
+		s.engineColor = core.Black
+	case "black":
+		s.engineColor = core.White
+		s.flipped = true
+	default:
+		return fmt.Errorf("bad color: %q", *color)
+	}
+
+	if *enginePath != "" {
+		e, err := startEngine(context.Background(), *enginePath, nil)
+		if err != nil {
+			return err
+		}
+		defer e.Close()
+		s.engine = e
+	}
+
+	return s.run(os.Stdin, os.Stdout)
+}
+
+// A playSession is an interactive game in the terminal.
+type playSession struct {
+	start       core.Position
+	game        *core.Game
+	engine      *client.Client // nil if no engine was given
+	engineColor core.Color     // the side the engine plays, if any
+	movetime    time.Duration
+	flipped     bool
+	ascii       bool
+	ansi        bool
+
+	w io.Writer
+}
+
+func (s *playSession) run(r io.Reader, w io.Writer) error {
+	s.w = w
+	if err := s.newGame(); err != nil {
+		return err
+	}
+
+	sc := bufio.NewScanner(r)
+	for {
+		fmt.Fprint(w, "> ")
+		if !sc.Scan() {
+			fmt.Fprintln(w)
+			return sc.Err()
+		}
+
+		fields := strings.Fields(sc.Text())
+		if len(fields) == 0 {
+			continue
+		}
+
+		var err error
+		switch fields[0] {
+		case "quit", "exit":
+			return nil
+		case "help", "?":
+			fmt.Fprintln(w, playHelp)
+		case "moves":
+			s.printMoves()
+		case "undo", "takeback":
+			err = s.undo()
+		case "switch":
+			err = s.switchSides()
+		case "flip":
+			s.flipped = !s.flipped
+			s.printBoard()
+		case "hint":
+			err = s.hint()
+		case "go":
+			err = s.goCmd(fields[1:])
+		case "fen":
+			p := s.game.Position()
+			fmt.Fprintln(w, fen.Encode(p))
+		case "board":
+			s.printBoard()
+		case "new":
+			err = s.newGame()
+		default:
+			err = s.userMove(fields[0])
+		}
+		if err != nil {
+			fmt.Fprintf(w, "error: %v\n", err)
+		}
+	}
+}
+
+func (s *playSession) newGame() error {
+	s.game = core.NewGame(s.start)
+	if s.engine != nil {
+		if err := s.engine.NewGame(context.Background()); err != nil {
+			return err
+		}
+	}
+	s.printBoard()
+	return s.maybeReply()
+}
+
+// userMove plays a move given in SAN or UCI notation.
+func (s *playSession) userMove(str string) error {
+	if s.over() {
+		return errors.New("the game is over; use undo or new")
+	}
+	p := s.game.Position()
+	m, err := core.ParseMove(str)
+	if err != nil || !p.IsLegal(m) {
+		if m, err = san.Decode(p, str); err != nil {
+			return fmt.Errorf("%v (try \"moves\")", err)
+		}
+	}
+	s.play(m)
+	return s.maybeReply()
+}
+
+// play plays a legal move and shows the result.
+func (s *playSession) play(m core.Move) {
+	p := s.game.Position()
+	n, stm := p.FullmoveNumber(), p.SideToMove()
+	str := san.Encode(p, m)
+	s.game.Move(m)
+
+	s.printBoard()
+	if stm == core.White {
+		fmt.Fprintf(s.w, "%d. %s\n", n, str)
+	} else {
+		fmt.Fprintf(s.w, "%d... %s\n", n, str)
+	}
+	if r, t := s.game.Result(); r != core.NoResult {
+		fmt.Fprintf(s.w, "%v {%v}\n", r, t)
+	}
+}
+
+// over returns true if the game has ended.
+func (s *playSession) over() bool {
+	r, _ := s.game.Result()
+	return r != core.NoResult
+}
+
+// maybeReply has the engine move if it is the engine's turn.
+func (s *playSession) maybeReply() error {
+	p := s.game.Position()
+	if s.engine == nil || s.over() || p.SideToMove() != s.engineColor {
+		return nil
+	}
+	return s.engineMove(s.movetime)
+}
+
+// engineMove has the engine search for d and plays its move.
+func (s *playSession) engineMove(d time.Duration) error {
+	info, res, err := s.think(d)
+	if err != nil {
+		return err
+	}
+	p := s.game.Position()
+	if !p.IsLegal(res.BestMove) {
+		return fmt.Errorf("engine: illegal move: %v", res.BestMove)
+	}
+	s.play(res.BestMove)
+	s.printScore(info, p.SideToMove())
+	return nil
+}
+
+// think runs an engine search of the current position, returning the last
+// reported information and the result.
+func (s *playSession) think(d time.Duration) (uci.Info, uci.Result, error) {
+	if s.engine == nil {
+		return uci.Info{}, uci.Result{}, errors.New("no engine; use -engine")
+	}
+	if err := s.engine.SetPosition(s.game.StartingPosition(), s.game.Moves()); err != nil {
+		return uci.Info{}, uci.Result{}, err
+	}
+	search, err := s.engine.Go(context.Background(), uci.Limits{MoveTime: d})
+	if err != nil {
+		return uci.Info{}, uci.Result{}, err
+	}
+
+	var last uci.Info
+	for info := range search.Info {
+		if info.Score != nil && len(info.PV) > 0 {
+			last = info
+		}
+	}
+	res, err := search.Wait()
+	return last, res, err
+}
+
+// printScore prints the engine's evaluation, if it reported one. UCI scores
+// are from the point of view of the side to move, c, so that side is named.
+func (s *playSession) printScore(info uci.Info, c core.Color) {
+	sc := info.Score
+	switch {
+	case sc == nil:
+	case sc.Mate > 0:
+		fmt.Fprintf(s.w, "(%s mates in %d, depth %d)\n", colorName(c), sc.Mate, info.Depth)
+	case sc.Mate < 0:
+		fmt.Fprintf(s.w, "(%s is mated in %d, depth %d)\n", colorName(c), -sc.Mate, info.Depth)
+	default:
+		fmt.Fprintf(s.w, "(%+.2f for %s, depth %d)\n", float64(sc.CP)/100, colorName(c), info.Depth)
+	}
+}
+
+func (s *playSession) undo() error {
+	if !s.game.Undo() {
+		return errors.New("no move to take back")
+	}
+	// Take back the engine's reply too, so it's the user's turn again. If
+	// the engine made the first move, it plays again instead.
+	p := s.game.Position()
+	if s.engine != nil && p.SideToMove() == s.engineColor && !s.game.Undo() {
+		s.printBoard()
+		return s.maybeReply()
+	}
+	s.printBoard()
+	return nil
+}
+
+func (s *playSession) switchSides() error {
+	if s.engine == nil {
+		return errors.New("no engine; use -engine")
+	}
+	s.engineColor = !s.engineColor
+	s.flipped = !s.flipped
+	s.printBoard()
+	return s.maybeReply()
+}
+
+func (s *playSession) hint() error {
+	if s.over() {
+		return errors.New("the game is over")
+	}
+	info, res, err := s.think(s.movetime)
+	if err != nil {
+		return err
+	}
+	p := s.game.Position()
+	if !p.IsLegal(res.BestMove) {
+		return fmt.Errorf("engine: illegal move: %v", res.BestMove)
+	}
+	fmt.Fprintf(s.w, "hint: %s ", san.Encode(p, res.BestMove))
+	s.printScore(info, p.SideToMove())
+	if info.Score == nil {
+		fmt.Fprintln(s.w)
+	}
+	return nil
+}
+
+// goCmd has the engine play the side to move, optionally for a number of
+// seconds.
+func (s *playSession) goCmd(args []string) error {
+	if s.over() {
+		return errors.New("the game is over")
+	}
+	d := s.movetime
+	if len(args) > 0 {
+		secs, err := strconv.ParseFloat(args[0], 64)
+		if err != nil || secs <= 0 {
+			return fmt.Errorf("bad time: %q", args[0])
+		}
+		d = time.Duration(secs * float64(time.Second))
+	}
+	return s.engineMove(d)
+}
+
+func (s *playSession) printMoves() {
+	p := s.game.Position()
+	var moves []string
+	for _, m := range p.LegalMoves() {
+		moves = append(moves, san.Encode(p, m))
+	}
+	if len(moves) == 0 {
+		fmt.Fprintln(s.w, "no legal moves")
+		return
+	}
+	fmt.Fprintln(s.w, strings.Join(moves, " "))
+}
+
+func (s *playSession) printBoard() {
+	p := s.game.Position()
+
+	opts := []render.Option{render.WithCoordinates()}
+	if s.flipped {
+		opts = append(opts, render.Flip())
+	}
+	switch {
+	case s.ascii:
+		fmt.Fprint(s.w, render.ASCII(p.Board(), opts...))
+	case s.ansi:
+		opts = append(opts, render.WithColors())
+		if moves := s.game.Moves(); len(moves) > 0 {
+			last := moves[len(moves)-1]
+			opts = append(opts, render.WithHighlights(last.From(), last.To()))
+		}
+		fmt.Fprint(s.w, render.Unicode(p.Board(), opts...))
+	default:
+		fmt.Fprint(s.w, render.Unicode(p.Board(), opts...))
+	}
+
+	fmt.Fprintf(s.w, "%s to move\n", colorName(p.SideToMove()))
+}
+
+// colorName returns "White" or "Black".
+func colorName(c core.Color) string {
+	if c == core.Black {
+		return "Black"
+	}
+	return "White"
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/clfs/lento/core"
+	"github.com/clfs/lento/encoding/fen"
+	"github.com/clfs/lento/render"
+	"github.com/clfs/lento/uci"
+	"github.com/clfs/lento/uci/client"
+)
+
+// If LENTO_FAKE_ENGINE is set, the test binary acts as a UCI engine instead.
+func TestMain(m *testing.M) {
+	if os.Getenv("LENTO_FAKE_ENGINE") != "" {
+		fakeEngine()
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// fakeEngine is a UCI engine that plays the alphabetically first legal move.
+func fakeEngine() {
+	p := core.NewPosition()
+	s := bufio.NewScanner(os.Stdin)
+	for s.Scan() {
+		fields := strings.Fields(s.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		switch fields[0] {
+		case "uci":
+			fmt.Println("id name Fake\nuciok")
+		case "isready":
+			fmt.Println("readyok")
+		case "position":
+			p = core.NewPosition()
+			rest := fields[2:]
+			if fields[1] == "fen" {
+				p = fen.MustDecode(strings.Join(fields[2:8], " "))
+				rest = fields[8:]
+			}
+			for _, str := range rest[min(1, len(rest)):] {
+				m, _ := core.ParseMove(str)
+				p.Move(m)
+			}
+		case "go":
+			moves := p.LegalMoves()
+			slices.SortFunc(moves, func(a, b core.Move) int {
+				return strings.Compare(a.String(), b.String())
+			})
+			if len(moves) == 0 {
+				fmt.Println("bestmove (none)")
+				continue
+			}
+			fmt.Printf("info depth 1 score cp 0 pv %v\nbestmove %v\n", moves[0], moves[0])
+		case "quit":
+			return
+		}
+	}
+}
+
+// playScript runs a session on input, returning its output.
+func playScript(t *testing.T, s *playSession, input string) string {
+	t.Helper()
+	var out strings.Builder
+	if err := s.run(strings.NewReader(input), &out); err != nil {
+		t.Fatal(err)
+	}
+	return out.String()
+}
+
+func newPlaySession(t *testing.T, engine bool) *playSession {
+	t.Helper()
+	s := &playSession{start: core.NewPosition(), engineColor: core.Black, ascii: true}
+	if engine {
+		t.Setenv("LENTO_FAKE_ENGINE", "1")
+		c, err := client.Start(context.Background(), os.Args[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { c.Close() })
+		s.engine = c
+	}
+	return s
+}
+
+// lastFEN returns the last FEN printed in out.
+func lastFEN(t *testing.T, out string) string {
+	t.Helper()
+	lines := strings.Split(out, "\n")
+	for i := len(lines) - 1; i >= 0; i-- {
+		line := strings.TrimPrefix(lines[i], "> ")
+		if _, err := fen.Decode(line); err == nil {
+			return line
+		}
+	}
+	t.Fatalf("no FEN in output:\n%s", out)
+	return ""
+}
+
+func TestPlay_Moves(t *testing.T) {
+	out := playScript(t, newPlaySession(t, false), "e4\ne7e5\nNf3\nfen\n")
+	for _, want := range []string{"1. e4\n", "1... e5\n", "2. Nf3\n"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output lacks %q", want)
+		}
+	}
+	if got, want := lastFEN(t, out), "rnbqkbnr/pppp1ppp/8/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R b KQkq - 1 2"; got != want {
+		t.Errorf("want %s, got %s", want, got)
+	}
+}
+
+func TestPlay_IllegalInput(t *testing.T) {
+	out := playScript(t, newPlaySession(t, false), "Ke2\ne2e5\nnonsense\nhint\nswitch\nfen\n")
+	if n := strings.Count(out, "error: "); n != 5 {
+		t.Errorf("want 5 errors, got %d:\n%s", n, out)
+	}
+	if got, want := lastFEN(t, out), fen.Encode(core.NewPosition()); got != want {
+		t.Errorf("want %s, got %s", want, got)
+	}
+}
+
+func TestPlay_Undo(t *testing.T) {
+	out := playScript(t, newPlaySession(t, false), "undo\ne4\nd5\nundo\nfen\n")
+	if !strings.Contains(out, "error: no move to take back") {
+		t.Error("undo at the start: no error")
+	}
+	if got, want := lastFEN(t, out), "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 1"; got != want {
+		t.Errorf("want %s, got %s", want, got)
+	}
+}
+
+func TestPlay_UndoEngineReply(t *testing.T) {
+	// The engine answers e4 with a5, the first move alphabetically, and
+	// undo takes back both.
+	out := playScript(t, newPlaySession(t, true), "e4\nundo\nfen\n")
+	if !strings.Contains(out, "1... a5\n") {
+		t.Errorf("no engine reply:\n%s", out)
+	}
+	if got, want := lastFEN(t, out), fen.Encode(core.NewPosition()); got != want {
+		t.Errorf("want %s, got %s", want, got)
+	}
+}
+
+func TestPlay_UndoEngineFirstMove(t *testing.T) {
+	s := newPlaySession(t, true)
+	s.engineColor = core.White
+	out := playScript(t, s, "undo\nfen\n")
+	if n := strings.Count(out, "1. a3\n"); n != 2 {
+		t.Errorf("want the engine to play 1. a3 twice, got %d times:\n%s", n, out)
+	}
+	if got, want := lastFEN(t, out), "rnbqkbnr/pppppppp/8/8/8/P7/1PPPPPPP/RNBQKBNR b KQkq - 0 1"; got != want {
+		t.Errorf("want %s, got %s", want, got)
+	}
+}
+
+func TestPlay_Switch(t *testing.T) {
+	out := playScript(t, newPlaySession(t, true), "switch\nfen\n")
+	if !strings.Contains(out, "1. a3\n") {
+		t.Errorf("engine didn't move after switch:\n%s", out)
+	}
+	if got, want := lastFEN(t, out), "rnbqkbnr/pppppppp/8/8/8/P7/1PPPPPPP/RNBQKBNR b KQkq - 0 1"; got != want {
+		t.Errorf("want %s, got %s", want, got)
+	}
+}
+
+func TestPlay_Flip(t *testing.T) {
+	out := playScript(t, newPlaySession(t, false), "flip\n")
+	b := core.NewBoard()
+	for _, want := range []string{
+		render.ASCII(b, render.WithCoordinates()),
+		render.ASCII(b, render.WithCoordinates(), render.Flip()),
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output lacks board:\n%s", want)
+		}
+	}
+}
+
+func TestPlaySession_PrintScore(t *testing.T) {
+	cases := []struct {
+		score uci.Score
+		c     core.Color
+		want  string
+	}{
+		{uci.Score{CP: 35}, core.White, "(+0.35 for White, depth 9)\n"},
+		{uci.Score{CP: -120}, core.Black, "(-1.20 for Black, depth 9)\n"},
+		{uci.Score{Mate: 3}, core.Black, "(Black mates in 3, depth 9)\n"},
+		{uci.Score{Mate: -2}, core.White, "(White is mated in 2, depth 9)\n"},
+	}
+	for _, tc := range cases {
+		var out strings.Builder
+		s := &playSession{w: &out}
+		s.printScore(uci.Info{Depth: 9, Score: &tc.score}, tc.c)
+		if got := out.String(); got != tc.want {
+			t.Errorf("%+v: want %q, got %q", tc.score, tc.want, got)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/clfs/lento/encoding/fen"
+	"github.com/clfs/lento/encoding/pgn"
+	"github.com/clfs/lento/puzzle"
+)
+
+func runPuzzles(args []string) error {
+	fs := flag.NewFlagSet("puzzles", flag.ExitOnError)
+	var (
+		cfg        puzzle.Config
+		enginePath = fs.String("engine", "", "UCI engine `executable` to analyze with")
+		options    = optionFlag(fs)
+		out        = fs.String("o", "", "write puzzles to this CSV `file` (default standard output)")
+	)
+	fs.DurationVar(&cfg.Limits.MoveTime, "movetime", time.Second, "search time per position, unless -depth is set")
+	fs.IntVar(&cfg.Limits.Depth, "depth", 0, "search each position to this depth instead")
+	fs.IntVar(&cfg.MinScore, "minscore", 200, "least score, in centipawns, at which a move is winning")
+	fs.IntVar(&cfg.MaxMoves, "maxmoves", 5, "most moves for the solver in a solution")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: lento puzzles [flags] file.pgn...")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if *enginePath == "" {
+		return errors.New("puzzles: -engine is required")
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return errors.New("puzzles: no PGN files")
+	}
+	if cfg.Limits.Depth > 0 {
+		cfg.Limits.MoveTime = 0
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	e, err := startEngine(ctx, *enginePath, options)
+	if err != nil {
+		return err
+	}
+	defer e.Close()
+	f, err := puzzle.NewFinder(e, cfg)
+	if err != nil {
+		return fmt.Errorf("%s: %v", *enginePath, err)
+	}
+
+	var w io.Writer = os.Stdout
+	if *out != "" {
+		file, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		w = file
+	}
+	cw := csv.NewWriter(w)
+	defer cw.Flush()
+	cw.Write([]string{"FEN", "Moves", "Themes"})
+
+	var found int
+	read, bad, err := readGames(fs.Args(), func(g pgn.Game) error {
+		puzzles, err := f.Find(ctx, g)
+		if err != nil {
+			return err
+		}
+		for _, pz := range puzzles {
+			moves := make([]string, len(pz.Moves))
+			for i, m := range pz.Moves {
+				moves[i] = m.String()
+			}
+			cw.Write([]string{fen.Encode(pz.Position), strings.Join(moves, " "), strings.Join(pz.Themes, " ")})
+		}
+		cw.Flush()
+		found += len(puzzles)
+		return cw.Error()
+	})
+	log.Printf("read %d games (%d malformed), found %d puzzles", read, bad, found)
+	return err
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/clfs/lento/analysis"
+)
+
+func runServe(args []string) error {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	var (
+		cfg  analysis.Config
+		addr = fs.String("addr", "localhost:8080", "listen on this `address`")
+	)
+	fs.StringVar(&cfg.Path, "engine", "", "UCI engine `executable` to analyze with")
+	cfg.Options = optionFlag(fs)
+	fs.IntVar(&cfg.Engines, "engines", 2, "number of engine processes, and so of concurrent searches")
+	fs.DurationVar(&cfg.MoveTime, "movetime", time.Second, "search time for requests without limits")
+	fs.DurationVar(&cfg.MaxMoveTime, "maxtime", 30*time.Second, "maximum search time per request, or 0 for none")
+	fs.IntVar(&cfg.MaxMultiPV, "maxmultipv", 5, "maximum lines per request, or 0 for no limit")
+	fs.Parse(args)
+
+	if cfg.Path == "" {
+		return errors.New("serve: -engine is required")
+	}
+
+	a := analysis.NewServer(cfg)
+	defer a.Close()
+
+	mux := http.NewServeMux()
+	mux.Handle("/analyze", a)
+	srv := &http.Server{Addr: *addr, Handler: mux}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	errc := make(chan error, 1)
+	go func() {
+		log.Printf("serving analysis on http://%s/analyze", *addr)
+		errc <- srv.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return srv.Shutdown(shutdownCtx)
+}
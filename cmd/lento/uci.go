@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/clfs/lento/uci/client"
+)
+
+// optionFlag defines a repeatable -option flag on fs, and returns the map it
+// collects UCI options into.
+func optionFlag(fs *flag.FlagSet) map[string]string {
+	options := make(map[string]string)
+	fs.Func("option", "set a UCI option, as `name=value` (repeatable)", func(s string) error {
+		name, value, ok := strings.Cut(s, "=")
+		if !ok {
+			return fmt.Errorf("bad option: %q", s)
+		}
+		options[name] = value
+		return nil
+	})
+	return options
+}
+
+// startEngine starts the UCI engine at path, giving it 10 seconds for the
+// handshake, and sets its options.
+func startEngine(ctx context.Context, path string, options map[string]string) (*client.Client, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	e, err := client.Start(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	for name, value := range options {
+		if err := e.SetOption(name, value); err != nil {
+			e.Close()
+			return nil, err
+		}
+	}
+	return e, nil
+}
@@ -0,0 +1,115 @@
+package core
+
+import "math/bits"
+
+// Precomputed attack tables for leaping pieces.
+var (
+	knightAttacks [64]Bitboard
+	kingAttacks   [64]Bitboard
+	pawnAttacks   [2][64]Bitboard // indexed by color, then square
+)
+
+// Ray directions, as file and rank deltas.
+var (
+	bishopDirections = [4][2]int{{1, 1}, {1, -1}, {-1, 1}, {-1, -1}}
+	rookDirections   = [4][2]int{{1, 0}, {-1, 0}, {0, 1}, {0, -1}}
+)
+
+func init() {
+	for s := A1; s <= H8; s++ {
+		for _, d := range [8][2]int{{1, 2}, {2, 1}, {2, -1}, {1, -2}, {-1, -2}, {-2, -1}, {-2, 1}, {-1, 2}} {
+			if t, ok := offset(s, d[0], d[1]); ok {
+				knightAttacks[s].Set(t)
+			}
+		}
+		for _, d := range [8][2]int{{1, 1}, {1, 0}, {1, -1}, {0, -1}, {-1, -1}, {-1, 0}, {-1, 1}, {0, 1}} {
+			if t, ok := offset(s, d[0], d[1]); ok {
+				kingAttacks[s].Set(t)
+			}
+		}
+		for _, df := range []int{-1, 1} {
+			if t, ok := offset(s, df, 1); ok {
+				pawnAttacks[White.index()][s].Set(t)
+			}
+			if t, ok := offset(s, df, -1); ok {
+				pawnAttacks[Black.index()][s].Set(t)
+			}
+		}
+	}
+}
+
+// offset returns the square df files and dr ranks away from s, if it is on
+// the board.
+func offset(s Square, df, dr int) (Square, bool) {
+	f, r := int(s.File())+df, int(s.Rank())+dr
+	if f < 0 || f > 7 || r < 0 || r > 7 {
+		return 0, false
+	}
+	return NewSquare(File(f), Rank(r)), true
+}
+
+// rayAttacks returns the squares attacked by a slider on s moving in the
+// given directions, stopping at the first occupied square in each direction.
+func rayAttacks(s Square, occupied Bitboard, directions [4][2]int) Bitboard {
+	var b Bitboard
+	for _, d := range directions {
+		t, ok := offset(s, d[0], d[1])
+		for ok {
+			b.Set(t)
+			if occupied.Get(t) {
+				break
+			}
+			t, ok = offset(t, d[0], d[1])
+		}
+	}
+	return b
+}
+
+func bishopAttacks(s Square, occupied Bitboard) Bitboard {
+	return rayAttacks(s, occupied, bishopDirections)
+}
+
+func rookAttacks(s Square, occupied Bitboard) Bitboard {
+	return rayAttacks(s, occupied, rookDirections)
+}
+
+// Attacks returns the squares attacked by p on s, given the occupied squares.
+// Sliding attacks stop at, and include, the first occupied square in each
+// direction. Pawn attacks are the diagonal captures only.
+func Attacks(p Piece, s Square, occupied Bitboard) Bitboard {
+	switch p.Type() {
+	case Pawn:
+		return pawnAttacks[p.Color().index()][s]
+	case Knight:
+		return knightAttacks[s]
+	case Bishop:
+		return bishopAttacks(s, occupied)
+	case Rook:
+		return rookAttacks(s, occupied)
+	case Queen:
+		return bishopAttacks(s, occupied) | rookAttacks(s, occupied)
+	default:
+		return kingAttacks[s]
+	}
+}
+
+// index returns 0 for White and 1 for Black.
+func (c Color) index() int {
+	if c == White {
+		return 0
+	}
+	return 1
+}
+
+// Count returns the number of bits set to 1.
+func (b Bitboard) Count() int {
+	return bits.OnesCount64(uint64(b))
+}
+
+// pop clears the lowest set bit and returns its square.
+// It is invalid to call pop on an empty bitboard.
+func (b *Bitboard) pop() Square {
+	s := Square(bits.TrailingZeros64(uint64(*b)))
+	*b &= *b - 1
+	return s
+}
@@ -1,6 +1,8 @@
 // Package core implements basic chess functionality.
 package core
 
+import "fmt"
+
 // A Color represents either [White] or [Black].
 type Color bool
 
@@ -204,3 +206,16 @@ func (s Square) Above() Square {
 func (s Square) Below() Square {
 	return s - 8
 }
+
+// String returns the square in algebraic notation, like "e4".
+func (s Square) String() string {
+	return string([]byte{'a' + byte(s.File()), '1' + byte(s.Rank())})
+}
+
+// ParseSquare parses a square in algebraic notation, like "e4".
+func ParseSquare(s string) (Square, error) {
+	if len(s) != 2 || s[0] < 'a' || s[0] > 'h' || s[1] < '1' || s[1] > '8' {
+		return 0, fmt.Errorf("bad square: %q", s)
+	}
+	return NewSquare(File(s[0]-'a'), Rank(s[1]-'1')), nil
+}
@@ -0,0 +1,24 @@
+package core
+
+import "testing"
+
+func TestParseSquare(t *testing.T) {
+	for s := A1; s <= H8; s++ {
+		got, err := ParseSquare(s.String())
+		if err != nil {
+			t.Errorf("%v: error: %v", s, err)
+			continue
+		}
+		if s != got {
+			t.Errorf("%v: changed in round trip: %v", s, got)
+		}
+	}
+}
+
+func TestParseSquare_Invalid(t *testing.T) {
+	for _, s := range []string{"", "e", "e0", "e9", "i1", "E4", "e44"} {
+		if _, err := ParseSquare(s); err == nil {
+			t.Errorf("%q: no error", s)
+		}
+	}
+}
@@ -0,0 +1,225 @@
+package core
+
+import "fmt"
+
+// A Result is the result of a game.
+type Result uint8
+
+// [Result] constants.
+const (
+	NoResult Result = iota
+	WhiteWins
+	BlackWins
+	Draw
+)
+
+// String returns the result as written in PGN, like "1-0", or "*" if there is
+// no result.
+func (r Result) String() string {
+	switch r {
+	case WhiteWins:
+		return "1-0"
+	case BlackWins:
+		return "0-1"
+	case Draw:
+		return "1/2-1/2"
+	default:
+		return "*"
+	}
+}
+
+// A Termination is the reason a game ended.
+type Termination uint8
+
+// [Termination] constants.
+const (
+	NoTermination Termination = iota
+	Checkmate
+	Stalemate
+	FiftyMoveRule
+	ThreefoldRepetition
+	InsufficientMaterial
+	// VariantEnd means the game was decided by the variant's own rules. See
+	// [Position.VariantResult].
+	VariantEnd
+)
+
+// String returns a description of the termination, like "checkmate".
+func (t Termination) String() string {
+	switch t {
+	case Checkmate:
+		return "checkmate"
+	case Stalemate:
+		return "stalemate"
+	case FiftyMoveRule:
+		return "fifty-move rule"
+	case ThreefoldRepetition:
+		return "threefold repetition"
+	case InsufficientMaterial:
+		return "insufficient material"
+	case VariantEnd:
+		return "variant rules"
+	default:
+		return "none"
+	}
+}
+
+// A Game is a sequence of legal moves from a starting position.
+//
+// The zero value of Game is not usable; use [NewGame].
+type Game struct {
+	// positions[0] is the starting position, and positions[i+1] is the
+	// position after moves[i].
+	positions []Position
+	moves     []Move
+}
+
+// NewGame returns a new game starting from p.
+func NewGame(p Position) *Game {
+	return &Game{positions: []Position{p}}
+}
+
+// Position returns the current position.
+func (g *Game) Position() Position {
+	return g.positions[len(g.positions)-1]
+}
+
+// StartingPosition returns the position the game started from.
+func (g *Game) StartingPosition() Position {
+	return g.positions[0]
+}
+
+// Moves returns the moves played so far.
+func (g *Game) Moves() []Move {
+	return append([]Move(nil), g.moves...)
+}
+
+// Move plays a move, returning an error if it is illegal.
+func (g *Game) Move(m Move) error {
+	p := g.Position()
+	if !p.IsLegal(m) {
+		return fmt.Errorf("illegal move: %v", m)
+	}
+	p.Move(m)
+	g.positions = append(g.positions, p)
+	g.moves = append(g.moves, m)
+	return nil
+}
+
+// Undo takes back the last move. It returns false if there is no move to take
+// back.
+func (g *Game) Undo() bool {
+	if len(g.moves) == 0 {
+		return false
+	}
+	g.positions = g.positions[:len(g.positions)-1]
+	g.moves = g.moves[:len(g.moves)-1]
+	return true
+}
+
+// Result returns the result of the game and the reason it ended, or
+// [NoResult] and [NoTermination] if it is still in progress.
+//
+// Draws by the fifty-move rule and threefold repetition are applied
+// automatically, as if claimed by the player to move.
+func (g *Game) Result() (Result, Termination) {
+	p := g.Position()
+
+	if r := p.VariantResult(); r != NoResult {
+		return r, VariantEnd
+	}
+
+	if len(p.LegalMoves()) == 0 {
+		if !p.InCheck() {
+			return Draw, Stalemate
+		}
+		if p.SideToMove() == White {
+			return BlackWins, Checkmate
+		}
+		return WhiteWins, Checkmate
+	}
+
+	switch {
+	case p.HalfmoveClock() >= 100:
+		return Draw, FiftyMoveRule
+	case g.repetitions() >= 3:
+		return Draw, ThreefoldRepetition
+	case p.hasInsufficientMaterial():
+		return Draw, InsufficientMaterial
+	}
+
+	return NoResult, NoTermination
+}
+
+// repetitions returns the number of times the current position has occurred.
+//
+// Positions are considered identical if they have the same board, side to
+// move, castling rights and en passant target, and in [Crazyhouse], the same
+// pockets and promoted pieces, or in [ThreeCheck], the same check counts.
+func (g *Game) repetitions() int {
+	cur := g.Position()
+	n := 0
+	// Only positions since the last capture or pawn move can repeat.
+	for i := len(g.positions) - 1; i >= 0 && i >= len(g.positions)-1-cur.hmc; i-- {
+		p := g.positions[i]
+		if p.board == cur.board && p.sideToMove == cur.sideToMove &&
+			p.cr == cur.cr && p.ep == cur.ep &&
+			p.pockets == cur.pockets && p.promoted == cur.promoted &&
+			p.checks == cur.checks {
+			n++
+		}
+	}
+	return n
+}
+
+// lightSquares are the light squares of the board.
+const lightSquares Bitboard = 0x55aa55aa55aa55aa
+
+// hasInsufficientMaterial returns true if neither side can possibly
+// checkmate: king against king, king and minor piece against king, or kings
+// and bishops that are all on squares of the same color.
+//
+// In [Crazyhouse], material is never insufficient, since captured pieces come
+// back, and neither is it in [KingOfTheHill] or [RacingKings], where kings can
+// walk to their goal, or in [Horde]. In [ThreeCheck], any piece can give
+// check, so only bare kings are insufficient. In [Atomic], a lone minor piece
+// against a bare king is insufficient too. In [Antichess], only a lone bishop
+// on each side, on squares of different colors, is insufficient, since
+// neither can ever be captured.
+func (p *Position) hasInsufficientMaterial() bool {
+	b := &p.board
+
+	switch p.variant {
+	case Crazyhouse, KingOfTheHill, Horde, RacingKings:
+		return false
+	case ThreeCheck:
+		return b.occupancy() == b.occupied[WhiteKing]|b.occupied[BlackKing]
+	case Atomic:
+		kings := b.occupied[WhiteKing] | b.occupied[BlackKing]
+		minors := b.occupied[WhiteKnight] | b.occupied[BlackKnight] |
+			b.occupied[WhiteBishop] | b.occupied[BlackBishop]
+		return b.occupancy()&^kings == minors && minors.Count() <= 1
+	case Antichess:
+		w, bl := b.occupied[WhiteBishop], b.occupied[BlackBishop]
+		return b.occupancy() == w|bl && w.Count() == 1 && bl.Count() == 1 &&
+			(w&lightSquares == 0) != (bl&lightSquares == 0)
+	}
+
+	for _, pt := range []PieceType{Pawn, Rook, Queen} {
+		if b.occupied[NewPiece(White, pt)]|b.occupied[NewPiece(Black, pt)] != 0 {
+			return false
+		}
+	}
+
+	knights := b.occupied[WhiteKnight] | b.occupied[BlackKnight]
+	bishops := b.occupied[WhiteBishop] | b.occupied[BlackBishop]
+
+	if minors := (knights | bishops).Count(); minors <= 1 {
+		return true
+	}
+	if knights != 0 {
+		return false
+	}
+
+	return bishops&lightSquares == 0 || bishops&^lightSquares == 0
+}
@@ -0,0 +1,85 @@
+package core_test
+
+import (
+	"testing"
+
+	"github.com/clfs/lento/core"
+	"github.com/clfs/lento/encoding/fen"
+)
+
+func playMoves(t *testing.T, g *core.Game, moves ...string) {
+	t.Helper()
+	for _, s := range moves {
+		m, err := core.ParseMove(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := g.Move(m); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestGame_Result(t *testing.T) {
+	cases := []struct {
+		name  string
+		fen   string
+		moves []string
+		want  core.Result
+		term  core.Termination
+	}{
+		{"in progress", fen.Starting, []string{"e2e4"}, core.NoResult, core.NoTermination},
+		{"checkmate", fen.Starting, []string{"f2f3", "e7e5", "g2g4", "d8h4"}, core.BlackWins, core.Checkmate},
+		{"stalemate", "7k/8/5Q2/6K1/8/8/8/8 w - - 0 1", []string{"f6f7"}, core.Draw, core.Stalemate},
+		{"fifty-move rule", "7k/8/8/8/8/8/8/R6K w - - 99 80", []string{"a1a2"}, core.Draw, core.FiftyMoveRule},
+		{
+			"threefold repetition",
+			fen.Starting,
+			[]string{"g1f3", "g8f6", "f3g1", "f6g8", "g1f3", "g8f6", "f3g1", "f6g8"},
+			core.Draw, core.ThreefoldRepetition,
+		},
+		{"two repetitions", fen.Starting, []string{"g1f3", "g8f6", "f3g1", "f6g8"}, core.NoResult, core.NoTermination},
+		{"king vs king", "7k/8/8/8/8/8/8/7K w - - 0 1", nil, core.Draw, core.InsufficientMaterial},
+		{"king and knight", "7k/8/8/8/8/8/8/6NK w - - 0 1", nil, core.Draw, core.InsufficientMaterial},
+		{"same-color bishops", "6bk/8/8/8/8/8/8/5B1K w - - 0 1", nil, core.Draw, core.InsufficientMaterial},
+		{"opposite-color bishops", "5b1k/8/8/8/8/8/8/5B1K w - - 0 1", nil, core.NoResult, core.NoTermination},
+		{"two knights", "7k/8/8/8/8/8/8/5NNK w - - 0 1", nil, core.NoResult, core.NoTermination},
+	}
+	for _, c := range cases {
+		g := core.NewGame(fen.MustDecode(c.fen))
+		playMoves(t, g, c.moves...)
+		if got, term := g.Result(); c.want != got || c.term != term {
+			t.Errorf("%s: want %v (%v), got %v (%v)", c.name, c.want, c.term, got, term)
+		}
+	}
+}
+
+func TestGame_Move_Illegal(t *testing.T) {
+	g := core.NewGame(core.NewPosition())
+	if err := g.Move(core.NewMove(core.E2, core.E5)); err == nil {
+		t.Error("no error")
+	}
+	if n := len(g.Moves()); n != 0 {
+		t.Errorf("want no moves, got %d", n)
+	}
+}
+
+func TestGame_Undo(t *testing.T) {
+	g := core.NewGame(core.NewPosition())
+	if g.Undo() {
+		t.Error("undo succeeded with no moves")
+	}
+
+	playMoves(t, g, "e2e4", "e7e5")
+	if !g.Undo() {
+		t.Fatal("undo failed")
+	}
+
+	want := "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 1"
+	if got := fen.Encode(g.Position()); want != got {
+		t.Errorf("want %q, got %q", want, got)
+	}
+	if n := len(g.Moves()); n != 1 {
+		t.Errorf("want 1 move, got %d", n)
+	}
+}
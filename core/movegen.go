@@ -0,0 +1,339 @@
+package core
+
+// Bitboard returns the locations of all pieces equal to p.
+func (b *Board) Bitboard(p Piece) Bitboard {
+	return b.occupied[p]
+}
+
+// colorOccupancy returns the locations of all pieces of color c.
+func (b *Board) colorOccupancy(c Color) Bitboard {
+	var res Bitboard
+	for pt := Pawn; pt <= King; pt++ {
+		res |= b.occupied[NewPiece(c, pt)]
+	}
+	return res
+}
+
+// occupancy returns the locations of all pieces.
+func (b *Board) occupancy() Bitboard {
+	return b.colorOccupancy(White) | b.colorOccupancy(Black)
+}
+
+// IsAttacked returns true if any piece of color c attacks s.
+func (b *Board) IsAttacked(s Square, c Color) bool {
+	occupied := b.occupancy()
+
+	if pawnAttacks[c.Other().index()][s]&b.occupied[NewPiece(c, Pawn)] != 0 {
+		return true
+	}
+	if knightAttacks[s]&b.occupied[NewPiece(c, Knight)] != 0 {
+		return true
+	}
+	if kingAttacks[s]&b.occupied[NewPiece(c, King)] != 0 {
+		return true
+	}
+
+	queens := b.occupied[NewPiece(c, Queen)]
+	if bishopAttacks(s, occupied)&(b.occupied[NewPiece(c, Bishop)]|queens) != 0 {
+		return true
+	}
+	if rookAttacks(s, occupied)&(b.occupied[NewPiece(c, Rook)]|queens) != 0 {
+		return true
+	}
+
+	return false
+}
+
+// InCheck returns true if the side to move's king is attacked.
+func (p *Position) InCheck() bool {
+	return p.kingAttacked(p.sideToMove)
+}
+
+// kingAttacked returns true if c's king is attacked. If c has no king, it
+// returns false.
+//
+// In [Atomic], a king next to the enemy king is never attacked, since
+// capturing it would explode both. In [Antichess], there is no check, so kings
+// are never attacked.
+func (p *Position) kingAttacked(c Color) bool {
+	kings := p.board.occupied[NewPiece(c, King)]
+	if kings == 0 || p.variant == Antichess {
+		return false
+	}
+	s := kings.pop()
+	if p.variant == Atomic && kingAttacks[s]&p.board.occupied[NewPiece(c.Other(), King)] != 0 {
+		return false
+	}
+	return p.board.IsAttacked(s, c.Other())
+}
+
+// isLegalAfter returns true if p, reached by a move of c's, is legal. c's king
+// must not be attacked, except in [Atomic], where c must still have a king,
+// and exploding the enemy king wins regardless. In [RacingKings], the enemy
+// king must not be attacked either.
+func (p *Position) isLegalAfter(c Color) bool {
+	if p.variant == RacingKings && p.kingAttacked(c.Other()) {
+		return false
+	}
+	if p.variant == Atomic {
+		switch {
+		case p.board.occupied[NewPiece(c, King)] == 0:
+			return false
+		case p.board.occupied[NewPiece(c.Other(), King)] == 0:
+			return true
+		}
+	}
+	return !p.kingAttacked(c)
+}
+
+// LegalMoves returns all legal moves.
+func (p *Position) LegalMoves() []Move {
+	if p.variant == Antichess {
+		return p.antichessMoves()
+	}
+	if p.VariantResult() != NoResult {
+		return nil
+	}
+	return p.legalMoves()
+}
+
+// legalMoves returns all legal moves, ignoring whether the game was decided
+// by the variant's own rules.
+func (p *Position) legalMoves() []Move {
+	var res []Move
+	for _, m := range p.pseudoLegalMoves() {
+		q := *p
+		q.Move(m)
+		if q.isLegalAfter(p.sideToMove) {
+			res = append(res, m)
+		}
+	}
+	return res
+}
+
+// antichessMoves returns the legal moves in [Antichess]: the captures, if there
+// are any, and otherwise all moves.
+func (p *Position) antichessMoves() []Move {
+	var (
+		moves    = p.pseudoLegalMoves()
+		captures []Move
+		enemy    = p.board.colorOccupancy(p.sideToMove.Other())
+		pawns    = p.board.occupied[NewPiece(p.sideToMove, Pawn)]
+	)
+	ep, hasEP := p.ep.Get()
+	for _, m := range moves {
+		to, from := m.To(), m.From()
+		if enemy.Get(to) || hasEP && to == ep && pawns.Get(from) && from.File() != to.File() {
+			captures = append(captures, m)
+		}
+	}
+	if len(captures) > 0 {
+		return captures
+	}
+	return moves
+}
+
+// IsLegal returns true if m is a legal move.
+func (p *Position) IsLegal(m Move) bool {
+	for _, lm := range p.LegalMoves() {
+		if m == lm {
+			return true
+		}
+	}
+	return false
+}
+
+// IsCheckmate returns true if the side to move is checkmated.
+func (p *Position) IsCheckmate() bool {
+	return p.VariantResult() == NoResult && p.InCheck() && len(p.LegalMoves()) == 0
+}
+
+// IsStalemate returns true if the side to move is stalemated.
+func (p *Position) IsStalemate() bool {
+	return p.VariantResult() == NoResult && !p.InCheck() && len(p.LegalMoves()) == 0
+}
+
+// pseudoLegalMoves returns all moves that are legal, ignoring whether they
+// leave the side to move's king in check.
+func (p *Position) pseudoLegalMoves() []Move {
+	var (
+		res      []Move
+		us       = p.sideToMove
+		own      = p.board.colorOccupancy(us)
+		enemy    = p.board.colorOccupancy(us.Other())
+		occupied = own | enemy
+	)
+
+	add := func(from Square, targets Bitboard) {
+		for targets != 0 {
+			res = append(res, NewMove(from, targets.pop()))
+		}
+	}
+
+	p.appendPawnMoves(&res, occupied, enemy)
+
+	for bb := p.board.occupied[NewPiece(us, Knight)]; bb != 0; {
+		s := bb.pop()
+		add(s, knightAttacks[s]&^own)
+	}
+	for bb := p.board.occupied[NewPiece(us, Bishop)]; bb != 0; {
+		s := bb.pop()
+		add(s, bishopAttacks(s, occupied)&^own)
+	}
+	for bb := p.board.occupied[NewPiece(us, Rook)]; bb != 0; {
+		s := bb.pop()
+		add(s, rookAttacks(s, occupied)&^own)
+	}
+	for bb := p.board.occupied[NewPiece(us, Queen)]; bb != 0; {
+		s := bb.pop()
+		add(s, (bishopAttacks(s, occupied)|rookAttacks(s, occupied))&^own)
+	}
+	// In Atomic, kings can't capture.
+	kingTargets := ^own
+	if p.variant == Atomic {
+		kingTargets &^= enemy
+	}
+	for bb := p.board.occupied[NewPiece(us, King)]; bb != 0; {
+		s := bb.pop()
+		add(s, kingAttacks[s]&kingTargets)
+	}
+
+	if p.variant != Antichess {
+		p.appendCastlingMoves(&res, occupied)
+	}
+
+	if p.variant == Crazyhouse {
+		p.appendDrops(&res, occupied)
+	}
+
+	return res
+}
+
+// appendDrops appends moves that drop a piece from the side to move's pocket
+// onto an empty square. Pawns can't be dropped on the first or last rank.
+func (p *Position) appendDrops(res *[]Move, occupied Bitboard) {
+	const backRanks Bitboard = 0xff000000000000ff
+
+	pocket := p.pockets[p.sideToMove.index()]
+	for pt := Pawn; pt <= Queen; pt++ {
+		if pocket.Count(pt) == 0 {
+			continue
+		}
+		targets := ^occupied
+		if pt == Pawn {
+			targets &^= backRanks
+		}
+		for targets != 0 {
+			*res = append(*res, NewDropMove(pt, targets.pop()))
+		}
+	}
+}
+
+func (p *Position) appendPawnMoves(res *[]Move, occupied, enemy Bitboard) {
+	us := p.sideToMove
+
+	var startRank, promoRank Rank
+	if us == White {
+		startRank, promoRank = Rank2, Rank8
+	} else {
+		startRank, promoRank = Rank7, Rank1
+	}
+
+	// In Horde, white pawns on the first rank can move two squares too.
+	canDouble := func(s Square) bool {
+		return s.Rank() == startRank || p.variant == Horde && us == White && s.Rank() == Rank1
+	}
+
+	// In Antichess, pawns can promote to kings too.
+	promotions := []PieceType{Queen, Rook, Bishop, Knight}
+	if p.variant == Antichess {
+		promotions = append(promotions, King)
+	}
+
+	add := func(from, to Square) {
+		if to.Rank() == promoRank {
+			for _, pt := range promotions {
+				*res = append(*res, NewPromotionMove(from, to, pt))
+			}
+			return
+		}
+		*res = append(*res, NewMove(from, to))
+	}
+
+	forward := func(s Square) Square {
+		if us == White {
+			return s.Above()
+		}
+		return s.Below()
+	}
+
+	captures := enemy
+	if ep, ok := p.ep.Get(); ok {
+		captures.Set(ep)
+	}
+
+	for bb := p.board.occupied[NewPiece(us, Pawn)]; bb != 0; {
+		from := bb.pop()
+
+		// Pawns on the promotion rank can't move forward.
+		if from.Rank() != promoRank {
+			one := forward(from)
+			if !occupied.Get(one) {
+				add(from, one)
+				if canDouble(from) {
+					if two := forward(one); !occupied.Get(two) {
+						add(from, two)
+					}
+				}
+			}
+		}
+
+		for targets := pawnAttacks[us.index()][from] & captures; targets != 0; {
+			add(from, targets.pop())
+		}
+	}
+}
+
+func (p *Position) appendCastlingMoves(res *[]Move, occupied Bitboard) {
+	type castle struct {
+		ok       bool
+		king     Piece
+		rook     Piece
+		from, to Square
+		rookFrom Square
+		empty    []Square // must be empty
+		safe     []Square // must not be attacked
+	}
+
+	cr := p.cr
+	var castles []castle
+	if p.sideToMove == White {
+		castles = []castle{
+			{cr.GetWhiteOO(), WhiteKing, WhiteRook, E1, G1, H1, []Square{F1, G1}, []Square{E1, F1, G1}},
+			{cr.GetWhiteOOO(), WhiteKing, WhiteRook, E1, C1, A1, []Square{B1, C1, D1}, []Square{E1, D1, C1}},
+		}
+	} else {
+		castles = []castle{
+			{cr.GetBlackOO(), BlackKing, BlackRook, E8, G8, H8, []Square{F8, G8}, []Square{E8, F8, G8}},
+			{cr.GetBlackOOO(), BlackKing, BlackRook, E8, C8, A8, []Square{B8, C8, D8}, []Square{E8, D8, C8}},
+		}
+	}
+
+outer:
+	for _, c := range castles {
+		if !c.ok || !p.board.occupied[c.king].Get(c.from) || !p.board.occupied[c.rook].Get(c.rookFrom) {
+			continue
+		}
+		for _, s := range c.empty {
+			if occupied.Get(s) {
+				continue outer
+			}
+		}
+		for _, s := range c.safe {
+			if p.board.IsAttacked(s, p.sideToMove.Other()) {
+				continue outer
+			}
+		}
+		*res = append(*res, NewMove(c.from, c.to))
+	}
+}
@@ -0,0 +1,130 @@
+package core_test
+
+import (
+	"testing"
+
+	"github.com/clfs/lento/core"
+	"github.com/clfs/lento/encoding/fen"
+)
+
+func perft(p core.Position, depth int) int {
+	if depth == 0 {
+		return 1
+	}
+	moves := p.LegalMoves()
+	if depth == 1 {
+		return len(moves)
+	}
+	n := 0
+	for _, m := range moves {
+		q := p
+		q.Move(m)
+		n += perft(q, depth-1)
+	}
+	return n
+}
+
+// Perft results from https://www.chessprogramming.org/Perft_Results.
+var perftTests = []struct {
+	fen  string
+	want []int // indexed by depth - 1
+}{
+	{
+		fen.Starting,
+		[]int{20, 400, 8902, 197281},
+	},
+	{
+		"r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1",
+		[]int{48, 2039, 97862},
+	},
+	{
+		"8/2p5/3p4/KP5r/1R3p1k/8/4P1P1/8 w - - 0 1",
+		[]int{14, 191, 2812, 43238},
+	},
+	{
+		"r3k2r/Pppp1ppp/1b3nbN/nP6/BBP1P3/q4N2/Pp1P2PP/R2Q1RK1 w kq - 0 1",
+		[]int{6, 264, 9467},
+	},
+	{
+		"rnbq1k1r/pp1Pbppp/2p5/8/2B5/8/PPP1NnPP/RNBQK2R w KQ - 1 8",
+		[]int{44, 1486, 62379},
+	},
+	{
+		"r4rk1/1pp1qppp/p1np1n2/2b1p1B1/2B1P1b1/P1NP1N2/1PP1QPPP/R4RK1 w - - 0 10",
+		[]int{46, 2079, 89890},
+	},
+}
+
+func TestPerft(t *testing.T) {
+	for _, tc := range perftTests {
+		p := fen.MustDecode(tc.fen)
+		for i, want := range tc.want {
+			if testing.Short() && want > 10000 {
+				break
+			}
+			if got := perft(p, i+1); want != got {
+				t.Errorf("%s: depth %d: want %d, got %d", tc.fen, i+1, want, got)
+			}
+		}
+	}
+}
+
+func TestPosition_IsCheckmate(t *testing.T) {
+	cases := map[string]bool{
+		fen.Starting: false,
+		"rnb1kbnr/pppp1ppp/8/4p3/6Pq/5P2/PPPPP2P/RNBQKBNR w KQkq - 1 3": true,
+		"7k/5Q2/6K1/8/8/8/8/8 b - - 0 1":                                false, // stalemate
+	}
+	for s, want := range cases {
+		p := fen.MustDecode(s)
+		if got := p.IsCheckmate(); want != got {
+			t.Errorf("%s: want %t, got %t", s, want, got)
+		}
+	}
+}
+
+func TestPosition_IsStalemate(t *testing.T) {
+	cases := map[string]bool{
+		fen.Starting:                     false,
+		"7k/5Q2/6K1/8/8/8/8/8 b - - 0 1": true,
+		"rnb1kbnr/pppp1ppp/8/4p3/6Pq/5P2/PPPPP2P/RNBQKBNR w KQkq - 1 3": false, // checkmate
+	}
+	for s, want := range cases {
+		p := fen.MustDecode(s)
+		if got := p.IsStalemate(); want != got {
+			t.Errorf("%s: want %t, got %t", s, want, got)
+		}
+	}
+}
+
+func TestAttacks(t *testing.T) {
+	bb := func(squares ...core.Square) core.Bitboard {
+		var b core.Bitboard
+		for _, s := range squares {
+			b.Set(s)
+		}
+		return b
+	}
+	occupied := bb(core.D6, core.F4)
+	cases := []struct {
+		p    core.Piece
+		s    core.Square
+		want core.Bitboard
+	}{
+		{core.WhitePawn, core.A2, bb(core.B3)},
+		{core.BlackPawn, core.E5, bb(core.D4, core.F4)},
+		{core.WhiteKnight, core.G1, bb(core.E2, core.F3, core.H3)},
+		{core.BlackKing, core.H8, bb(core.G8, core.G7, core.H7)},
+		{core.WhiteBishop, core.D4, bb(core.A1, core.B2, core.C3, core.E5, core.F6, core.G7, core.H8,
+			core.A7, core.B6, core.C5, core.E3, core.F2, core.G1)},
+		{core.BlackRook, core.D4, bb(core.D1, core.D2, core.D3, core.D5, core.D6,
+			core.A4, core.B4, core.C4, core.E4, core.F4)},
+		{core.WhiteQueen, core.D8, bb(core.A8, core.B8, core.C8, core.E8, core.F8, core.G8, core.H8,
+			core.D7, core.D6, core.C7, core.B6, core.A5, core.E7, core.F6, core.G5, core.H4)},
+	}
+	for _, tc := range cases {
+		if got := core.Attacks(tc.p, tc.s, occupied); got != tc.want {
+			t.Errorf("%v on %v: want %#x, got %#x", tc.p, tc.s, uint64(tc.want), uint64(got))
+		}
+	}
+}
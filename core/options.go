@@ -2,11 +2,17 @@ package core
 
 type positionOptions struct {
 	board      Board
+	hasBoard   bool
 	sideToMove Color
 	cr         CastlingRights
+	hasCR      bool
 	ep         EnPassantTarget
 	hmc        int
 	fmn        int
+	variant    Variant
+	pockets    [2]Pocket
+	promoted   Bitboard
+	checks     [2]uint8
 }
 
 // PositionOption configures the creation of a new position.
@@ -20,6 +26,7 @@ type boardOption Board
 
 func (b boardOption) apply(opts *positionOptions) {
 	opts.board = Board(b)
+	opts.hasBoard = true
 }
 
 func WithBoard(b Board) PositionOption {
@@ -40,6 +47,7 @@ type castlingRightsOption CastlingRights
 
 func (c castlingRightsOption) apply(opts *positionOptions) {
 	opts.cr = CastlingRights(c)
+	opts.hasCR = true
 }
 
 func WithCastlingRights(cr CastlingRights) PositionOption {
@@ -77,3 +85,56 @@ func (f fullmoveNumberOption) apply(opts *positionOptions) {
 func WithFullmoveNumber(n int) PositionOption {
 	return fullmoveNumberOption(n)
 }
+
+type variantOption Variant
+
+func (v variantOption) apply(opts *positionOptions) {
+	opts.variant = Variant(v)
+}
+
+// WithVariant sets the variant being played. Unless set with [WithBoard] and
+// [WithCastlingRights], the board and castling rights are those the variant
+// starts with.
+func WithVariant(v Variant) PositionOption {
+	return variantOption(v)
+}
+
+type pocketOption struct {
+	c  Color
+	pk Pocket
+}
+
+func (o pocketOption) apply(opts *positionOptions) {
+	opts.pockets[o.c.index()] = o.pk
+}
+
+// WithPocket sets the pieces c has in hand in [Crazyhouse].
+func WithPocket(c Color, pk Pocket) PositionOption {
+	return pocketOption{c, pk}
+}
+
+type promotedOption Bitboard
+
+func (p promotedOption) apply(opts *positionOptions) {
+	opts.promoted = Bitboard(p)
+}
+
+// WithPromoted sets the locations of pieces that were promoted from pawns in
+// [Crazyhouse].
+func WithPromoted(b Bitboard) PositionOption {
+	return promotedOption(b)
+}
+
+type checksGivenOption struct {
+	c Color
+	n uint8
+}
+
+func (o checksGivenOption) apply(opts *positionOptions) {
+	opts.checks[o.c.index()] = o.n
+}
+
+// WithChecksGiven sets the number of times c has given check in [ThreeCheck].
+func WithChecksGiven(c Color, n int) PositionOption {
+	return checksGivenOption{c, uint8(n)}
+}
@@ -1,5 +1,10 @@
 package core
 
+import (
+	"fmt"
+	"strings"
+)
+
 // A Move represents a chess move.
 //
 // The zero value of Move represents a null move.
@@ -7,10 +12,16 @@ type Move struct {
 	// Bit-packed:
 	//   - Bits 0-5: Final square, or king's final square when castling.
 	//   - Bits 6-11: Initial square, or king's initial square when castling.
-	//   - Bits 12-15: Piece type to promote to, or 0 if no promotion.
+	//     For drops, the final square again.
+	//   - Bits 12-14: Piece type to promote to, or 0 if no promotion. For
+	//     drops, the piece type dropped.
+	//   - Bit 15: Set for drops.
 	val uint16
 }
 
+// dropFlag marks a drop in [Move].
+const dropFlag = 1 << 15
+
 // NewMove returns a new move.
 //
 // To create a castling move, provide the initial and final squares of the king.
@@ -21,7 +32,7 @@ func NewMove(from, to Square) Move {
 		f = uint16(from)
 		t = uint16(to)
 	)
-	return Move{val: f << 6 & t}
+	return Move{val: f<<6 | t}
 }
 
 // NewPromotionMove returns a new promotion move.
@@ -33,10 +44,20 @@ func NewPromotionMove(from, to Square, become PieceType) Move {
 		t = uint16(to)
 		b = uint16(become)
 	)
-	return Move{val: b << 12 & f << 6 & t}
+	return Move{val: b<<12 | f<<6 | t}
+}
+
+// NewDropMove returns a new move that drops a piece of type pt from the
+// pocket onto s, as in [Crazyhouse].
+func NewDropMove(pt PieceType, s Square) Move {
+	var (
+		p = uint16(pt)
+		t = uint16(s)
+	)
+	return Move{val: dropFlag | p<<12 | t<<6 | t}
 }
 
-// To returns the square that the move starts from.
+// To returns the square that the move ends on.
 //
 // If the move is a castling move, To returns the king's final location.
 func (m Move) To() Square {
@@ -45,15 +66,97 @@ func (m Move) To() Square {
 
 // From returns the square that the move starts from.
 //
-// If the move is a castling move, From returns the king's initial location.
+// If the move is a castling move, From returns the king's initial location. If
+// the move is a drop, From returns the same square as [Move.To].
 func (m Move) From() Square {
 	return Square(m.val >> 6 & 0b111111)
 }
 
 // Promotion returns the piece type that the move promotes to, if any.
 func (m Move) Promotion() (PieceType, bool) {
-	n := PieceType(m.val >> 12)
-	return n, n == 0
+	if m.val&dropFlag != 0 {
+		return 0, false
+	}
+	n := PieceType(m.val >> 12 & 0b111)
+	return n, n != 0
+}
+
+// Drop returns the piece type that the move drops, if the move is a drop.
+func (m Move) Drop() (PieceType, bool) {
+	if m.val&dropFlag == 0 {
+		return 0, false
+	}
+	return PieceType(m.val >> 12 & 0b111), true
+}
+
+// String returns the move in UCI long algebraic notation, like "e2e4" or
+// "e7e8q". Drops are written like "N@f3". The null move is "0000".
+func (m Move) String() string {
+	if m == (Move{}) {
+		return "0000"
+	}
+	if pt, ok := m.Drop(); ok {
+		return string("PNBRQK"[pt]) + "@" + m.To().String()
+	}
+	s := m.From().String() + m.To().String()
+	if pt, ok := m.Promotion(); ok {
+		s += string("pnbrqk"[pt])
+	}
+	return s
+}
+
+// ParseMove parses a move in UCI long algebraic notation, like "e2e4" or
+// "e7e8q". Drops are written like "N@f3". The null move is "0000".
+func ParseMove(s string) (Move, error) {
+	if s == "0000" {
+		return Move{}, nil
+	}
+	if len(s) == 4 && s[1] == '@' {
+		return parseDrop(s)
+	}
+	if n := len(s); n != 4 && n != 5 {
+		return Move{}, fmt.Errorf("bad move: %q", s)
+	}
+
+	from, err := ParseSquare(s[0:2])
+	if err != nil {
+		return Move{}, fmt.Errorf("bad move: %q", s)
+	}
+	to, err := ParseSquare(s[2:4])
+	if err != nil {
+		return Move{}, fmt.Errorf("bad move: %q", s)
+	}
+
+	if len(s) == 4 {
+		return NewMove(from, to), nil
+	}
+
+	switch s[4] {
+	case 'n':
+		return NewPromotionMove(from, to, Knight), nil
+	case 'b':
+		return NewPromotionMove(from, to, Bishop), nil
+	case 'r':
+		return NewPromotionMove(from, to, Rook), nil
+	case 'q':
+		return NewPromotionMove(from, to, Queen), nil
+	case 'k':
+		return NewPromotionMove(from, to, King), nil
+	default:
+		return Move{}, fmt.Errorf("bad move: %q", s)
+	}
+}
+
+func parseDrop(s string) (Move, error) {
+	pt := strings.IndexByte("PNBRQ", s[0])
+	if pt < 0 {
+		return Move{}, fmt.Errorf("bad move: %q", s)
+	}
+	to, err := ParseSquare(s[2:4])
+	if err != nil {
+		return Move{}, fmt.Errorf("bad move: %q", s)
+	}
+	return NewDropMove(PieceType(pt), to), nil
 }
 
 // A Bitboard contains one bit of information for each square on a board.
@@ -294,15 +397,21 @@ type Position struct {
 	hmc int
 	// The fullmove number starts at 1 and is incremented after each Black move.
 	fmn int
+
+	variant Variant
+	// Crazyhouse pockets, indexed by color, and the locations of pieces that
+	// were promoted from pawns.
+	pockets  [2]Pocket
+	promoted Bitboard
+	// Three-check counters: the number of checks given by each color.
+	checks [2]uint8
 }
 
 // NewPosition returns a starting position.
 func NewPosition(opts ...PositionOption) Position {
 	// Default options.
 	options := positionOptions{
-		board: NewBoard(),
-		cr:    NewCastlingRights(),
-		fmn:   1,
+		fmn: 1,
 	}
 
 	// Custom options.
@@ -310,6 +419,14 @@ func NewPosition(opts ...PositionOption) Position {
 		o.apply(&options)
 	}
 
+	// The default board and castling rights depend on the variant.
+	if !options.hasBoard {
+		options.board = options.variant.startingBoard()
+	}
+	if !options.hasCR {
+		options.cr = options.variant.startingCastlingRights()
+	}
+
 	return Position{
 		board:      options.board,
 		sideToMove: options.sideToMove,
@@ -317,11 +434,20 @@ func NewPosition(opts ...PositionOption) Position {
 		cr:         options.cr,
 		hmc:        options.hmc,
 		fmn:        options.fmn,
+		variant:    options.variant,
+		pockets:    options.pockets,
+		promoted:   options.promoted,
+		checks:     options.checks,
 	}
 }
 
 // Move makes the given move without ensuring legality.
 func (p *Position) Move(m Move) {
+	if pt, ok := m.Drop(); ok {
+		p.drop(pt, m.To())
+		return
+	}
+
 	to, from := m.To(), m.From()
 
 	// The moved piece, or if castling, the king.
@@ -332,6 +458,30 @@ func (p *Position) Move(m Move) {
 	isCapture := p.board.IsOccupied(to) ||
 		(isPawnMove && from.File() != to.File())
 
+	// In Crazyhouse, captured pieces go to the capturer's pocket, and
+	// promoted pieces go back to being pawns. Kings can only be captured
+	// while checking legality, and stay out of the pocket.
+	if p.variant == Crazyhouse {
+		pocket := &p.pockets[p.sideToMove.index()]
+		captured, ok := p.board.Get(to)
+		switch {
+		case ok && p.promoted.Get(to):
+			pocket.Add(Pawn)
+		case ok && captured.Type() != King:
+			pocket.Add(captured.Type())
+		case !ok && isCapture:
+			pocket.Add(Pawn) // e.p.
+		}
+		p.promoted.Clear(to)
+		if p.promoted.Get(from) {
+			p.promoted.Clear(from)
+			p.promoted.Set(to)
+		}
+		if _, ok := m.Promotion(); ok {
+			p.promoted.Set(to)
+		}
+	}
+
 	// If capturing e.p., remove the captured pawn.
 	epSq, ok := p.ep.Get()
 	if ok && isPawnMove && isCapture && to == epSq {
@@ -365,17 +515,10 @@ func (p *Position) Move(m Move) {
 		p.cr.ClearBlack()
 	}
 
-	// If moving from or to a corner square, update castling rights.
-	switch {
-	case from == A1 || to == A1:
-		p.cr.ClearWhiteOOO()
-	case from == H1 || to == H1:
-		p.cr.ClearWhiteOO()
-	case from == A8 || to == A8:
-		p.cr.ClearBlackOOO()
-	case from == H8 || to == H8:
-		p.cr.ClearBlackOO()
-	}
+	// If moving from or to a corner square, update castling rights. A move
+	// can touch two corners, like a rook capturing from a1 to a8.
+	p.clearCornerRights(from)
+	p.clearCornerRights(to)
 
 	// If promoting, swap out the held piece.
 	if become, ok := m.Promotion(); ok {
@@ -404,6 +547,11 @@ func (p *Position) Move(m Move) {
 		}
 	}
 
+	// In Atomic, captures explode.
+	if p.variant == Atomic && isCapture {
+		p.explode(to)
+	}
+
 	// Update the half move clock.
 	if isPawnMove || isCapture {
 		p.hmc = 0
@@ -411,13 +559,51 @@ func (p *Position) Move(m Move) {
 		p.hmc++
 	}
 
-	// Update the full move number.
+	p.endMove()
+}
+
+// clearCornerRights clears the castling rights that need a rook on s, if s is
+// a corner square.
+func (p *Position) clearCornerRights(s Square) {
+	switch s {
+	case A1:
+		p.cr.ClearWhiteOOO()
+	case H1:
+		p.cr.ClearWhiteOO()
+	case A8:
+		p.cr.ClearBlackOOO()
+	case H8:
+		p.cr.ClearBlackOO()
+	}
+}
+
+// drop drops a piece of type pt from the side to move's pocket onto s.
+func (p *Position) drop(pt PieceType, s Square) {
+	p.pockets[p.sideToMove.index()].Remove(pt)
+	p.board.Set(NewPiece(p.sideToMove, pt), s)
+	p.ep.Clear()
+
+	// A pawn drop is a pawn move.
+	if pt == Pawn {
+		p.hmc = 0
+	} else {
+		p.hmc++
+	}
+
+	p.endMove()
+}
+
+// endMove updates the fullmove number and switches sides. In [ThreeCheck],
+// it also counts the check, if the move gave one.
+func (p *Position) endMove() {
 	if p.sideToMove == Black {
 		p.fmn++
 	}
-
-	// Switch sides.
 	p.sideToMove = p.sideToMove.Other()
+
+	if p.variant == ThreeCheck && p.InCheck() {
+		p.checks[p.sideToMove.Other().index()]++
+	}
 }
 
 // Board returns the board.
@@ -446,6 +632,29 @@ func (p *Position) HalfmoveClock() int {
 	return int(p.hmc)
 }
 
+// Variant returns the variant being played.
+func (p *Position) Variant() Variant {
+	return p.variant
+}
+
+// Pocket returns the pieces c has in hand. Pockets are always empty unless
+// the variant is [Crazyhouse].
+func (p *Position) Pocket(c Color) Pocket {
+	return p.pockets[c.index()]
+}
+
+// Promoted returns the locations of pieces that were promoted from pawns. It
+// is only tracked in [Crazyhouse], where such pieces are captured as pawns.
+func (p *Position) Promoted() Bitboard {
+	return p.promoted
+}
+
+// ChecksGiven returns the number of times c has given check. It is only
+// tracked in [ThreeCheck], where the third check wins.
+func (p *Position) ChecksGiven(c Color) int {
+	return int(p.checks[c.index()])
+}
+
 // FullmoveNumber returns the fullmove number.
 //
 // The fullmove number starts at 1 and is incremented after each Black move.
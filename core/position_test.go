@@ -0,0 +1,79 @@
+package core
+
+import "testing"
+
+func TestNewMove(t *testing.T) {
+	m := NewMove(E2, E4)
+	if got := m.From(); got != E2 {
+		t.Errorf("from: want %d, got %d", E2, got)
+	}
+	if got := m.To(); got != E4 {
+		t.Errorf("to: want %d, got %d", E4, got)
+	}
+	if _, ok := m.Promotion(); ok {
+		t.Errorf("unexpected promotion")
+	}
+}
+
+func TestNewPromotionMove(t *testing.T) {
+	m := NewPromotionMove(B7, A8, Knight)
+	if got := m.From(); got != B7 {
+		t.Errorf("from: want %d, got %d", B7, got)
+	}
+	if got := m.To(); got != A8 {
+		t.Errorf("to: want %d, got %d", A8, got)
+	}
+	if pt, ok := m.Promotion(); !ok || pt != Knight {
+		t.Errorf("promotion: want %d, got %d (ok: %t)", Knight, pt, ok)
+	}
+}
+
+func TestNewDropMove(t *testing.T) {
+	m := NewDropMove(Pawn, E4)
+	if pt, ok := m.Drop(); !ok || pt != Pawn {
+		t.Errorf("drop: want %d, got %d (ok: %t)", Pawn, pt, ok)
+	}
+	if got := m.To(); got != E4 {
+		t.Errorf("to: want %d, got %d", E4, got)
+	}
+	if _, ok := m.Promotion(); ok {
+		t.Errorf("unexpected promotion")
+	}
+	if _, ok := NewPromotionMove(B7, A8, Queen).Drop(); ok {
+		t.Errorf("unexpected drop")
+	}
+}
+
+func TestParseMove(t *testing.T) {
+	cases := map[string]Move{
+		"e2e4":  NewMove(E2, E4),
+		"e1g1":  NewMove(E1, G1),
+		"b7a8n": NewPromotionMove(B7, A8, Knight),
+		"h2h1q": NewPromotionMove(H2, H1, Queen),
+		"e7e8k": NewPromotionMove(E7, E8, King), // Antichess
+		"P@e4":  NewDropMove(Pawn, E4),
+		"Q@h8":  NewDropMove(Queen, H8),
+		"0000":  {},
+	}
+	for s, want := range cases {
+		got, err := ParseMove(s)
+		if err != nil {
+			t.Errorf("%q: error: %v", s, err)
+			continue
+		}
+		if want != got {
+			t.Errorf("%q: want %v, got %v", s, want, got)
+		}
+		if got.String() != s {
+			t.Errorf("%q: changed in round trip: %q", s, got.String())
+		}
+	}
+}
+
+func TestParseMove_Invalid(t *testing.T) {
+	for _, s := range []string{"", "e2", "e2e9", "i2e4", "e7e8p", "e7e8Q", "e2e4 ", "K@e4", "p@e4", "N@i9"} {
+		if _, err := ParseMove(s); err == nil {
+			t.Errorf("%q: no error", s)
+		}
+	}
+}
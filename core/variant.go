@@ -0,0 +1,255 @@
+package core
+
+import (
+	"fmt"
+	"strings"
+)
+
+// A Variant is a set of rules for playing chess.
+type Variant uint8
+
+// [Variant] constants.
+const (
+	Standard Variant = iota
+	// Crazyhouse lets players drop captured pieces back onto the board as
+	// their own. Promoted pieces turn back into pawns when captured.
+	Crazyhouse
+	// ThreeCheck is won by checkmate or by giving check three times.
+	ThreeCheck
+	// KingOfTheHill is won by checkmate or by moving the king to one of the
+	// four center squares.
+	KingOfTheHill
+	// Atomic is won by checkmate or by exploding the enemy king. Captures
+	// explode, removing the capturing piece and every piece next to the
+	// captured one except pawns. Kings can't capture, and kings next to each
+	// other can't give check.
+	Atomic
+	// Antichess is won by losing all of one's pieces, or by having no legal
+	// moves. Captures are compulsory, there is no check, kings are ordinary
+	// pieces that can be captured, pawns can promote to kings, and there is
+	// no castling.
+	Antichess
+	// Horde pits 36 white pawns against Black's usual army. White has no
+	// king, and wins by checkmate; Black wins by capturing every white piece.
+	// White pawns on the first rank may move two squares.
+	Horde
+	// RacingKings is won by moving the king to the eighth rank. If Black
+	// can follow White there on the very next move, the game is a draw.
+	// Giving check is illegal, and there are no pawns.
+	RacingKings
+)
+
+// variantNames are the names of the variants, as used by the UCI_Variant
+// option.
+var variantNames = [...]string{
+	Standard:      "chess",
+	Crazyhouse:    "crazyhouse",
+	ThreeCheck:    "3check",
+	KingOfTheHill: "kingofthehill",
+	Atomic:        "atomic",
+	Antichess:     "antichess",
+	Horde:         "horde",
+	RacingKings:   "racingkings",
+}
+
+// Variants returns all variants, starting with [Standard].
+func Variants() []Variant {
+	res := make([]Variant, len(variantNames))
+	for i := range res {
+		res[i] = Variant(i)
+	}
+	return res
+}
+
+// String returns the variant's name as used by the UCI_Variant option, like
+// "chess" or "crazyhouse".
+func (v Variant) String() string {
+	if int(v) < len(variantNames) {
+		return variantNames[v]
+	}
+	return fmt.Sprintf("Variant(%d)", v)
+}
+
+// ParseVariant parses a variant name as used by the UCI_Variant option, like
+// "chess" or "crazyhouse". Case is ignored.
+func ParseVariant(s string) (Variant, error) {
+	for i, name := range variantNames {
+		if strings.EqualFold(s, name) {
+			return Variant(i), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown variant: %q", s)
+}
+
+// startingBoard returns the board a game of v starts from.
+func (v Variant) startingBoard() Board {
+	switch v {
+	case Horde:
+		b := NewBoard()
+		for s := A1; s <= H4; s++ {
+			b.Set(WhitePawn, s)
+		}
+		for _, s := range []Square{B5, C5, F5, G5} {
+			b.Set(WhitePawn, s)
+		}
+		return b
+	case RacingKings:
+		var b Board
+		for i, p := range []Piece{
+			BlackQueen, BlackRook, BlackBishop, BlackKnight,
+			WhiteKnight, WhiteBishop, WhiteRook, WhiteQueen,
+		} {
+			b.Set(p, A1+Square(i))
+		}
+		for i, p := range []Piece{
+			BlackKing, BlackRook, BlackBishop, BlackKnight,
+			WhiteKnight, WhiteBishop, WhiteRook, WhiteKing,
+		} {
+			b.Set(p, A2+Square(i))
+		}
+		return b
+	default:
+		return NewBoard()
+	}
+}
+
+// startingCastlingRights returns the castling rights a game of v starts
+// with.
+func (v Variant) startingCastlingRights() CastlingRights {
+	cr := NewCastlingRights()
+	switch v {
+	case Horde:
+		cr.ClearWhite()
+	case Antichess, RacingKings:
+		cr.ClearWhite()
+		cr.ClearBlack()
+	}
+	return cr
+}
+
+// A Pocket holds the pieces a player has in hand in [Crazyhouse], ready to be
+// dropped. Pockets never hold kings.
+//
+// The zero value of Pocket is an empty pocket.
+type Pocket struct {
+	// The number of pawns, knights, bishops, rooks and queens, indexed by
+	// piece type.
+	counts [5]uint8
+}
+
+// Count returns the number of pieces of type pt in the pocket.
+func (p *Pocket) Count(pt PieceType) int {
+	if pt > Queen {
+		return 0
+	}
+	return int(p.counts[pt])
+}
+
+// Add adds a piece of type pt to the pocket. It is invalid to add a king.
+func (p *Pocket) Add(pt PieceType) {
+	p.counts[pt]++
+}
+
+// Remove removes a piece of type pt from the pocket. It is invalid to remove
+// a piece that isn't there.
+func (p *Pocket) Remove(pt PieceType) {
+	p.counts[pt]--
+}
+
+// IsEmpty returns true if the pocket holds no pieces.
+func (p *Pocket) IsEmpty() bool {
+	return p.counts == [5]uint8{}
+}
+
+// The center squares of [KingOfTheHill]: d4, e4, d5 and e5.
+const hill Bitboard = 0x0000001818000000
+
+// VariantResult returns the result of the game if it was decided by the
+// variant's own rules, rather than by checkmate or a draw: three checks in
+// [ThreeCheck], a king on the hill in [KingOfTheHill], an exploded king in
+// [Atomic], a side to move with no pieces or no legal moves in [Antichess],
+// no white pieces in [Horde], or a king on the eighth rank in [RacingKings].
+// Otherwise it returns [NoResult].
+//
+// Once the game is decided, there are no legal moves.
+func (p *Position) VariantResult() Result {
+	switch p.variant {
+	case ThreeCheck:
+		switch {
+		case p.checks[White.index()] >= 3:
+			return WhiteWins
+		case p.checks[Black.index()] >= 3:
+			return BlackWins
+		}
+	case KingOfTheHill:
+		switch {
+		case p.board.occupied[WhiteKing]&hill != 0:
+			return WhiteWins
+		case p.board.occupied[BlackKing]&hill != 0:
+			return BlackWins
+		}
+	case Atomic:
+		switch {
+		case p.board.occupied[BlackKing] == 0:
+			return WhiteWins
+		case p.board.occupied[WhiteKing] == 0:
+			return BlackWins
+		}
+	case Antichess:
+		if len(p.antichessMoves()) == 0 {
+			if p.sideToMove == White {
+				return WhiteWins
+			}
+			return BlackWins
+		}
+	case Horde:
+		if p.board.colorOccupancy(White) == 0 {
+			return BlackWins
+		}
+	case RacingKings:
+		white := p.board.occupied[WhiteKing]&rank8 != 0
+		black := p.board.occupied[BlackKing]&rank8 != 0
+		switch {
+		case white && black:
+			return Draw
+		case black:
+			return BlackWins
+		case white && (p.sideToMove == White || !p.kingCanReach(rank8)):
+			return WhiteWins
+		}
+	}
+	return NoResult
+}
+
+// The eighth rank, the goal of [RacingKings].
+const rank8 Bitboard = 0xff00000000000000
+
+// kingCanReach returns true if the side to move has a legal king move onto
+// goal.
+func (p *Position) kingCanReach(goal Bitboard) bool {
+	king := p.board.occupied[NewPiece(p.sideToMove, King)]
+	for _, m := range p.legalMoves() {
+		if king.Get(m.From()) && goal.Get(m.To()) {
+			return true
+		}
+	}
+	return false
+}
+
+// explode removes the piece on s, where a capture was just made in [Atomic],
+// along with every piece next to it except pawns.
+func (p *Position) explode(s Square) {
+	blast := kingAttacks[s] &^ (p.board.occupied[WhitePawn] | p.board.occupied[BlackPawn])
+	blast.Set(s)
+	for blast != 0 {
+		t := blast.pop()
+		switch piece, _ := p.board.Get(t); piece {
+		case WhiteKing:
+			p.cr.ClearWhite()
+		case BlackKing:
+			p.cr.ClearBlack()
+		}
+		p.clearCornerRights(t)
+		p.board.Clear(t)
+	}
+}
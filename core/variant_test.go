@@ -0,0 +1,594 @@
+package core_test
+
+import (
+	"testing"
+
+	"github.com/clfs/lento/core"
+	"github.com/clfs/lento/encoding/fen"
+)
+
+// Variant perft results, checked against Fairy-Stockfish. Three-check and
+// King of the Hill match standard chess from the starting position until a
+// game can end by their own rules.
+var variantPerftTests = []struct {
+	variant core.Variant
+	fen     string
+	want    []int // indexed by depth - 1
+}{
+	{
+		core.Crazyhouse,
+		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR[] w KQkq - 0 1",
+		[]int{20, 400, 8902, 197281},
+	},
+	{
+		core.Crazyhouse,
+		"2k5/8/8/8/8/8/8/4K3[QRBNPqrbnp] w - - 0 1",
+		[]int{301, 75353},
+	},
+	{
+		core.ThreeCheck,
+		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1 +0+0",
+		[]int{20, 400, 8902, 197281},
+	},
+	{
+		core.KingOfTheHill,
+		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1",
+		[]int{20, 400, 8902, 197281},
+	},
+	{
+		core.Atomic,
+		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1",
+		[]int{20, 400, 8902, 197326},
+	},
+	{
+		core.Atomic,
+		"rn2kb1r/1pp1p2p/p2q1pp1/3P4/2P3b1/4PN2/PP3PPP/R2QKB1R b KQkq - 0 1",
+		[]int{40, 1238, 45237},
+	},
+	{
+		core.Atomic,
+		"rn1qkb1r/p5pp/2p5/3p4/N3P3/5P2/PPP4P/R1BQK3 w Qkq - 0 1",
+		[]int{28, 833, 23353},
+	},
+	{
+		core.Antichess,
+		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w - - 0 1",
+		[]int{20, 400, 8067, 153299},
+	},
+	{
+		core.Antichess,
+		"8/1p6/8/8/8/8/P7/8 w - - 0 1",
+		[]int{2, 4, 4, 3, 1},
+	},
+	{
+		core.Horde,
+		"rnbqkbnr/pppppppp/8/1PP2PP1/PPPPPPPP/PPPPPPPP/PPPPPPPP/PPPPPPPP w kq - 0 1",
+		[]int{8, 128, 1274, 23310},
+	},
+	{
+		core.RacingKings,
+		"8/8/8/8/8/8/krbnNBRK/qrbnNBRQ w - - 0 1",
+		[]int{21, 421, 11264, 296242},
+	},
+}
+
+func TestPerft_Variants(t *testing.T) {
+	for _, tc := range variantPerftTests {
+		p, err := fen.DecodeVariant(tc.fen, tc.variant)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i, want := range tc.want {
+			if testing.Short() && want > 10000 {
+				break
+			}
+			if got := perft(p, i+1); want != got {
+				t.Errorf("%v: %s: depth %d: want %d, got %d", tc.variant, tc.fen, i+1, want, got)
+			}
+		}
+	}
+}
+
+func TestParseVariant(t *testing.T) {
+	for _, v := range core.Variants() {
+		got, err := core.ParseVariant(v.String())
+		if err != nil {
+			t.Errorf("%v: error: %v", v, err)
+			continue
+		}
+		if v != got {
+			t.Errorf("%v: changed in round trip: %v", v, got)
+		}
+	}
+	if _, err := core.ParseVariant("shogi"); err == nil {
+		t.Error("shogi: no error")
+	}
+}
+
+func TestPosition_Move_Crazyhouse(t *testing.T) {
+	cases := []struct {
+		fen, move, want string
+	}{
+		// Captures go to the capturer's pocket.
+		{
+			"4k3/8/8/3p4/4N3/8/8/4K3[] w - - 0 1", "e4f6",
+			"4k3/8/5N2/3p4/8/8/8/4K3[] b - - 1 1",
+		},
+		{
+			"4k3/8/8/3p4/4B3/8/8/4K3[] w - - 0 1", "e4d5",
+			"4k3/8/8/3B4/8/8/8/4K3[P] b - - 0 1",
+		},
+		// En passant captures a pawn.
+		{
+			"4k3/8/8/3pP3/8/8/8/4K3[] w - d6 0 1", "e5d6",
+			"4k3/8/3P4/8/8/8/8/4K3[P] b - - 0 1",
+		},
+		// Promoted pieces are marked, and captured as pawns.
+		{
+			"4k3/1P6/8/8/8/8/8/4K3[] w - - 0 1", "b7b8q",
+			"1Q~2k3/8/8/8/8/8/8/4K3[] b - - 0 1",
+		},
+		{
+			"1Q~2k3/8/8/8/8/8/8/4K3[] w - - 0 1", "b8b7",
+			"4k3/1Q~6/8/8/8/8/8/4K3[] b - - 1 1",
+		},
+		{
+			"2Q~1k3/8/8/8/8/8/7K/2r5[] b - - 0 1", "c1c8",
+			"2r1k3/8/8/8/8/8/7K/8[p] w - - 0 2",
+		},
+		// Drops come from the pocket.
+		{
+			"4k3/8/8/8/8/8/8/4K3[NPp] w - - 3 1", "N@e4",
+			"4k3/8/8/8/4N3/8/8/4K3[Pp] b - - 4 1",
+		},
+		{
+			"4k3/8/8/8/8/8/8/4K3[NPp] b - - 3 1", "P@e5",
+			"4k3/8/8/4p3/8/8/8/4K3[NP] w - - 0 2",
+		},
+	}
+	for _, tc := range cases {
+		p, err := fen.DecodeVariant(tc.fen, core.Crazyhouse)
+		if err != nil {
+			t.Fatal(err)
+		}
+		m, err := core.ParseMove(tc.move)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !p.IsLegal(m) {
+			t.Errorf("%s: %v: illegal", tc.fen, m)
+			continue
+		}
+		p.Move(m)
+		if got := fen.Encode(p); tc.want != got {
+			t.Errorf("%s: %v: want %q, got %q", tc.fen, m, tc.want, got)
+		}
+	}
+}
+
+func TestPosition_LegalMoves_Drops(t *testing.T) {
+	cases := []struct {
+		fen   string
+		legal []string
+		not   []string
+	}{
+		{
+			// Pawns can't be dropped on the back ranks.
+			fen:   "4k3/8/8/8/8/8/8/4K3[P] w - - 0 1",
+			legal: []string{"P@a2", "P@h7"},
+			not:   []string{"P@a1", "P@h8", "P@e1"},
+		},
+		{
+			// A drop can block a check, and can't be made onto a piece.
+			fen:   "4k3/8/8/8/8/8/8/r3K3[R] w - - 0 1",
+			legal: []string{"R@b1", "R@d1"},
+			not:   []string{"R@e4", "R@a1"},
+		},
+		{
+			// Drops can mate.
+			fen:   "k7/8/1K6/8/8/8/8/8[Q] w - - 0 1",
+			legal: []string{"Q@a7", "Q@b7"},
+		},
+	}
+	for _, tc := range cases {
+		p, err := fen.DecodeVariant(tc.fen, core.Crazyhouse)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, s := range tc.legal {
+			if m, _ := core.ParseMove(s); !p.IsLegal(m) {
+				t.Errorf("%s: %s: want legal", tc.fen, s)
+			}
+		}
+		for _, s := range tc.not {
+			if m, _ := core.ParseMove(s); p.IsLegal(m) {
+				t.Errorf("%s: %s: want illegal", tc.fen, s)
+			}
+		}
+	}
+
+	p := fen.MustDecode("k7/8/1K6/8/8/8/8/8 w - - 0 1")
+	if m, _ := core.ParseMove("Q@b7"); p.IsLegal(m) {
+		t.Error("standard chess: drop is legal")
+	}
+}
+
+func TestGame_Result_Variants(t *testing.T) {
+	cases := []struct {
+		name    string
+		variant core.Variant
+		fen     string
+		moves   []string
+		want    core.Result
+		term    core.Termination
+	}{
+		{
+			"two checks", core.ThreeCheck,
+			"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1 +0+0",
+			[]string{"e2e4", "e7e5", "f1c4", "g8f6", "c4f7", "e8f7", "d1h5", "g7g6", "h5e5"},
+			core.NoResult, core.NoTermination,
+		},
+		{
+			"third check", core.ThreeCheck,
+			"rnbqkbnr/pppp1ppp/8/4p3/4P3/8/PPPP1PPP/RNBQKBNR w KQkq - 0 2 +2+0",
+			[]string{"d1h5", "g8f6", "h5f7"},
+			core.WhiteWins, core.VariantEnd,
+		},
+		{
+			"black's third check", core.ThreeCheck,
+			"4k3/8/8/8/8/8/q7/4K3 b - - 0 1 +0+2",
+			[]string{"a2a5"},
+			core.BlackWins, core.VariantEnd,
+		},
+		{
+			"checks alone aren't insufficient", core.ThreeCheck,
+			"7k/8/8/8/8/8/8/6NK w - - 0 1 +0+0",
+			nil, core.NoResult, core.NoTermination,
+		},
+		{
+			"bare kings", core.ThreeCheck,
+			"7k/8/8/8/8/8/8/7K w - - 0 1 +2+2",
+			nil, core.Draw, core.InsufficientMaterial,
+		},
+		{
+			"king on the hill", core.KingOfTheHill,
+			"4k3/8/8/8/8/3K4/8/8 w - - 0 1",
+			[]string{"d3e4"},
+			core.WhiteWins, core.VariantEnd,
+		},
+		{
+			"black king on the hill", core.KingOfTheHill,
+			"4k3/8/3K4/8/8/8/8/8 w - - 0 1",
+			[]string{"d6c6", "e8e7", "c6b6", "e7d6", "b6b7", "d6d5"},
+			core.BlackWins, core.VariantEnd,
+		},
+		{
+			"kings can always reach the hill", core.KingOfTheHill,
+			"7k/8/8/8/8/8/8/7K w - - 0 1",
+			nil, core.NoResult, core.NoTermination,
+		},
+		{
+			"exploded king", core.Atomic,
+			"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1",
+			[]string{"g1f3", "e7e6", "f3e5", "a7a6", "e5d7"},
+			core.WhiteWins, core.VariantEnd,
+		},
+		{
+			"atomic checkmate", core.Atomic,
+			"rnbqkbnr/pppp1ppp/8/4p3/6P1/5P2/PPPPP2P/RNBQKBNR b KQkq - 0 2",
+			[]string{"d8h4"},
+			core.BlackWins, core.Checkmate,
+		},
+		{
+			"atomic bare kings", core.Atomic,
+			"7k/8/8/8/8/8/8/6NK w - - 0 1",
+			nil, core.Draw, core.InsufficientMaterial,
+		},
+		{
+			"no pieces left", core.Antichess,
+			"8/8/8/8/8/8/8/r6R w - - 0 1",
+			[]string{"h1a1"},
+			core.BlackWins, core.VariantEnd,
+		},
+		{
+			"no legal moves", core.Antichess,
+			"8/8/8/8/8/p7/P7/8 w - - 0 1",
+			nil, core.WhiteWins, core.VariantEnd,
+		},
+		{
+			"antichess check", core.Antichess,
+			"4k3/8/8/8/8/8/8/4R2K b - - 0 1",
+			nil, core.NoResult, core.NoTermination,
+		},
+		{
+			"opposite-color bishops", core.Antichess,
+			"8/8/8/8/8/8/8/b6B w - - 0 1",
+			nil, core.Draw, core.InsufficientMaterial,
+		},
+		{
+			"horde captured", core.Horde,
+			"4k3/8/8/8/8/8/3q4/4P3 b - - 0 1",
+			[]string{"d2e1"},
+			core.BlackWins, core.VariantEnd,
+		},
+		{
+			"horde mates", core.Horde,
+			"7k/6pp/8/8/8/8/8/R7 w - - 0 1",
+			[]string{"a1a8"},
+			core.WhiteWins, core.Checkmate,
+		},
+		{
+			"king reaches the goal", core.RacingKings,
+			"8/6K1/8/8/8/8/8/k7 w - - 0 1",
+			[]string{"g7g8"},
+			core.WhiteWins, core.VariantEnd,
+		},
+		{
+			"black can follow", core.RacingKings,
+			"8/k5K1/8/8/8/8/8/8 w - - 0 1",
+			[]string{"g7g8"},
+			core.NoResult, core.NoTermination,
+		},
+		{
+			"black follows", core.RacingKings,
+			"8/k5K1/8/8/8/8/8/8 w - - 0 1",
+			[]string{"g7g8", "a7a8"},
+			core.Draw, core.VariantEnd,
+		},
+		{
+			"black doesn't follow", core.RacingKings,
+			"8/k5K1/8/8/8/8/8/8 w - - 0 1",
+			[]string{"g7g8", "a7a6"},
+			core.WhiteWins, core.VariantEnd,
+		},
+		{
+			"black reaches the goal", core.RacingKings,
+			"8/k7/8/8/8/8/8/7K b - - 0 1",
+			[]string{"a7b8"},
+			core.BlackWins, core.VariantEnd,
+		},
+	}
+	for _, c := range cases {
+		p, err := fen.DecodeVariant(c.fen, c.variant)
+		if err != nil {
+			t.Fatal(err)
+		}
+		g := core.NewGame(p)
+		playMoves(t, g, c.moves...)
+		if got, term := g.Result(); c.want != got || c.term != term {
+			t.Errorf("%s: want %v (%v), got %v (%v)", c.name, c.want, c.term, got, term)
+		}
+		if c.term == core.VariantEnd {
+			p := g.Position()
+			if n := len(p.LegalMoves()); n != 0 {
+				t.Errorf("%s: want no legal moves, got %d", c.name, n)
+			}
+			if p.IsCheckmate() || p.IsStalemate() {
+				t.Errorf("%s: want neither checkmate nor stalemate", c.name)
+			}
+		}
+	}
+}
+
+func TestPosition_ChecksGiven(t *testing.T) {
+	p := core.NewPosition(core.WithVariant(core.ThreeCheck))
+	for _, s := range []string{"e2e4", "f7f6", "d1h5"} {
+		m, _ := core.ParseMove(s)
+		p.Move(m)
+	}
+	if w, b := p.ChecksGiven(core.White), p.ChecksGiven(core.Black); w != 1 || b != 0 {
+		t.Errorf("want +1+0, got +%d+%d", w, b)
+	}
+
+	// Checks are only counted in Three-check.
+	p = core.NewPosition()
+	for _, s := range []string{"e2e4", "f7f6", "d1h5"} {
+		m, _ := core.ParseMove(s)
+		p.Move(m)
+	}
+	if w := p.ChecksGiven(core.White); w != 0 {
+		t.Errorf("standard chess: want no checks, got %d", w)
+	}
+}
+
+func TestPosition_Move_Atomic(t *testing.T) {
+	cases := []struct {
+		fen, move, want string
+	}{
+		// The capturing piece and all non-pawns next to the capture explode.
+		{
+			"4k3/8/2nbp3/3q4/2PN4/8/8/4K3 w - - 0 1", "c4d5",
+			"4k3/8/4p3/8/8/8/8/4K3 b - - 0 1",
+		},
+		// En passant explodes around the capturing pawn's square.
+		{
+			"4k3/8/2n5/3pP3/8/8/8/4K3 w - d6 0 1", "e5d6",
+			"4k3/8/8/8/8/8/8/4K3 b - - 0 1",
+		},
+		// Exploded rooks lose their castling rights.
+		{
+			"r3k2r/8/8/8/8/8/1p6/R3K2R b KQkq - 0 1", "b2a1q",
+			"r3k2r/8/8/8/8/8/8/4K2R w Kkq - 0 2",
+		},
+	}
+	for _, tc := range cases {
+		p, err := fen.DecodeVariant(tc.fen, core.Atomic)
+		if err != nil {
+			t.Fatal(err)
+		}
+		m, err := core.ParseMove(tc.move)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !p.IsLegal(m) {
+			t.Errorf("%s: %v: illegal", tc.fen, m)
+			continue
+		}
+		p.Move(m)
+		if got := fen.Encode(p); tc.want != got {
+			t.Errorf("%s: %v: want %q, got %q", tc.fen, m, tc.want, got)
+		}
+	}
+}
+
+func TestPosition_LegalMoves_Atomic(t *testing.T) {
+	cases := []struct {
+		fen   string
+		legal []string
+		not   []string
+	}{
+		{
+			// Kings can't capture, but can stand next to each other.
+			fen:   "8/8/8/3k4/3p4/3K4/8/8 w - - 0 1",
+			legal: []string{"d3e4", "d3c4"},
+			not:   []string{"d3d4"},
+		},
+		{
+			// A capture can't explode one's own king.
+			fen:   "4k3/8/8/8/8/8/3p4/3QK3 w - - 0 1",
+			legal: []string{"e1f2"},
+			not:   []string{"d1d2", "e1d2"},
+		},
+		{
+			// Exploding the enemy king wins, even out of check.
+			fen:   "3rk3/3p4/8/8/8/8/8/3QK2r w - - 0 1",
+			legal: []string{"d1d7"},
+		},
+	}
+	for _, tc := range cases {
+		p, err := fen.DecodeVariant(tc.fen, core.Atomic)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, s := range tc.legal {
+			if m, _ := core.ParseMove(s); !p.IsLegal(m) {
+				t.Errorf("%s: %s: want legal", tc.fen, s)
+			}
+		}
+		for _, s := range tc.not {
+			if m, _ := core.ParseMove(s); p.IsLegal(m) {
+				t.Errorf("%s: %s: want illegal", tc.fen, s)
+			}
+		}
+	}
+}
+
+func TestPosition_LegalMoves_Antichess(t *testing.T) {
+	cases := []struct {
+		fen   string
+		legal []string
+		not   []string
+	}{
+		{
+			// Captures are compulsory, even for kings.
+			fen:   "8/8/8/3p4/4K3/8/8/R7 w - - 0 1",
+			legal: []string{"e4d5"},
+			not:   []string{"e4e5", "a1a2"},
+		},
+		{
+			// There is no check, and kings can be captured.
+			fen:   "4k3/8/8/8/8/8/8/4R2K w - - 0 1",
+			legal: []string{"e1e8"},
+			not:   []string{"h1h2"},
+		},
+		{
+			// Pawns can promote to kings, and there is no castling.
+			fen:   "8/P7/8/8/8/8/8/R3K2R w KQ - 0 1",
+			legal: []string{"a7a8k", "a7a8q"},
+			not:   []string{"e1g1", "e1c1"},
+		},
+	}
+	for _, tc := range cases {
+		p, err := fen.DecodeVariant(tc.fen, core.Antichess)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, s := range tc.legal {
+			if m, _ := core.ParseMove(s); !p.IsLegal(m) {
+				t.Errorf("%s: %s: want legal", tc.fen, s)
+			}
+		}
+		for _, s := range tc.not {
+			if m, _ := core.ParseMove(s); p.IsLegal(m) {
+				t.Errorf("%s: %s: want illegal", tc.fen, s)
+			}
+		}
+	}
+}
+
+func TestNewPosition_Variants(t *testing.T) {
+	cases := map[core.Variant]string{
+		core.Standard:    "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1",
+		core.Antichess:   "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w - - 0 1",
+		core.Horde:       "rnbqkbnr/pppppppp/8/1PP2PP1/PPPPPPPP/PPPPPPPP/PPPPPPPP/PPPPPPPP w kq - 0 1",
+		core.RacingKings: "8/8/8/8/8/8/krbnNBRK/qrbnNBRQ w - - 0 1",
+	}
+	for v, want := range cases {
+		p := core.NewPosition(core.WithVariant(v))
+		if got := fen.Encode(p); want != got {
+			t.Errorf("%v: want %q, got %q", v, want, got)
+		}
+	}
+
+	// A board given as an option wins over the variant's.
+	b, err := fen.DecodeBoard("4k3/8/8/8/8/8/8/4K3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := core.NewPosition(core.WithBoard(b), core.WithVariant(core.Horde))
+	if p.Board() != b {
+		t.Error("board replaced by the variant's")
+	}
+}
+
+func TestPosition_LegalMoves_HordeAndRacingKings(t *testing.T) {
+	cases := []struct {
+		variant core.Variant
+		fen     string
+		legal   []string
+		not     []string
+	}{
+		{
+			// White pawns on the first rank can move two squares.
+			variant: core.Horde,
+			fen:     "4k3/8/8/8/8/8/8/P7 w - - 0 1",
+			legal:   []string{"a1a2", "a1a3"},
+		},
+		{
+			// Black pawns can't.
+			variant: core.Horde,
+			fen:     "p3k3/8/8/8/8/8/8/P7 b - - 0 1",
+			not:     []string{"a8a6"},
+		},
+		{
+			// Giving check is illegal.
+			variant: core.RacingKings,
+			fen:     "8/8/8/8/8/8/k7/6RK w - - 0 1",
+			legal:   []string{"g1g3"},
+			not:     []string{"g1g2", "g1a1"},
+		},
+	}
+	for _, tc := range cases {
+		p, err := fen.DecodeVariant(tc.fen, tc.variant)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, s := range tc.legal {
+			if m, _ := core.ParseMove(s); !p.IsLegal(m) {
+				t.Errorf("%s: %s: want legal", tc.fen, s)
+			}
+		}
+		for _, s := range tc.not {
+			if m, _ := core.ParseMove(s); p.IsLegal(m) {
+				t.Errorf("%s: %s: want illegal", tc.fen, s)
+			}
+		}
+	}
+
+	// A double move from the first rank can't be captured en passant.
+	p, _ := fen.DecodeVariant("4k3/8/8/8/1p6/8/8/P7 w - - 0 1", core.Horde)
+	p.Move(core.NewMove(core.A1, core.A3))
+	if ep := p.EnPassantTarget(); ep.Exists() {
+		t.Error("first-rank double move: e.p. target set")
+	}
+}
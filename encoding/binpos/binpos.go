@@ -0,0 +1,204 @@
+// Package binpos implements a compact, fixed-size binary encoding of
+// positions for training data.
+//
+// Each [Record] is encoded into exactly [Size] bytes, laid out as follows,
+// with all integers little-endian:
+//
+//   - Bytes 0-7: occupancy bitboard, with bit i set if square i is occupied.
+//   - Bytes 8-23: up to 32 4-bit piece codes, one for each occupied square in
+//     ascending square order, low nibble first. Unused nibbles are zero.
+//   - Byte 24: bit 0 is the side to move (1 for Black), bits 1-4 are the
+//     castling rights (K, Q, k, q) and bits 5-6 are the game result.
+//   - Byte 25: en passant target square, or 0 if none.
+//   - Byte 26: halfmove clock.
+//   - Bytes 27-28: fullmove number.
+//   - Bytes 29-30: score.
+//   - Byte 31: reserved, always 0.
+//
+// Records are typically stored in streams written by [Writer] and read by
+// [Reader], which add checksums.
+package binpos
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math/bits"
+
+	"github.com/clfs/lento/core"
+)
+
+// Size is the size of an encoded record, in bytes.
+const Size = 32
+
+// A Result is the result of the game a record was taken from.
+type Result uint8
+
+// [Result] constants.
+const (
+	BlackWin Result = iota
+	Draw
+	WhiteWin
+)
+
+// A Record is a position labelled with a search score and a game result.
+type Record struct {
+	Position core.Position
+	// Score is the search score in centipawns, from the perspective of the
+	// side to move.
+	Score int16
+	// Result is the result of the game.
+	Result Result
+}
+
+// Encode encodes a record.
+//
+// Encode returns an error if the position has more than 32 pieces, a halfmove
+// clock above 255 or a fullmove number above 65535.
+func Encode(r Record) ([Size]byte, error) {
+	var buf [Size]byte
+
+	p := r.Position
+	b := p.Board()
+
+	var (
+		occupied core.Bitboard
+		n        int
+	)
+	for s := core.A1; s <= core.H8; s++ {
+		piece, ok := b.Get(s)
+		if !ok {
+			continue
+		}
+		if n == 32 {
+			return buf, fmt.Errorf("too many pieces")
+		}
+		occupied.Set(s)
+		buf[8+n/2] |= byte(piece) << (4 * (n % 2))
+		n++
+	}
+	binary.LittleEndian.PutUint64(buf[0:], uint64(occupied))
+
+	if r.Result > WhiteWin {
+		return buf, fmt.Errorf("bad result: %d", r.Result)
+	}
+
+	var flags byte
+	if p.SideToMove() == core.Black {
+		flags |= 1
+	}
+	cr := p.CastlingRights()
+	if cr.GetWhiteOO() {
+		flags |= 1 << 1
+	}
+	if cr.GetWhiteOOO() {
+		flags |= 1 << 2
+	}
+	if cr.GetBlackOO() {
+		flags |= 1 << 3
+	}
+	if cr.GetBlackOOO() {
+		flags |= 1 << 4
+	}
+	flags |= byte(r.Result) << 5
+	buf[24] = flags
+
+	ep := p.EnPassantTarget()
+	if s, ok := ep.Get(); ok {
+		buf[25] = byte(s)
+	}
+
+	hmc := p.HalfmoveClock()
+	if hmc > 255 {
+		return buf, fmt.Errorf("halfmove clock too large: %d", hmc)
+	}
+	buf[26] = byte(hmc)
+
+	fmn := p.FullmoveNumber()
+	if fmn > 65535 {
+		return buf, fmt.Errorf("fullmove number too large: %d", fmn)
+	}
+	binary.LittleEndian.PutUint16(buf[27:], uint16(fmn))
+
+	binary.LittleEndian.PutUint16(buf[29:], uint16(r.Score))
+
+	return buf, nil
+}
+
+// Decode decodes a record.
+func Decode(buf [Size]byte) (Record, error) {
+	var b core.Board
+
+	occupied := binary.LittleEndian.Uint64(buf[0:])
+	if bits.OnesCount64(occupied) > 32 {
+		return Record{}, fmt.Errorf("too many pieces")
+	}
+	for n := 0; occupied != 0; n++ {
+		s := core.Square(bits.TrailingZeros64(occupied))
+		occupied &= occupied - 1
+
+		piece := core.Piece(buf[8+n/2] >> (4 * (n % 2)) & 0xf)
+		if piece > core.BlackKing {
+			return Record{}, fmt.Errorf("bad piece: %d", piece)
+		}
+		b.Set(piece, s)
+	}
+
+	flags := buf[24]
+
+	sideToMove := core.White
+	if flags&1 != 0 {
+		sideToMove = core.Black
+	}
+
+	var cr core.CastlingRights
+	if flags&(1<<1) != 0 {
+		cr.SetWhiteOO()
+	}
+	if flags&(1<<2) != 0 {
+		cr.SetWhiteOOO()
+	}
+	if flags&(1<<3) != 0 {
+		cr.SetBlackOO()
+	}
+	if flags&(1<<4) != 0 {
+		cr.SetBlackOOO()
+	}
+
+	result := Result(flags >> 5 & 0b11)
+	if result > WhiteWin {
+		return Record{}, fmt.Errorf("bad result: %d", result)
+	}
+	if flags>>7 != 0 {
+		return Record{}, fmt.Errorf("bad flags: %#x", flags)
+	}
+
+	fmn := int(binary.LittleEndian.Uint16(buf[27:]))
+	if fmn == 0 {
+		return Record{}, fmt.Errorf("bad fullmove number: 0")
+	}
+
+	if buf[31] != 0 {
+		return Record{}, fmt.Errorf("bad reserved byte: %#x", buf[31])
+	}
+
+	opts := []core.PositionOption{
+		core.WithBoard(b),
+		core.WithSideToMove(sideToMove),
+		core.WithCastlingRights(cr),
+		core.WithHalfmoveClock(int(buf[26])),
+		core.WithFullmoveNumber(fmn),
+	}
+
+	if ep := core.Square(buf[25]); ep != 0 {
+		if ep > core.H8 {
+			return Record{}, fmt.Errorf("bad e.p. target: %d", ep)
+		}
+		opts = append(opts, core.WithEnPassantTarget(ep))
+	}
+
+	return Record{
+		Position: core.NewPosition(opts...),
+		Score:    int16(binary.LittleEndian.Uint16(buf[29:])),
+		Result:   result,
+	}, nil
+}
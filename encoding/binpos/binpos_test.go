@@ -0,0 +1,79 @@
+package binpos
+
+import (
+	"testing"
+
+	"github.com/clfs/lento/core"
+	"github.com/clfs/lento/encoding/fen"
+)
+
+var testRecords = []Record{
+	{fen.MustDecode(fen.Starting), 25, Draw},
+	{fen.MustDecode("rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 1"), -30, WhiteWin},
+	{fen.MustDecode("r1k4r/p2nb1p1/2b4p/1p1n1p2/2PP4/3Q1NB1/1P3PPP/R5K1 b - - 0 19"), 120, BlackWin},
+	{fen.MustDecode("4k3/8/8/8/8/8/4P3/4K3 w - - 5 39"), -32768, BlackWin},
+	{fen.MustDecode("r3k2r/8/8/8/8/8/8/R3K2R w Kq - 255 65535"), 32767, WhiteWin},
+}
+
+func TestEncodeDecode(t *testing.T) {
+	for _, want := range testRecords {
+		b, err := Encode(want)
+		if err != nil {
+			t.Errorf("%s: encode error: %v", fen.Encode(want.Position), err)
+			continue
+		}
+
+		got, err := Decode(b)
+		if err != nil {
+			t.Errorf("%s: decode error: %v", fen.Encode(want.Position), err)
+			continue
+		}
+
+		if want != got {
+			t.Errorf("changed in round trip: %+v -> %+v", want, got)
+		}
+	}
+}
+
+func TestEncode_Invalid(t *testing.T) {
+	// The starting position plus a 33rd piece.
+	b := core.NewBoard()
+	b.Set(core.WhiteQueen, core.E4)
+
+	cases := map[string]Record{
+		"too many pieces": {Position: core.NewPosition(core.WithBoard(b))},
+		"bad result":      {Position: fen.MustDecode(fen.Starting), Result: 3},
+		"halfmove clock":  {Position: fen.MustDecode("4k3/8/8/8/8/8/8/4K3 w - - 256 200")},
+		"fullmove number": {Position: fen.MustDecode("4k3/8/8/8/8/8/8/4K3 w - - 0 65536")},
+	}
+
+	for name, r := range cases {
+		if _, err := Encode(r); err == nil {
+			t.Errorf("%s: no error", name)
+		}
+	}
+}
+
+func TestDecode_Invalid(t *testing.T) {
+	valid, err := Encode(testRecords[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mutations := map[string]func(b *[Size]byte){
+		"bad piece":    func(b *[Size]byte) { b[8] = 0xff },
+		"bad result":   func(b *[Size]byte) { b[24] |= 0b11 << 5 },
+		"bad flags":    func(b *[Size]byte) { b[24] |= 1 << 7 },
+		"bad fullmove": func(b *[Size]byte) { b[27], b[28] = 0, 0 },
+		"bad reserved": func(b *[Size]byte) { b[31] = 1 },
+		"bad e.p.":     func(b *[Size]byte) { b[25] = 64 },
+	}
+
+	for name, mutate := range mutations {
+		b := valid
+		mutate(&b)
+		if _, err := Decode(b); err == nil {
+			t.Errorf("%s: no error", name)
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package binpos
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+)
+
+// Streams begin with the four bytes "LBPS", followed by any number of blocks.
+// Each block is laid out as follows, with all integers little-endian:
+//
+//   - Record count: uint32, from 1 to BlockLen.
+//   - Records: count encoded records of [Size] bytes each.
+//   - Checksum: uint32 CRC-32 (IEEE) of the record count and records.
+const streamMagic = "LBPS"
+
+// BlockLen is the maximum number of records in a block.
+const BlockLen = 4096
+
+// ErrChecksum is returned when reading a block with an invalid checksum.
+var ErrChecksum = errors.New("invalid checksum")
+
+// A Writer writes records to a checksummed stream.
+//
+// Records are buffered into blocks. Call [Writer.Flush] when done writing.
+type Writer struct {
+	w           io.Writer
+	buf         []byte // record count, then records
+	n           int    // records in buf
+	wroteHeader bool
+}
+
+// NewWriter returns a new writer that writes to w.
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{
+		w:   w,
+		buf: make([]byte, 4, 4+BlockLen*Size+4),
+	}
+}
+
+// Write buffers a record, writing out a block when the buffer is full.
+func (w *Writer) Write(r Record) error {
+	b, err := Encode(r)
+	if err != nil {
+		return err
+	}
+	w.buf = append(w.buf, b[:]...)
+	w.n++
+	if w.n == BlockLen {
+		return w.Flush()
+	}
+	return nil
+}
+
+// Flush writes any buffered records to the underlying writer.
+func (w *Writer) Flush() error {
+	if !w.wroteHeader {
+		if _, err := io.WriteString(w.w, streamMagic); err != nil {
+			return err
+		}
+		w.wroteHeader = true
+	}
+	if w.n == 0 {
+		return nil
+	}
+
+	binary.LittleEndian.PutUint32(w.buf, uint32(w.n))
+	w.buf = binary.LittleEndian.AppendUint32(w.buf, crc32.ChecksumIEEE(w.buf))
+	if _, err := w.w.Write(w.buf); err != nil {
+		return err
+	}
+
+	w.buf = w.buf[:4]
+	w.n = 0
+	return nil
+}
+
+// A Reader reads records from a checksummed stream.
+type Reader struct {
+	r          *bufio.Reader
+	block      []byte // unread records in the current block
+	readHeader bool
+}
+
+// NewReader returns a new reader that reads from r.
+func NewReader(r io.Reader) *Reader {
+	return &Reader{r: bufio.NewReader(r)}
+}
+
+// Read reads the next record. At the end of the stream, Read returns
+// [io.EOF].
+//
+// Read verifies each block's checksum before returning any of its records.
+func (r *Reader) Read() (Record, error) {
+	if !r.readHeader {
+		var magic [len(streamMagic)]byte
+		if _, err := io.ReadFull(r.r, magic[:]); err != nil {
+			return Record{}, fmt.Errorf("bad header: %w", noEOF(err))
+		}
+		if string(magic[:]) != streamMagic {
+			return Record{}, fmt.Errorf("bad magic: %q", magic)
+		}
+		r.readHeader = true
+	}
+
+	if len(r.block) == 0 {
+		if err := r.readBlock(); err != nil {
+			return Record{}, err
+		}
+	}
+
+	rec, err := Decode([Size]byte(r.block))
+	r.block = r.block[Size:]
+	return rec, err
+}
+
+func (r *Reader) readBlock() error {
+	var head [4]byte
+	if _, err := io.ReadFull(r.r, head[:]); err != nil {
+		if err == io.EOF {
+			return io.EOF // clean end of stream
+		}
+		return fmt.Errorf("bad block: %w", noEOF(err))
+	}
+
+	n := binary.LittleEndian.Uint32(head[:])
+	if n == 0 || n > BlockLen {
+		return fmt.Errorf("bad record count: %d", n)
+	}
+
+	buf := make([]byte, 4+int(n)*Size+4)
+	copy(buf, head[:])
+	if _, err := io.ReadFull(r.r, buf[4:]); err != nil {
+		return fmt.Errorf("bad block: %w", noEOF(err))
+	}
+
+	body, sum := buf[:len(buf)-4], buf[len(buf)-4:]
+	if crc32.ChecksumIEEE(body) != binary.LittleEndian.Uint32(sum) {
+		return ErrChecksum
+	}
+
+	r.block = body[4:]
+	return nil
+}
+
+// noEOF converts io.EOF into io.ErrUnexpectedEOF, for reads that should not
+// end the stream.
+func noEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
@@ -0,0 +1,93 @@
+package binpos
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+)
+
+func writeStream(t *testing.T, records []Record) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	for _, r := range records {
+		if err := w.Write(r); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func readStream(b []byte) ([]Record, error) {
+	var res []Record
+	r := NewReader(bytes.NewReader(b))
+	for {
+		rec, err := r.Read()
+		if err == io.EOF {
+			return res, nil
+		}
+		if err != nil {
+			return res, err
+		}
+		res = append(res, rec)
+	}
+}
+
+func TestStream(t *testing.T) {
+	// Span several blocks, with a partial block at the end.
+	var want []Record
+	for i := 0; i < 2*BlockLen+3; i++ {
+		want = append(want, testRecords[i%len(testRecords)])
+	}
+
+	got, err := readStream(writeStream(t, want))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(want) != len(got) {
+		t.Fatalf("want %d records, got %d", len(want), len(got))
+	}
+	for i := range want {
+		if want[i] != got[i] {
+			t.Errorf("record %d: want %+v, got %+v", i, want[i], got[i])
+		}
+	}
+}
+
+func TestStream_Empty(t *testing.T) {
+	got, err := readStream(writeStream(t, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 0 {
+		t.Errorf("want no records, got %d", len(got))
+	}
+}
+
+func TestStream_Corrupt(t *testing.T) {
+	b := writeStream(t, testRecords)
+	b[len(streamMagic)+4+Size] ^= 1 // flip a bit in the second record
+
+	got, err := readStream(b)
+	if !errors.Is(err, ErrChecksum) {
+		t.Errorf("want %v, got %v", ErrChecksum, err)
+	}
+	if len(got) != 0 {
+		t.Errorf("want no records, got %d", len(got))
+	}
+}
+
+func TestStream_Truncated(t *testing.T) {
+	b := writeStream(t, testRecords)
+
+	_, err := readStream(b[:len(b)-1])
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("want %v, got %v", io.ErrUnexpectedEOF, err)
+	}
+}
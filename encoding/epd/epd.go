@@ -0,0 +1,249 @@
+// Package epd implements encoding and decoding Extended Position Description.
+//
+// This package follows "Standard: Portable Game Notation Specification and
+// Implementation Guide", revision 1994.03.12, §16.2. The halfmove clock and
+// fullmove number of a decoded position are taken from the "hmvc" and "fmvn"
+// operations, and default to 0 and 1 when absent.
+package epd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/clfs/lento/core"
+	"github.com/clfs/lento/encoding/fen"
+	"github.com/clfs/lento/encoding/san"
+)
+
+// A Record is a position and a list of operations.
+type Record struct {
+	Position   core.Position
+	Operations []Operation
+}
+
+// An Operation is an opcode with zero or more operands.
+type Operation struct {
+	Opcode   string
+	Operands []string
+}
+
+// moveOpcodes are opcodes whose operands are SAN moves from the record's
+// position.
+var moveOpcodes = map[string]bool{
+	"am": true, // avoid move(s)
+	"bm": true, // best move(s)
+	"pm": true, // predicted move
+	"sm": true, // supplied move
+}
+
+// Get returns the first operation with the given opcode, if any.
+func (r Record) Get(opcode string) (Operation, bool) {
+	for _, op := range r.Operations {
+		if op.Opcode == opcode {
+			return op, true
+		}
+	}
+	return Operation{}, false
+}
+
+// ID returns the operand of the "id" operation, or "" if there is none.
+func (r Record) ID() string {
+	op, ok := r.Get("id")
+	if !ok || len(op.Operands) == 0 {
+		return ""
+	}
+	return op.Operands[0]
+}
+
+// Int returns the first operand of an operation as an integer, like the
+// "acd" (analysis count depth) or "ce" (centipawn evaluation) operations.
+func (r Record) Int(opcode string) (int, error) {
+	op, ok := r.Get(opcode)
+	if !ok {
+		return 0, fmt.Errorf("missing opcode: %q", opcode)
+	}
+	if len(op.Operands) == 0 {
+		return 0, fmt.Errorf("missing operand: %q", opcode)
+	}
+	return strconv.Atoi(op.Operands[0])
+}
+
+// Moves resolves the SAN operands of an operation into moves.
+//
+// For the "pv" (predicted variation) opcode, each move is resolved in the
+// position after the previous moves. For other opcodes, like "bm" (best
+// moves) or "am" (avoid moves), each move is resolved in the record's
+// position.
+func (r Record) Moves(opcode string) ([]core.Move, error) {
+	op, ok := r.Get(opcode)
+	if !ok {
+		return nil, fmt.Errorf("missing opcode: %q", opcode)
+	}
+
+	var res []core.Move
+	p := r.Position
+	for _, s := range op.Operands {
+		m, err := san.Decode(p, s)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", opcode, err)
+		}
+		res = append(res, m)
+		if opcode == "pv" {
+			p.Move(m)
+		}
+	}
+	return res, nil
+}
+
+// Decode decodes a record from EPD.
+//
+// Decode returns an error if any move operand is illegal in the record's
+// position.
+func Decode(s string) (Record, error) {
+	// The four position fields are separated by any run of spaces and tabs,
+	// and the operations follow.
+	var fields []string
+	rest := s
+	for len(fields) < 4 {
+		rest = strings.TrimLeft(rest, " \t")
+		if rest == "" {
+			break
+		}
+		i := strings.IndexAny(rest, " \t")
+		if i < 0 {
+			i = len(rest)
+		}
+		fields = append(fields, rest[:i])
+		rest = rest[i:]
+	}
+	if n := len(fields); n < 4 {
+		return Record{}, fmt.Errorf("bad field count: %d", n)
+	}
+
+	var ops []Operation
+	if rest = strings.TrimSpace(rest); rest != "" {
+		var err error
+		ops, err = decodeOperations(rest)
+		if err != nil {
+			return Record{}, err
+		}
+	}
+
+	r := Record{Operations: ops}
+
+	hmvc, fmvn := "0", "1"
+	if op, ok := r.Get("hmvc"); ok && len(op.Operands) == 1 {
+		hmvc = op.Operands[0]
+	}
+	if op, ok := r.Get("fmvn"); ok && len(op.Operands) == 1 {
+		fmvn = op.Operands[0]
+	}
+
+	p, err := fen.Decode(strings.Join([]string{
+		fields[0], fields[1], fields[2], fields[3], hmvc, fmvn,
+	}, " "))
+	if err != nil {
+		return Record{}, err
+	}
+	r.Position = p
+
+	for _, op := range r.Operations {
+		if moveOpcodes[op.Opcode] || op.Opcode == "pv" {
+			if _, err := r.Moves(op.Opcode); err != nil {
+				return Record{}, err
+			}
+		}
+	}
+
+	return r, nil
+}
+
+// decodeOperations decodes semicolon-terminated operations.
+func decodeOperations(s string) ([]Operation, error) {
+	var (
+		res     []Operation
+		tokens  []string
+		current strings.Builder
+		quoted  bool
+		inToken bool
+	)
+
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quoted && c == '"':
+			quoted = false
+		case quoted:
+			current.WriteByte(c)
+		case c == '"':
+			quoted, inToken = true, true
+		case c == ' ' || c == '\t' || c == ';':
+			if inToken {
+				tokens = append(tokens, current.String())
+				current.Reset()
+				inToken = false
+			}
+			if c == ';' {
+				if len(tokens) == 0 {
+					return nil, fmt.Errorf("empty operation")
+				}
+				res = append(res, Operation{Opcode: tokens[0], Operands: tokens[1:]})
+				tokens = nil
+			}
+		default:
+			current.WriteByte(c)
+			inToken = true
+		}
+	}
+
+	if quoted {
+		return nil, fmt.Errorf("unterminated string: %q", s)
+	}
+	if inToken || len(tokens) != 0 {
+		return nil, fmt.Errorf("unterminated operation: %q", s)
+	}
+
+	return res, nil
+}
+
+// Encode encodes a record to EPD.
+//
+// The halfmove clock and fullmove number of the position are only encoded if
+// the record has "hmvc" and "fmvn" operations.
+func Encode(r Record) string {
+	p := r.Position
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s %s %s %s",
+		fen.EncodeBoard(p.Board()),
+		fen.EncodeColor(p.SideToMove()),
+		fen.EncodeCastlingRights(p.CastlingRights()),
+		fen.EncodeEnPassantTarget(p.EnPassantTarget()),
+	)
+
+	for _, op := range r.Operations {
+		sb.WriteByte(' ')
+		sb.WriteString(op.Opcode)
+		for _, operand := range op.Operands {
+			sb.WriteByte(' ')
+			if needsQuotes(op.Opcode, operand) {
+				fmt.Fprintf(&sb, "\"%s\"", operand)
+			} else {
+				sb.WriteString(operand)
+			}
+		}
+		sb.WriteByte(';')
+	}
+
+	return sb.String()
+}
+
+// needsQuotes returns true if an operand must be written as a string.
+func needsQuotes(opcode, operand string) bool {
+	switch opcode {
+	case "id", "c0", "c1", "c2", "c3", "c4", "c5", "c6", "c7", "c8", "c9":
+		return true
+	}
+	return operand == "" || strings.ContainsAny(operand, " \t;")
+}
@@ -0,0 +1,146 @@
+package epd
+
+import (
+	"bufio"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/clfs/lento/core"
+)
+
+func readEPDFile(t *testing.T, name string) []string {
+	t.Helper()
+
+	f, err := os.Open(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	var res []string
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		line := s.Text()
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue // Ignore blank lines and comments.
+		}
+		res = append(res, line)
+	}
+	if err := s.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return res
+}
+
+func TestDecode_WAC(t *testing.T) {
+	for _, s := range readEPDFile(t, "testdata/wac.epd") {
+		r, err := Decode(s)
+		if err != nil {
+			t.Errorf("%q: error: %v", s, err)
+			continue
+		}
+		if !strings.HasPrefix(r.ID(), "WAC.") {
+			t.Errorf("%q: bad id: %q", s, r.ID())
+		}
+		if bm, err := r.Moves("bm"); err != nil || len(bm) != 1 {
+			t.Errorf("%q: bad best moves: %v (error: %v)", s, bm, err)
+		}
+		if got := Encode(r); s != got {
+			t.Errorf("changed in round trip: %q -> %q", s, got)
+		}
+	}
+}
+
+func TestDecode(t *testing.T) {
+	s := `r1bqkbnr/pppp1ppp/2n5/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R w KQkq - hmvc 2; fmvn 3; bm Bb5 Bc4; am a3; acd 12; ce 35; pv Bb5 a6 Ba4; c0 "Ruy Lopez; main line"; id "test.1";`
+
+	r, err := Decode(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := r.Position.HalfmoveClock(); got != 2 {
+		t.Errorf("halfmove clock: want 2, got %d", got)
+	}
+	if got := r.Position.FullmoveNumber(); got != 3 {
+		t.Errorf("fullmove number: want 3, got %d", got)
+	}
+
+	moves := func(ss ...string) []core.Move {
+		var res []core.Move
+		for _, s := range ss {
+			m, err := core.ParseMove(s)
+			if err != nil {
+				t.Fatal(err)
+			}
+			res = append(res, m)
+		}
+		return res
+	}
+
+	for opcode, want := range map[string][]core.Move{
+		"bm": moves("f1b5", "f1c4"),
+		"am": moves("a2a3"),
+		"pv": moves("f1b5", "a7a6", "b5a4"),
+	} {
+		got, err := r.Moves(opcode)
+		if err != nil {
+			t.Errorf("%s: error: %v", opcode, err)
+		} else if !reflect.DeepEqual(want, got) {
+			t.Errorf("%s: want %v, got %v", opcode, want, got)
+		}
+	}
+
+	for opcode, want := range map[string]int{"acd": 12, "ce": 35} {
+		if got, err := r.Int(opcode); err != nil || want != got {
+			t.Errorf("%s: want %d, got %d (error: %v)", opcode, want, got, err)
+		}
+	}
+
+	if op, ok := r.Get("c0"); !ok || !reflect.DeepEqual(op.Operands, []string{"Ruy Lopez; main line"}) {
+		t.Errorf("c0: got %v", op)
+	}
+	if got := r.ID(); got != "test.1" {
+		t.Errorf("id: want %q, got %q", "test.1", got)
+	}
+
+	if got := Encode(r); s != got {
+		t.Errorf("changed in round trip: %q -> %q", s, got)
+	}
+}
+
+func TestDecode_Whitespace(t *testing.T) {
+	want := "r1bqkbnr/pppp1ppp/2n5/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R w KQkq - bm Bb5; id \"ruy\";"
+	for _, s := range []string{
+		"r1bqkbnr/pppp1ppp/2n5/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R\tw\tKQkq\t-\tbm Bb5; id \"ruy\";",
+		"  r1bqkbnr/pppp1ppp/2n5/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R  w \t KQkq   -   bm  Bb5;\tid \"ruy\";  ",
+	} {
+		r, err := Decode(s)
+		if err != nil {
+			t.Errorf("%q: %v", s, err)
+			continue
+		}
+		if got := Encode(r); want != got {
+			t.Errorf("%q: want %q, got %q", s, want, got)
+		}
+	}
+}
+
+func TestDecode_Invalid(t *testing.T) {
+	for _, s := range []string{
+		"",
+		"8/8/8/8/8/8/8/K6k w -",
+		"8/8/8/8/8/8/8/K6k w - - bm",
+		"8/8/8/8/8/8/8/K6k w - - ;",
+		`8/8/8/8/8/8/8/K6k w - - id "unterminated;`,
+		"8/8/8/8/8/8/8/K6k w - - hmvc x;",
+		"8/8/8/8/8/8/8/K6k w - - bm Ka3;",
+		"8/8/8/8/8/8/8/K6k w - - pv Ka2 Kb3;",
+	} {
+		if _, err := Decode(s); err == nil {
+			t.Errorf("%q: no error", s)
+		}
+	}
+}
@@ -18,14 +18,52 @@ func MustDecode(s string) core.Position {
 	return p
 }
 
-// Decode decodes a position from FEN.
+// Decode decodes a standard chess position from FEN.
 func Decode(s string) (core.Position, error) {
+	return DecodeVariant(s, core.Standard)
+}
+
+// DecodeVariant decodes a position in variant v from FEN.
+//
+// In [core.Crazyhouse], the board may be followed by the pieces in hand in
+// brackets, like "[QNp]", and promoted pieces may be followed by "~". If the
+// brackets are missing, both pockets are empty.
+//
+// In [core.ThreeCheck], the fullmove number may be followed by the number of
+// checks given by each side, like "+1+0". If it is missing, neither side has
+// given check.
+func DecodeVariant(s string, v core.Variant) (core.Position, error) {
 	fields := strings.Split(s, " ")
+
+	var opts []core.PositionOption
+
+	if v == core.ThreeCheck && len(fields) == 7 {
+		checkOpts, err := decodeChecks(fields[6])
+		if err != nil {
+			return core.Position{}, err
+		}
+		opts = append(opts, checkOpts...)
+		fields = fields[:6]
+	}
+
 	if n := len(fields); n != 6 {
 		return core.Position{}, fmt.Errorf("bad field count: %d", n)
 	}
 
-	board, err := DecodeBoard(fields[0])
+	boardField := fields[0]
+	if v == core.Crazyhouse {
+		var (
+			pocketOpts []core.PositionOption
+			err        error
+		)
+		boardField, pocketOpts, err = decodePockets(boardField)
+		if err != nil {
+			return core.Position{}, err
+		}
+		opts = append(opts, pocketOpts...)
+	}
+
+	board, promoted, err := decodeBoard(boardField, v == core.Crazyhouse)
 	if err != nil {
 		return core.Position{}, fmt.Errorf("bad board: %v", err)
 	}
@@ -71,13 +109,15 @@ func Decode(s string) (core.Position, error) {
 		return core.Position{}, err
 	}
 
-	opts := []core.PositionOption{
+	opts = append(opts,
+		core.WithVariant(v),
+		core.WithPromoted(promoted),
 		core.WithBoard(board),
 		core.WithSideToMove(sideToMove),
 		core.WithCastlingRights(castlingRights),
 		core.WithHalfmoveClock(halfmoveClock),
 		core.WithFullmoveNumber(fullmoveNumber),
-	}
+	)
 
 	if epTarget, ok := enPassantRight.Get(); ok {
 		opts = append(opts, core.WithEnPassantTarget(epTarget))
@@ -111,47 +151,115 @@ func decodePiece(s string) (core.Piece, error) {
 	return p, nil
 }
 
+// decodePockets splits a Crazyhouse board field, like
+// "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR[Qp]", into the board and
+// options setting the pockets.
+func decodePockets(s string) (string, []core.PositionOption, error) {
+	board, pockets, ok := strings.Cut(s, "[")
+	if !ok {
+		return s, nil, nil
+	}
+	pockets, ok = strings.CutSuffix(pockets, "]")
+	if !ok {
+		return "", nil, fmt.Errorf("bad pockets: %q", s)
+	}
+
+	var pk [2]core.Pocket
+	for _, rn := range pockets {
+		piece, err := decodePiece(string(rn))
+		if err != nil || piece.Type() == core.King {
+			return "", nil, fmt.Errorf("bad pockets: %q", pockets)
+		}
+		i := 0
+		if piece.Color() == core.Black {
+			i = 1
+		}
+		pk[i].Add(piece.Type())
+	}
+
+	opts := []core.PositionOption{
+		core.WithPocket(core.White, pk[0]),
+		core.WithPocket(core.Black, pk[1]),
+	}
+	return board, opts, nil
+}
+
+var checksRegexp = regexp.MustCompile(`^\+([0-3])\+([0-3])$`)
+
+// decodeChecks decodes the Three-check counters, like "+1+0", into options
+// setting the checks given by each side.
+func decodeChecks(s string) ([]core.PositionOption, error) {
+	m := checksRegexp.FindStringSubmatch(s)
+	if m == nil {
+		return nil, fmt.Errorf("bad checks: %q", s)
+	}
+	white, _ := strconv.Atoi(m[1])
+	black, _ := strconv.Atoi(m[2])
+	return []core.PositionOption{
+		core.WithChecksGiven(core.White, white),
+		core.WithChecksGiven(core.Black, black),
+	}, nil
+}
+
 // DecodeBoard decodes a board from FEN.
 func DecodeBoard(s string) (core.Board, error) {
-	var b core.Board
+	b, _, err := decodeBoard(s, false)
+	return b, err
+}
+
+// decodeBoard decodes a board from FEN. If promotions is true, pieces may be
+// followed by "~" to mark them as promoted, and the promoted pieces are
+// returned too.
+func decodeBoard(s string, promotions bool) (core.Board, core.Bitboard, error) {
+	var (
+		b        core.Board
+		promoted core.Bitboard
+	)
 
 	offset := int(core.A8) // top left corner
 
 	ranks := strings.Split(s, "/")
 	if n := len(ranks); n != 8 {
-		return core.Board{}, fmt.Errorf("bad rank count: %d", n)
+		return core.Board{}, 0, fmt.Errorf("bad rank count: %d", n)
 	}
 
 	for i, rank := range ranks {
-		var numPrev bool
+		var numPrev, piecePrev bool
 		for _, rn := range rank {
 			switch rn {
 			case '1', '2', '3', '4', '5', '6', '7', '8':
 				if numPrev {
-					return core.Board{}, fmt.Errorf("bad rank: %q", rank)
+					return core.Board{}, 0, fmt.Errorf("bad rank: %q", rank)
 				}
 				offset += int(rn - '0') // advance rightwards by n
-				numPrev = true
+				numPrev, piecePrev = true, false
+			case '~':
+				// Marks the previous piece as promoted.
+				if !promotions || !piecePrev {
+					return core.Board{}, 0, fmt.Errorf("bad rank: %q", rank)
+				}
+				promoted.Set(core.Square(offset - 1))
+				piecePrev = false
 			default:
 				piece, err := decodePiece(string(rn))
 				if err != nil {
-					return core.Board{}, err
+					return core.Board{}, 0, err
 				}
 				b.Set(piece, core.Square(offset))
 				offset++ // advance rightwards by 1
-				numPrev = false
+				numPrev, piecePrev = false, true
 			}
 		}
 
 		// Were all eight squares accounted for?
 		if offset != 8*(8-i) {
-			return core.Board{}, fmt.Errorf("bad rank: %q", rank)
+			return core.Board{}, 0, fmt.Errorf("bad rank: %q", rank)
 		}
 
 		offset -= 16 // advance down by 2
 	}
 
-	return b, nil
+	return b, promoted, nil
 }
 
 // DecodeColor decodes a color from FEN.
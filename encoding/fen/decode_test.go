@@ -6,6 +6,8 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+
+	"github.com/clfs/lento/core"
 )
 
 func readFENFile(t testing.TB, name string) []string {
@@ -81,3 +83,111 @@ func FuzzRoundTrip(f *testing.F) {
 		}
 	})
 }
+
+func TestDecodeVariant_Crazyhouse(t *testing.T) {
+	cases := map[string]string{
+		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR[] w KQkq - 0 1":              "",
+		"r1bqk2r/pppp1ppp/2n1p3/4P3/1b1Pn3/2NB1N2/PPP2PPP/R1BQK2R[] b KQkq - 0 1": "",
+		"2k5/8/8/8/8/8/8/4K3[QRBNPqrbnp] w - - 0 1":                               "",
+		"2k5/8/8/8/8/8/8/4K3[NPPq] w - - 0 1":                                     "",
+		"2k1Q~3/8/8/8/8/8/8/4K3[] b - - 0 1":                                      "",
+		// Missing pockets are empty.
+		"8/8/8/8/8/8/8/K6k w - - 0 1": "8/8/8/8/8/8/8/K6k[] w - - 0 1",
+		// Pockets are sorted.
+		"8/8/8/8/8/8/8/K6k[pPNQ] w - - 0 1": "8/8/8/8/8/8/8/K6k[QNPp] w - - 0 1",
+	}
+	for s, want := range cases {
+		if want == "" {
+			want = s
+		}
+		p, err := DecodeVariant(s, core.Crazyhouse)
+		if err != nil {
+			t.Errorf("%q: error: %v", s, err)
+			continue
+		}
+		if got := Encode(p); want != got {
+			t.Errorf("%q: want %q, got %q", s, want, got)
+		}
+	}
+}
+
+func TestDecodeVariant_Crazyhouse_Invalid(t *testing.T) {
+	for _, s := range []string{
+		"8/8/8/8/8/8/8/K6k[K] w - - 0 1",
+		"8/8/8/8/8/8/8/K6k[x] w - - 0 1",
+		"8/8/8/8/8/8/8/K6k[Q w - - 0 1",
+		"8/8/8/8/8/8/8/~K6k[] w - - 0 1",
+		"8/8/8/8/8/8/8/K~~6k[] w - - 0 1",
+		"8/8/8/8/8/8/8/1~K5k[] w - - 0 1",
+	} {
+		if _, err := DecodeVariant(s, core.Crazyhouse); err == nil {
+			t.Errorf("%q: no error", s)
+		}
+	}
+
+	// Standard chess has no pockets or promoted pieces.
+	for _, s := range []string{
+		"8/8/8/8/8/8/8/K6k[] w - - 0 1",
+		"8/8/8/8/8/8/8/K~6k w - - 0 1",
+	} {
+		if _, err := Decode(s); err == nil {
+			t.Errorf("%q: no error", s)
+		}
+	}
+}
+
+func TestDecodeVariant_ThreeCheck(t *testing.T) {
+	cases := map[string]string{
+		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1 +0+0":       "",
+		"rnb1kbnr/pppp1ppp/8/4p3/4P2q/8/PPPP1PPP/RNBQKBNR w KQkq - 1 3 +0+2":  "",
+		"r1bqkbnr/pppp1Bpp/2n5/4p3/4P3/8/PPPP1PPP/RNBQK1NR b KQkq - 0 3 +1+0": "",
+		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1":            "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1 +0+0",
+	}
+	for s, want := range cases {
+		if want == "" {
+			want = s
+		}
+		p, err := DecodeVariant(s, core.ThreeCheck)
+		if err != nil {
+			t.Errorf("%q: error: %v", s, err)
+			continue
+		}
+		if got := Encode(p); want != got {
+			t.Errorf("%q: want %q, got %q", s, want, got)
+		}
+	}
+
+	for _, s := range []string{
+		"8/8/8/8/8/8/8/K6k w - - 0 1 +4+0",
+		"8/8/8/8/8/8/8/K6k w - - 0 1 3+3",
+		"8/8/8/8/8/8/8/K6k w - - 0 1 +0+0 x",
+	} {
+		if _, err := DecodeVariant(s, core.ThreeCheck); err == nil {
+			t.Errorf("%q: no error", s)
+		}
+	}
+
+	// Standard chess has no check counters.
+	if _, err := Decode("8/8/8/8/8/8/8/K6k w - - 0 1 +0+0"); err == nil {
+		t.Error("standard: no error")
+	}
+}
+
+func TestDecodeVariant_Starting(t *testing.T) {
+	cases := map[core.Variant]string{
+		core.Antichess:   "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w - - 0 1",
+		core.Horde:       "rnbqkbnr/pppppppp/8/1PP2PP1/PPPPPPPP/PPPPPPPP/PPPPPPPP/PPPPPPPP w kq - 0 1",
+		core.RacingKings: "8/8/8/8/8/8/krbnNBRK/qrbnNBRQ w - - 0 1",
+		core.ThreeCheck:  "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1 +0+0",
+	}
+	for v, s := range cases {
+		got, err := DecodeVariant(s, v)
+		if err != nil {
+			t.Errorf("%v: error: %v", v, err)
+			continue
+		}
+		if want := core.NewPosition(core.WithVariant(v)); want != got {
+			t.Errorf("%v: want %q, got %q", v, Encode(want), Encode(got))
+		}
+	}
+}
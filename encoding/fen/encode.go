@@ -8,16 +8,30 @@ import (
 )
 
 // Encode encodes a position to FEN.
+//
+// In [core.Crazyhouse], promoted pieces are followed by "~", and the board is
+// followed by the pieces in hand in brackets, like "[QNp]".
+//
+// In [core.ThreeCheck], the fullmove number is followed by the number of
+// checks given by each side, like "+1+0".
 func Encode(p core.Position) string {
 	var b strings.Builder
 
-	fmt.Fprintf(&b, "%s ", EncodeBoard(p.Board()))
+	if p.Variant() == core.Crazyhouse {
+		fmt.Fprintf(&b, "%s[%s] ", encodeBoard(p.Board(), p.Promoted()), encodePockets(p))
+	} else {
+		fmt.Fprintf(&b, "%s ", EncodeBoard(p.Board()))
+	}
 	fmt.Fprintf(&b, "%s ", EncodeColor(p.SideToMove()))
 	fmt.Fprintf(&b, "%s ", EncodeCastlingRights(p.CastlingRights()))
 	fmt.Fprintf(&b, "%s ", EncodeEnPassantTarget(p.EnPassantTarget()))
 	fmt.Fprintf(&b, "%d ", p.HalfmoveClock())
 	fmt.Fprintf(&b, "%d", p.FullmoveNumber())
 
+	if p.Variant() == core.ThreeCheck {
+		fmt.Fprintf(&b, " +%d+%d", p.ChecksGiven(core.White), p.ChecksGiven(core.Black))
+	}
+
 	return b.String()
 }
 
@@ -47,8 +61,25 @@ func EncodeColor(c core.Color) string {
 	return "b"
 }
 
+// encodePockets encodes the pieces in hand, White's first, like "QNp".
+func encodePockets(p core.Position) string {
+	var sb strings.Builder
+	for _, c := range []core.Color{core.White, core.Black} {
+		pk := p.Pocket(c)
+		for _, pt := range []core.PieceType{core.Queen, core.Rook, core.Bishop, core.Knight, core.Pawn} {
+			sb.WriteString(strings.Repeat(encodePiece(core.NewPiece(c, pt)), pk.Count(pt)))
+		}
+	}
+	return sb.String()
+}
+
 // EncodeBoard encodes a board to FEN.
 func EncodeBoard(b core.Board) string {
+	return encodeBoard(b, 0)
+}
+
+// encodeBoard encodes a board to FEN, marking promoted pieces with "~".
+func encodeBoard(b core.Board, promoted core.Bitboard) string {
 	var sb strings.Builder
 
 	for r := core.Rank8; r <= core.Rank8; r-- {
@@ -69,6 +100,9 @@ func EncodeBoard(b core.Board) string {
 			}
 
 			sb.WriteString(encodePiece(p))
+			if s := core.NewSquare(f, r); promoted.Get(s) {
+				sb.WriteByte('~')
+			}
 		}
 
 		// Row ends in gap?
@@ -0,0 +1,342 @@
+package pgn
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/clfs/lento/core"
+	"github.com/clfs/lento/encoding/fen"
+	"github.com/clfs/lento/encoding/san"
+)
+
+// maxLineBytes is the longest line a [Decoder] accepts.
+const maxLineBytes = 1 << 20
+
+// A Decoder reads games from a stream of PGN.
+type Decoder struct {
+	s       *bufio.Scanner
+	pending *string // a line read ahead, if any
+	err     *ReadError
+}
+
+// A ReadError is an error reading the stream, like a line longer than a
+// megabyte. A [Decoder] reads no more games after one.
+type ReadError struct {
+	Err error
+}
+
+func (e *ReadError) Error() string {
+	return "read pgn: " + e.Err.Error()
+}
+
+func (e *ReadError) Unwrap() error {
+	return e.Err
+}
+
+// NewDecoder returns a new decoder that reads from r.
+func NewDecoder(r io.Reader) *Decoder {
+	s := bufio.NewScanner(r)
+	s.Buffer(nil, maxLineBytes)
+	return &Decoder{s: s}
+}
+
+// Decode reads the next game, returning [io.EOF] if there are none left.
+//
+// If a game is malformed, Decode returns an error, and the next call reads
+// the game after it. If reading the stream fails, Decode returns a
+// [*ReadError], and so does every later call.
+func (d *Decoder) Decode() (Game, error) {
+	if d.err != nil {
+		return Game{}, d.err
+	}
+	text, err := d.next()
+	if err != nil {
+		return Game{}, err
+	}
+	return Decode(text)
+}
+
+// next returns the text of the next game.
+func (d *Decoder) next() (string, error) {
+	var (
+		sb       strings.Builder
+		movetext bool // seen movetext
+		comment  bool // inside a brace comment
+	)
+	for {
+		line, ok := d.line()
+		if !ok {
+			break
+		}
+		trimmed := strings.TrimSpace(line)
+		if !comment && movetext && strings.HasPrefix(trimmed, "[") {
+			d.pending = &line
+			break
+		}
+		if strings.HasPrefix(line, "%") {
+			continue // escape mechanism (§6)
+		}
+		if trimmed != "" && !strings.HasPrefix(trimmed, "[") {
+			movetext = true
+		}
+		wasComment := comment
+		comment = inComment(line, comment)
+		sb.WriteString(line)
+		sb.WriteByte('\n')
+		if !wasComment && !comment && endsGame(line) {
+			break
+		}
+	}
+	if err := d.s.Err(); err != nil {
+		d.err = &ReadError{err}
+		return "", d.err
+	}
+	if strings.TrimSpace(sb.String()) == "" {
+		return "", io.EOF
+	}
+	return sb.String(), nil
+}
+
+func (d *Decoder) line() (string, bool) {
+	if d.pending != nil {
+		line := *d.pending
+		d.pending = nil
+		return line, true
+	}
+	if !d.s.Scan() {
+		return "", false
+	}
+	return d.s.Text(), true
+}
+
+// inComment reports whether a brace comment is open at the end of line,
+// given whether one was open at its start.
+func inComment(line string, open bool) bool {
+	for _, c := range line {
+		switch {
+		case open:
+			open = c != '}'
+		case c == '{':
+			open = true
+		case c == ';':
+			return false
+		}
+	}
+	return open
+}
+
+// endsGame reports whether line ends with a game termination marker.
+func endsGame(line string) bool {
+	line, _, _ = strings.Cut(line, ";")
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return false
+	}
+	_, ok := parseResult(fields[len(fields)-1])
+	return ok
+}
+
+// Decode decodes a single game from PGN.
+//
+// Comments, NAGs and variations are skipped. The starting position is taken
+// from the "FEN" tag, if any. The result is taken from the game termination
+// marker.
+func Decode(s string) (Game, error) {
+	g := Game{Position: core.NewPosition()}
+
+	var (
+		t      = tokenizer{s: s}
+		p      core.Position
+		inited bool
+		depth  int // variation nesting
+	)
+	for {
+		tok, err := t.next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return Game{}, err
+		}
+
+		switch {
+		case tok[0] == '[':
+			if inited {
+				return Game{}, fmt.Errorf("bad tag: %q", tok)
+			}
+			tag, err := parseTag(tok)
+			if err != nil {
+				return Game{}, err
+			}
+			g.Tags = append(g.Tags, tag)
+			continue
+		case tok[0] == '{' || tok[0] == ';' || tok[0] == '$':
+			continue
+		case tok == "(":
+			depth++
+			continue
+		case tok == ")":
+			if depth == 0 {
+				return Game{}, errors.New("unbalanced variation")
+			}
+			depth--
+			continue
+		case depth > 0:
+			continue
+		}
+
+		if !inited {
+			if g.Position, err = startingPosition(g); err != nil {
+				return Game{}, err
+			}
+			p, inited = g.Position, true
+		}
+
+		if r, ok := parseResult(tok); ok {
+			g.Result = r
+			break
+		}
+
+		tok = trimMoveNumber(tok)
+		if tok == "" {
+			continue
+		}
+		m, err := san.Decode(p, tok)
+		if err != nil {
+			return Game{}, fmt.Errorf("move %d: %v", len(g.Moves)+1, err)
+		}
+		p.Move(m)
+		g.Moves = append(g.Moves, m)
+	}
+
+	if depth > 0 {
+		return Game{}, errors.New("unbalanced variation")
+	}
+	if !inited {
+		var err error
+		if g.Position, err = startingPosition(g); err != nil {
+			return Game{}, err
+		}
+	}
+	return g, nil
+}
+
+// startingPosition returns the position given by g's "FEN" tag, or the
+// standard starting position.
+func startingPosition(g Game) (core.Position, error) {
+	s, ok := g.Tag("FEN")
+	if !ok {
+		return core.NewPosition(), nil
+	}
+	p, err := fen.Decode(s)
+	if err != nil {
+		return core.Position{}, fmt.Errorf("bad FEN tag: %v", err)
+	}
+	return p, nil
+}
+
+func parseResult(s string) (core.Result, bool) {
+	switch s {
+	case "1-0":
+		return core.WhiteWins, true
+	case "0-1":
+		return core.BlackWins, true
+	case "1/2-1/2":
+		return core.Draw, true
+	case "*":
+		return core.NoResult, true
+	}
+	return core.NoResult, false
+}
+
+// trimMoveNumber removes a move number indication, like "12." or "12...",
+// from the start of s.
+func trimMoveNumber(s string) string {
+	t := strings.TrimLeft(s, "0123456789")
+	if t == s || !strings.HasPrefix(t, ".") {
+		return s
+	}
+	return strings.TrimLeft(t, ".")
+}
+
+// parseTag parses a tag pair token, like `[Event "F/S Return Match"]`.
+func parseTag(tok string) (Tag, error) {
+	inner := strings.TrimSpace(tok[1 : len(tok)-1])
+	name, value, ok := strings.Cut(inner, " ")
+	value = strings.TrimSpace(value)
+	if !ok || name == "" || len(value) < 2 || value[0] != '"' || value[len(value)-1] != '"' {
+		return Tag{}, fmt.Errorf("bad tag: %q", tok)
+	}
+	value = value[1 : len(value)-1]
+	value = strings.ReplaceAll(value, `\"`, `"`)
+	value = strings.ReplaceAll(value, `\\`, `\`)
+	return Tag{Name: name, Value: value}, nil
+}
+
+// A tokenizer splits PGN into tokens: tag pairs, comments, NAGs,
+// parentheses, and symbols such as moves and results.
+type tokenizer struct {
+	s string
+	i int
+}
+
+func (t *tokenizer) next() (string, error) {
+	for t.i < len(t.s) && isSpace(t.s[t.i]) {
+		t.i++
+	}
+	if t.i == len(t.s) {
+		return "", io.EOF
+	}
+
+	start := t.i
+	switch t.s[start] {
+	case '[':
+		return t.until(start, ']', true)
+	case '{':
+		return t.until(start, '}', false)
+	case ';':
+		end := strings.IndexByte(t.s[start:], '\n')
+		if end < 0 {
+			end = len(t.s) - start
+		}
+		t.i = start + end
+		return t.s[start:t.i], nil
+	case '(', ')':
+		t.i++
+		return t.s[start:t.i], nil
+	}
+
+	for t.i < len(t.s) && !isSpace(t.s[t.i]) && !strings.ContainsRune("[]{}();", rune(t.s[t.i])) {
+		t.i++
+	}
+	if t.i == start {
+		return "", fmt.Errorf("bad token: %q", t.s[start:start+1])
+	}
+	return t.s[start:t.i], nil
+}
+
+// until returns the token from start through the next end byte. If quoted is
+// true, end bytes inside quoted strings are skipped.
+func (t *tokenizer) until(start int, end byte, quoted bool) (string, error) {
+	inString := false
+	for i := start + 1; i < len(t.s); i++ {
+		c := t.s[i]
+		switch {
+		case inString && c == '\\':
+			i++
+		case quoted && c == '"':
+			inString = !inString
+		case !inString && c == end:
+			t.i = i + 1
+			return t.s[start:t.i], nil
+		}
+	}
+	return "", fmt.Errorf("unterminated %q", t.s[start:start+1])
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
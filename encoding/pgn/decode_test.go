@@ -0,0 +1,159 @@
+package pgn
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/clfs/lento/core"
+	"github.com/clfs/lento/encoding/fen"
+)
+
+func TestDecode(t *testing.T) {
+	s := `[Event "Test"]
+[White "A \"quoted\" name"]
+[Result "0-1"]
+
+1.f3 {a comment
+spanning lines} e5 $2 2. g4?! (2. e4 Nc6 (2... d6) 3. d4) 2... Qh4# ; mate
+0-1
+`
+	want := Game{
+		Tags: []Tag{
+			{"Event", "Test"},
+			{"White", `A "quoted" name`},
+			{"Result", "0-1"},
+		},
+		Position: core.NewPosition(),
+		Moves:    parseMoves(t, "f2f3", "e7e5", "g2g4", "d8h4"),
+		Result:   core.BlackWins,
+	}
+
+	got, err := Decode(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("want %+v, got %+v", want, got)
+	}
+}
+
+func TestDecode_FEN(t *testing.T) {
+	s := `[SetUp "1"]
+[FEN "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 1"]
+
+1... e5 2. Nf3 *
+`
+	g, err := Decode(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := fen.MustDecode("rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 1"); want != g.Position {
+		t.Errorf("want position %v, got %v", fen.Encode(want), fen.Encode(g.Position))
+	}
+	if want := parseMoves(t, "e7e5", "g1f3"); !reflect.DeepEqual(want, g.Moves) {
+		t.Errorf("want moves %v, got %v", want, g.Moves)
+	}
+	if g.Result != core.NoResult {
+		t.Errorf("want no result, got %v", g.Result)
+	}
+}
+
+func TestDecode_Invalid(t *testing.T) {
+	for _, s := range []string{
+		`[Event "unterminated]`,
+		`[Event] 1. e4 *`,
+		"1. e4 e5 2. Ke3 *",
+		"1. e4 (1. d4 *",
+		"1. e4 ) *",
+		`[FEN "bad"] *`,
+		"1. e4 {unterminated",
+	} {
+		if _, err := Decode(s); err == nil {
+			t.Errorf("%q: no error", s)
+		}
+	}
+}
+
+func TestDecoder(t *testing.T) {
+	s := `% an escaped line
+[Event "One"]
+
+1. e4 e5 1-0
+
+[Event "Two"]
+[Annotator "Nobody"]
+
+1. d4 {[%clk 0:01:00]
+[not a tag]} d5 2. Kd3 *
+
+[Event "Three"]
+
+1. c4 1/2-1/2
+1. e4 0-1
+`
+	d := NewDecoder(strings.NewReader(s))
+
+	g, err := d.Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ev, _ := g.Tag("Event"); ev != "One" || len(g.Moves) != 2 || g.Result != core.WhiteWins {
+		t.Errorf("game one: got %+v", g)
+	}
+
+	if _, err := d.Decode(); err == nil {
+		t.Error("game two: no error")
+	}
+
+	g, err = d.Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ev, _ := g.Tag("Event"); ev != "Three" || len(g.Moves) != 1 || g.Result != core.Draw {
+		t.Errorf("game three: got %+v", g)
+	}
+
+	g, err = d.Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(g.Tags) != 0 || len(g.Moves) != 1 || g.Result != core.BlackWins {
+		t.Errorf("game four: got %+v", g)
+	}
+
+	if _, err := d.Decode(); err != io.EOF {
+		t.Errorf("want io.EOF, got %v", err)
+	}
+}
+
+func TestDecode_RoundTrip(t *testing.T) {
+	g := Game{
+		Tags:     []Tag{{"Event", "Round trip"}},
+		Position: core.NewPosition(),
+		Moves:    parseMoves(t, "e2e4", "e7e5", "g1f3", "b8c6", "f1b5", "a7a6", "e1g1"),
+		Result:   core.Draw,
+	}
+	got, err := Decode(Encode(g))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(g, got) {
+		t.Errorf("want %+v, got %+v", g, got)
+	}
+}
+
+func TestDecoder_ReadError(t *testing.T) {
+	s := "[Event \"Long\"]\n\n1. e4 {" + strings.Repeat("x", maxLineBytes) + "} *\n\n[Event \"Next\"]\n\n1. d4 *\n"
+	d := NewDecoder(strings.NewReader(s))
+	for i := range 3 {
+		_, err := d.Decode()
+		var re *ReadError
+		if !errors.As(err, &re) || !errors.Is(err, bufio.ErrTooLong) {
+			t.Fatalf("call %d: want a read error wrapping bufio.ErrTooLong, got %v", i, err)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package pgn
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/clfs/lento/core"
+	"github.com/clfs/lento/encoding/san"
+)
+
+// maxLineLen is the maximum length of a movetext line.
+const maxLineLen = 79
+
+// Encode encodes a game to PGN, including a trailing blank line.
+//
+// The game's moves must be legal.
+func Encode(g Game) string {
+	var sb strings.Builder
+
+	for _, t := range g.Tags {
+		v := strings.ReplaceAll(t.Value, `\`, `\\`)
+		v = strings.ReplaceAll(v, `"`, `\"`)
+		fmt.Fprintf(&sb, "[%s \"%s\"]\n", t.Name, v)
+	}
+	sb.WriteByte('\n')
+
+	var (
+		tokens []string
+		p      = g.Position
+	)
+	for i, m := range g.Moves {
+		n := p.FullmoveNumber()
+		switch {
+		case p.SideToMove() == core.White:
+			tokens = append(tokens, fmt.Sprintf("%d.", n))
+		case i == 0:
+			tokens = append(tokens, fmt.Sprintf("%d...", n))
+		}
+		tokens = append(tokens, san.Encode(p, m))
+		p.Move(m)
+	}
+	tokens = append(tokens, g.Result.String())
+
+	lineLen := 0
+	for _, tok := range tokens {
+		if lineLen > 0 && lineLen+1+len(tok) > maxLineLen {
+			sb.WriteByte('\n')
+			lineLen = 0
+		}
+		if lineLen > 0 {
+			sb.WriteByte(' ')
+			lineLen++
+		}
+		sb.WriteString(tok)
+		lineLen += len(tok)
+	}
+	sb.WriteString("\n\n")
+
+	return sb.String()
+}
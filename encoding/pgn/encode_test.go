@@ -0,0 +1,78 @@
+package pgn
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/clfs/lento/core"
+	"github.com/clfs/lento/encoding/fen"
+)
+
+func parseMoves(t *testing.T, ss ...string) []core.Move {
+	t.Helper()
+	var res []core.Move
+	for _, s := range ss {
+		m, err := core.ParseMove(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res = append(res, m)
+	}
+	return res
+}
+
+func TestEncode(t *testing.T) {
+	g := Game{
+		Tags: []Tag{
+			{"Event", "Test"},
+			{"White", `A "quoted" name`},
+			{"Result", "0-1"},
+		},
+		Position: core.NewPosition(),
+		Moves:    parseMoves(t, "f2f3", "e7e5", "g2g4", "d8h4"),
+		Result:   core.BlackWins,
+	}
+
+	want := `[Event "Test"]
+[White "A \"quoted\" name"]
+[Result "0-1"]
+
+1. f3 e5 2. g4 Qh4# 0-1
+
+`
+	if got := Encode(g); want != got {
+		t.Errorf("want:\n%s\ngot:\n%s", want, got)
+	}
+}
+
+func TestEncode_BlackToMove(t *testing.T) {
+	p := fen.MustDecode("rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 1")
+	g := Game{
+		Position: p,
+		Moves:    parseMoves(t, "e7e5", "g1f3"),
+	}
+
+	want := "\n1... e5 2. Nf3 *\n\n"
+	if got := Encode(g); want != got {
+		t.Errorf("want %q, got %q", want, got)
+	}
+}
+
+func TestEncode_LineLength(t *testing.T) {
+	// Shuffle knights for long enough to need several lines.
+	var moves []string
+	for i := 0; i < 20; i++ {
+		moves = append(moves, "g1f3", "g8f6", "f3g1", "f6g8")
+	}
+	g := Game{Position: core.NewPosition(), Moves: parseMoves(t, moves...)}
+
+	lines := strings.Split(strings.TrimSpace(Encode(g)), "\n")
+	if len(lines) < 2 {
+		t.Fatalf("want several lines, got %d", len(lines))
+	}
+	for _, line := range lines {
+		if len(line) > maxLineLen {
+			t.Errorf("line too long: %q", line)
+		}
+	}
+}
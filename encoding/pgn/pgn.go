@@ -0,0 +1,37 @@
+// Package pgn implements encoding and decoding Portable Game Notation.
+//
+// This package follows "Standard: Portable Game Notation Specification and
+// Implementation Guide", revision 1994.03.12. It encodes games in export
+// format (§3.2) and decodes games in import format (§3.1).
+package pgn
+
+import "github.com/clfs/lento/core"
+
+// A Tag is a PGN tag pair.
+type Tag struct {
+	Name  string
+	Value string
+}
+
+// A Game is a game with its tag pairs.
+type Game struct {
+	// Tags are encoded in order. Games that don't start from the standard
+	// starting position should include "SetUp" and "FEN" tags.
+	Tags []Tag
+	// Position is the starting position.
+	Position core.Position
+	// Moves are the moves played from the starting position.
+	Moves []core.Move
+	// Result is the game result, encoded as the game termination marker.
+	Result core.Result
+}
+
+// Tag returns the value of the first tag with the given name, if any.
+func (g *Game) Tag(name string) (string, bool) {
+	for _, t := range g.Tags {
+		if t.Name == name {
+			return t.Value, true
+		}
+	}
+	return "", false
+}
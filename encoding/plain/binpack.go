@@ -0,0 +1,434 @@
+package plain
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/bits"
+
+	"github.com/clfs/lento/core"
+)
+
+// A BinpackReader reads entries from Stockfish .binpack data.
+//
+// A binpack file is a sequence of chunks, each made of the magic "BINP", a
+// little-endian 32-bit size and that many bytes of chains. A chain starts
+// with a 32-byte stem entry and a count of the plies that follow it, which
+// are packed into a bitstream: each move as an index among the moving side's
+// pieces and that piece's destinations, and each score as a variable-length
+// difference from the previous one.
+type BinpackReader struct {
+	r     io.Reader
+	buf   bytes.Buffer // holds chunk
+	chunk []byte
+	off   int // offset of the next chain in chunk
+
+	// The chain being read.
+	e         Entry
+	plies     int // plies left in the chain
+	bits      bitReader
+	lastScore int
+}
+
+// NewBinpackReader returns a new reader that reads from r.
+func NewBinpackReader(r io.Reader) *BinpackReader {
+	return &BinpackReader{r: r}
+}
+
+// stemSize is the size of a chain's stem entry and ply count.
+const stemSize = 34
+
+// Read reads the next entry. At the end of the input, Read returns [io.EOF].
+func (r *BinpackReader) Read() (Entry, error) {
+	if r.plies > 0 {
+		return r.nextPly()
+	}
+
+	if r.off >= len(r.chunk) {
+		if err := r.readChunk(); err != nil {
+			return Entry{}, err
+		}
+	}
+	if len(r.chunk)-r.off < stemSize {
+		return Entry{}, errors.New("truncated chain")
+	}
+	e, err := decodeStem(r.chunk[r.off : r.off+32])
+	if err != nil {
+		return Entry{}, err
+	}
+	r.e = e
+	r.plies = int(binary.BigEndian.Uint16(r.chunk[r.off+32:]))
+	r.off += stemSize
+	r.bits = bitReader{data: r.chunk[r.off:]}
+	r.lastScore = -e.Score
+	return e, nil
+}
+
+// readChunk reads the next chunk.
+func (r *BinpackReader) readChunk() error {
+	var header [8]byte
+	if _, err := io.ReadFull(r.r, header[:]); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			return errors.New("truncated chunk header")
+		}
+		return err
+	}
+	if string(header[:4]) != "BINP" {
+		return fmt.Errorf("bad chunk magic: %q", header[:4])
+	}
+	// The size comes from the input, so the buffer grows with the bytes
+	// actually read rather than being allocated up front.
+	r.buf.Reset()
+	size := int64(binary.LittleEndian.Uint32(header[4:]))
+	if _, err := io.CopyN(&r.buf, r.r, size); err != nil {
+		if err == io.EOF {
+			return errors.New("truncated chunk")
+		}
+		return err
+	}
+	r.chunk, r.off = r.buf.Bytes(), 0
+	return nil
+}
+
+// nextPly plays the last entry's move and reads the next ply of the chain.
+func (r *BinpackReader) nextPly() (Entry, error) {
+	p := r.e.Position
+	if !p.IsLegal(r.e.Move) {
+		return Entry{}, fmt.Errorf("illegal move: %v", r.e.Move)
+	}
+	p.Move(r.e.Move)
+
+	m, err := r.bits.move(&p)
+	if err != nil {
+		return Entry{}, err
+	}
+	delta, err := r.bits.vle16(4)
+	if err != nil {
+		return Entry{}, err
+	}
+
+	r.e = Entry{
+		Position: p,
+		Move:     m,
+		Score:    r.lastScore + int(unsignedToSigned(delta)),
+		Ply:      r.e.Ply + 1,
+		Result:   -r.e.Result,
+	}
+	r.lastScore = -r.e.Score
+	r.plies--
+	if r.plies == 0 {
+		r.off += r.bits.bytesRead()
+	}
+	return r.e, nil
+}
+
+// decodeStem decodes a 32-byte stem entry: a compressed position, a
+// compressed move, and the score, ply, result and halfmove clock.
+func decodeStem(data []byte) (Entry, error) {
+	score := unsignedToSigned(binary.BigEndian.Uint16(data[26:]))
+	pr := binary.BigEndian.Uint16(data[28:])
+	rule50 := binary.BigEndian.Uint16(data[30:])
+
+	e := Entry{
+		Score:  int(score),
+		Ply:    int(pr & 0x3fff),
+		Result: int(unsignedToSigned(pr >> 14)),
+	}
+	if e.Result < -1 || e.Result > 1 {
+		return Entry{}, fmt.Errorf("bad result: %d", e.Result)
+	}
+
+	var err error
+	e.Position, err = decodePosition(data[:24], int(rule50), e.Ply)
+	if err != nil {
+		return Entry{}, err
+	}
+	e.Move = decodeMove(binary.BigEndian.Uint16(data[24:]))
+	return e, nil
+}
+
+// decodePosition decodes a 24-byte compressed position: a big-endian
+// occupancy bitboard, then a nibble for each occupied square, in order.
+// Nibbles 0-11 are pieces, ordered by type and then color. The others are:
+//
+//   - 12: a pawn that just moved two squares and can be taken en passant.
+//   - 13 and 14: a white or black rook with castling rights.
+//   - 15: the black king, with Black to move.
+func decodePosition(data []byte, halfmoveClock, ply int) (core.Position, error) {
+	occupied := binary.BigEndian.Uint64(data)
+	if bits.OnesCount64(occupied) > 32 {
+		return core.Position{}, errors.New("too many pieces")
+	}
+
+	var (
+		b    core.Board
+		cr   core.CastlingRights
+		side = core.White
+		ep   *core.Square
+	)
+	for i := 0; occupied != 0; i++ {
+		s := core.Square(bits.TrailingZeros64(occupied))
+		occupied &= occupied - 1
+
+		nibble := data[8+i/2] >> (4 * (i % 2)) & 0xf
+		switch nibble {
+		case 12:
+			t := s.Below()
+			piece := core.WhitePawn
+			if s.Rank() == core.Rank5 {
+				t, piece = s.Above(), core.BlackPawn
+			}
+			b.Set(piece, s)
+			ep = &t
+		case 13:
+			b.Set(core.WhiteRook, s)
+			switch s {
+			case core.A1:
+				cr.SetWhiteOOO()
+			case core.H1:
+				cr.SetWhiteOO()
+			}
+		case 14:
+			b.Set(core.BlackRook, s)
+			switch s {
+			case core.A8:
+				cr.SetBlackOOO()
+			case core.H8:
+				cr.SetBlackOO()
+			}
+		case 15:
+			b.Set(core.BlackKing, s)
+			side = core.Black
+		default:
+			c := core.Color(nibble%2 == 1)
+			b.Set(core.NewPiece(c, core.PieceType(nibble/2)), s)
+		}
+	}
+
+	opts := []core.PositionOption{
+		core.WithBoard(b),
+		core.WithSideToMove(side),
+		core.WithCastlingRights(cr),
+		core.WithHalfmoveClock(halfmoveClock),
+		core.WithFullmoveNumber(ply/2 + 1),
+	}
+	if ep != nil {
+		opts = append(opts, core.WithEnPassantTarget(*ep))
+	}
+	return core.NewPosition(opts...), nil
+}
+
+// decodeMove decodes a 16-bit compressed move: from the top, 2 bits of move
+// type, 6 bits of initial square, 6 bits of final square and 2 bits of
+// promotion piece. Castling moves go from the king to the rook.
+func decodeMove(v uint16) core.Move {
+	from := core.Square(v >> 8 & 0x3f)
+	to := core.Square(v >> 2 & 0x3f)
+	switch v >> 14 {
+	case 1:
+		return core.NewPromotionMove(from, to, core.Knight+core.PieceType(v&3))
+	case 2:
+		if to > from {
+			return core.NewMove(from, from+2)
+		}
+		return core.NewMove(from, from-2)
+	default:
+		return core.NewMove(from, to)
+	}
+}
+
+// unsignedToSigned decodes an int16 from binpack's encoding, which keeps
+// small magnitudes in the low bits with the sign in the lowest.
+func unsignedToSigned(u uint16) int16 {
+	u = u<<15 | u>>1
+	if u&0x8000 != 0 {
+		u ^= 0x7fff
+	}
+	return int16(u)
+}
+
+// errShortBitstream is returned when a chain's bitstream ends early.
+var errShortBitstream = errors.New("truncated bitstream")
+
+// A bitReader reads a chain's bitstream, most significant bits first.
+type bitReader struct {
+	data []byte
+	n    int // bits read
+}
+
+// read reads an n-bit value, for n up to 8.
+func (br *bitReader) read(n int) (uint8, error) {
+	var v uint8
+	for range n {
+		i := br.n / 8
+		if i >= len(br.data) {
+			return 0, errShortBitstream
+		}
+		v = v<<1 | br.data[i]>>(7-br.n%8)&1
+		br.n++
+	}
+	return v, nil
+}
+
+// index reads an index below n, using just enough bits for n values.
+func (br *bitReader) index(n int) (int, error) {
+	if n == 0 {
+		return 0, errors.New("no moves")
+	}
+	v, err := br.read(bits.Len(uint(n - 1)))
+	if err == nil && int(v) >= n {
+		err = fmt.Errorf("bad index: %d of %d", v, n)
+	}
+	return int(v), err
+}
+
+// vle16 reads a variable-length value in blocks of size bits, least
+// significant first, each preceded by a bit saying whether another follows.
+func (br *bitReader) vle16(size int) (uint16, error) {
+	var v uint16
+	for shift := 0; ; shift += size {
+		block, err := br.read(size + 1)
+		if err != nil {
+			return 0, err
+		}
+		v |= uint16(block&(1<<size-1)) << shift
+		if block>>size == 0 {
+			return v, nil
+		}
+		if shift+size >= 16 {
+			return 0, errors.New("bad score")
+		}
+	}
+}
+
+// bytesRead returns the number of bytes read, counting partly read ones.
+func (br *bitReader) bytesRead() int {
+	return (br.n + 7) / 8
+}
+
+// move reads a move in p: the index of the moving piece among the side to
+// move's pieces, then the index of the move among the piece's destinations.
+func (br *bitReader) move(p *core.Position) (core.Move, error) {
+	b := p.Board()
+	us := p.SideToMove()
+	var ours, theirs uint64
+	for pt := core.Pawn; pt <= core.King; pt++ {
+		ours |= uint64(b.Bitboard(core.NewPiece(us, pt)))
+		theirs |= uint64(b.Bitboard(core.NewPiece(us.Other(), pt)))
+	}
+	occupied := ours | theirs
+
+	i, err := br.index(bits.OnesCount64(ours))
+	if err != nil {
+		return core.Move{}, err
+	}
+	from := nthSquare(ours, i)
+	piece, _ := b.Get(from)
+
+	switch piece.Type() {
+	case core.Pawn:
+		forward := 8
+		startRank, promotionRank := core.Rank2, core.Rank7
+		if us == core.Black {
+			forward = -8
+			startRank, promotionRank = core.Rank7, core.Rank2
+		}
+
+		targets := theirs
+		if ep, ok := epSquare(p); ok {
+			targets |= 1 << ep
+		}
+		dests := attacks(piece, from, occupied) & targets
+		if one := int(from) + forward; occupied&(1<<one) == 0 {
+			dests |= 1 << one
+			if two := one + forward; from.Rank() == startRank && occupied&(1<<two) == 0 {
+				dests |= 1 << two
+			}
+		}
+
+		n := bits.OnesCount64(dests)
+		if from.Rank() == promotionRank {
+			j, err := br.index(4 * n)
+			if err != nil {
+				return core.Move{}, err
+			}
+			promo := core.Knight + core.PieceType(j%4)
+			return core.NewPromotionMove(from, nthSquare(dests, j/4), promo), nil
+		}
+		j, err := br.index(n)
+		if err != nil {
+			return core.Move{}, err
+		}
+		return core.NewMove(from, nthSquare(dests, j)), nil
+
+	case core.King:
+		dests := attacks(piece, from, occupied) &^ ours
+		n := bits.OnesCount64(dests)
+
+		cr := p.CastlingRights()
+		long, short := cr.GetWhiteOOO(), cr.GetWhiteOO()
+		if us == core.Black {
+			long, short = cr.GetBlackOOO(), cr.GetBlackOO()
+		}
+		castlings := 0
+		for _, ok := range []bool{long, short} {
+			if ok {
+				castlings++
+			}
+		}
+
+		j, err := br.index(n + castlings)
+		if err != nil {
+			return core.Move{}, err
+		}
+		switch {
+		case j < n:
+			return core.NewMove(from, nthSquare(dests, j)), nil
+		case j == n && long:
+			return core.NewMove(from, from-2), nil
+		default:
+			return core.NewMove(from, from+2), nil
+		}
+
+	default:
+		dests := attacks(piece, from, occupied) &^ ours
+		j, err := br.index(bits.OnesCount64(dests))
+		if err != nil {
+			return core.Move{}, err
+		}
+		return core.NewMove(from, nthSquare(dests, j)), nil
+	}
+}
+
+// epSquare returns the en passant target square of p, if an en passant
+// capture is legal. Binpack only records the square in that case.
+func epSquare(p *core.Position) (core.Square, bool) {
+	ept := p.EnPassantTarget()
+	ep, ok := ept.Get()
+	if !ok {
+		return 0, false
+	}
+	b := p.Board()
+	pawn := core.NewPiece(p.SideToMove(), core.Pawn)
+	for _, m := range p.LegalMoves() {
+		if piece, _ := b.Get(m.From()); piece == pawn && m.To() == ep {
+			return ep, true
+		}
+	}
+	return 0, false
+}
+
+// nthSquare returns the square of the nth set bit of bb, counting from 0.
+func nthSquare(bb uint64, n int) core.Square {
+	for range n {
+		bb &= bb - 1
+	}
+	return core.Square(bits.TrailingZeros64(bb))
+}
+
+// attacks returns the squares piece on s attacks, as [core.Attacks] does.
+func attacks(piece core.Piece, s core.Square, occupied uint64) uint64 {
+	return uint64(core.Attacks(piece, s, core.Bitboard(occupied)))
+}
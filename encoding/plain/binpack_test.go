@@ -0,0 +1,125 @@
+package plain
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"runtime"
+	"testing"
+
+	"github.com/clfs/lento/encoding/fen"
+)
+
+// chunk returns a binpack chunk holding chains.
+func chunk(chains ...[]byte) []byte {
+	data := bytes.Join(chains, nil)
+	res := binary.LittleEndian.AppendUint32([]byte("BINP"), uint32(len(data)))
+	return append(res, data...)
+}
+
+// Two chains, written out by hand.
+var (
+	// 1. e4 e5 2. Nf3 from the starting position, won by White.
+	startChain = []byte{
+		// Occupancy.
+		0xff, 0xff, 0x00, 0x00, 0x00, 0x00, 0xff, 0xff,
+		// Pieces, with castling rooks.
+		0x2d, 0x84, 0x4a, 0xd2, 0x00, 0x00, 0x00, 0x00,
+		0x11, 0x11, 0x11, 0x11, 0x3e, 0x95, 0x5b, 0xe3,
+		0x0c, 0x70, // e2e4
+		0x00, 0x00, // score 0
+		0x80, 0x00, // ply 0, result 1
+		0x00, 0x00, // halfmove clock 0
+		0x00, 0x02, // 2 plies follow
+		// e7e5, score 0; g1f3, score 30.
+		0x40, 0x19, 0xe0, 0xc0,
+	}
+	// White takes en passant, then the king steps aside.
+	epChain = []byte{
+		0x10, 0x00, 0x00, 0x18, 0x00, 0x00, 0x00, 0x10,
+		0xca, 0xb0, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0xe4, 0xac, // e5d6 en passant
+		0x00, 0xc8, // score 100
+		0x00, 0x02, // ply 2, result 0
+		0x00, 0x00,
+		0x00, 0x01,
+		// e8d8, score -90.
+		0x74, 0x08,
+	}
+)
+
+func TestBinpackReader(t *testing.T) {
+	data := append(chunk(startChain, epChain), chunk(startChain[:stemSize-2], []byte{0, 0})...)
+
+	want := []struct {
+		fen                string
+		move               string
+		score, ply, result int
+	}{
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", "e2e4", 0, 0, 1},
+		{"rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 1", "e7e5", 0, 1, -1},
+		{"rnbqkbnr/pppp1ppp/8/4p3/4P3/8/PPPP1PPP/RNBQKBNR w KQkq e6 0 2", "g1f3", 30, 2, 1},
+		{"4k3/8/8/3pP3/8/8/8/4K3 w - d6 0 2", "e5d6", 100, 2, 0},
+		{"4k3/8/3P4/8/8/8/8/4K3 b - - 0 2", "e8d8", -90, 3, 0},
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", "e2e4", 0, 0, 1},
+	}
+
+	r := NewBinpackReader(bytes.NewReader(data))
+	for i, w := range want {
+		e, err := r.Read()
+		if err != nil {
+			t.Fatalf("entry %d: %v", i, err)
+		}
+		if got := fen.Encode(e.Position); got != w.fen {
+			t.Errorf("entry %d: want position %s, got %s", i, w.fen, got)
+		}
+		if e.Move.String() != w.move || e.Score != w.score || e.Ply != w.ply || e.Result != w.result {
+			t.Errorf("entry %d: want %s %d %d %d, got %v %d %d %d",
+				i, w.move, w.score, w.ply, w.result, e.Move, e.Score, e.Ply, e.Result)
+		}
+	}
+	if _, err := r.Read(); err != io.EOF {
+		t.Errorf("want io.EOF, got %v", err)
+	}
+}
+
+func TestBinpackReader_Invalid(t *testing.T) {
+	for name, data := range map[string][]byte{
+		"bad magic":       append([]byte("PNIB"), chunk(epChain)[4:]...),
+		"truncated chunk": chunk(epChain)[:20],
+		"truncated chain": chunk(epChain[:30]),
+		"short bitstream": chunk(startChain[:len(startChain)-1]),
+	} {
+		r := NewBinpackReader(bytes.NewReader(data))
+		var err error
+		for err == nil {
+			_, err = r.Read()
+		}
+		if err == io.EOF {
+			t.Errorf("%s: no error", name)
+		}
+	}
+}
+
+func TestBinpackReader_HugeChunkSize(t *testing.T) {
+	data := []byte("BINP\xff\xff\xff\xff")
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	_, err := NewBinpackReader(bytes.NewReader(data)).Read()
+	runtime.ReadMemStats(&after)
+	if err == nil || err == io.EOF {
+		t.Errorf("want a truncation error, got %v", err)
+	}
+	if n := after.TotalAlloc - before.TotalAlloc; n > 1<<20 {
+		t.Errorf("allocated %d bytes for an empty chunk", n)
+	}
+}
+
+func TestUnsignedToSigned(t *testing.T) {
+	for u, want := range map[uint16]int16{0: 0, 1: -1, 2: 1, 200: 100, 199: -100, 0xffff: -32768} {
+		if got := unsignedToSigned(u); got != want {
+			t.Errorf("unsignedToSigned(%d): want %d, got %d", u, want, got)
+		}
+	}
+}
@@ -0,0 +1,161 @@
+// Package plain implements reading and writing Stockfish "plain" training
+// data, and reading the compressed .binpack format with [BinpackReader].
+//
+// A plain file is a sequence of entries, each made of these lines:
+//
+//	fen <FEN>
+//	move <move in UCI notation>
+//	score <score>
+//	ply <ply>
+//	result <-1, 0 or 1>
+//	e
+//
+// Scores and results are from the perspective of the side to move.
+package plain
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/clfs/lento/core"
+	"github.com/clfs/lento/encoding/binpos"
+	"github.com/clfs/lento/encoding/fen"
+)
+
+// An Entry is a single training position.
+type Entry struct {
+	Position core.Position
+	// Move is the move played from the position.
+	Move core.Move
+	// Score is the search score in centipawns, from the perspective of the
+	// side to move.
+	Score int
+	// Ply is the number of halfmoves played in the game so far.
+	Ply int
+	// Result is the game result from the perspective of the side to move:
+	// 1 for a win, 0 for a draw and -1 for a loss.
+	Result int
+}
+
+// Record converts e to a [binpos.Record], dropping the move and ply.
+// Scores outside the range of an int16 are clamped.
+func (e Entry) Record() binpos.Record {
+	r := binpos.Record{
+		Position: e.Position,
+		Score:    int16(min(max(e.Score, -32768), 32767)),
+		Result:   binpos.Draw,
+	}
+
+	whiteResult := e.Result
+	if e.Position.SideToMove() == core.Black {
+		whiteResult = -whiteResult
+	}
+	switch {
+	case whiteResult > 0:
+		r.Result = binpos.WhiteWin
+	case whiteResult < 0:
+		r.Result = binpos.BlackWin
+	}
+
+	return r
+}
+
+// A Reader reads entries from plain text.
+type Reader struct {
+	s    *bufio.Scanner
+	line int
+}
+
+// NewReader returns a new reader that reads from r.
+func NewReader(r io.Reader) *Reader {
+	return &Reader{s: bufio.NewScanner(r)}
+}
+
+// Read reads the next entry. At the end of the input, Read returns [io.EOF].
+func (r *Reader) Read() (Entry, error) {
+	var (
+		e    Entry
+		seen = make(map[string]bool)
+	)
+
+	for r.s.Scan() {
+		r.line++
+		line := strings.TrimSpace(r.s.Text())
+		if line == "" {
+			continue
+		}
+
+		key, value, _ := strings.Cut(line, " ")
+		if key != "e" && seen[key] {
+			return Entry{}, r.errorf("duplicate key: %q", key)
+		}
+		seen[key] = true
+
+		var err error
+		switch key {
+		case "fen":
+			e.Position, err = fen.Decode(value)
+		case "move":
+			e.Move, err = core.ParseMove(value)
+		case "score":
+			e.Score, err = strconv.Atoi(value)
+		case "ply":
+			e.Ply, err = strconv.Atoi(value)
+		case "result":
+			e.Result, err = strconv.Atoi(value)
+			if err == nil && (e.Result < -1 || e.Result > 1) {
+				err = fmt.Errorf("bad result: %q", value)
+			}
+		case "e":
+			for _, k := range []string{"fen", "move", "score", "ply", "result"} {
+				if !seen[k] {
+					return Entry{}, r.errorf("missing key: %q", k)
+				}
+			}
+			return e, nil
+		default:
+			return Entry{}, r.errorf("unknown key: %q", key)
+		}
+		if err != nil {
+			return Entry{}, r.errorf("%v", err)
+		}
+	}
+
+	if err := r.s.Err(); err != nil {
+		return Entry{}, err
+	}
+	if len(seen) != 0 {
+		return Entry{}, io.ErrUnexpectedEOF
+	}
+	return Entry{}, io.EOF
+}
+
+func (r *Reader) errorf(format string, args ...any) error {
+	return fmt.Errorf("line %d: %s", r.line, fmt.Sprintf(format, args...))
+}
+
+// A Writer writes entries as plain text.
+type Writer struct {
+	w *bufio.Writer
+}
+
+// NewWriter returns a new writer that writes to w.
+// Call [Writer.Flush] when done writing.
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: bufio.NewWriter(w)}
+}
+
+// Write writes an entry.
+func (w *Writer) Write(e Entry) error {
+	_, err := fmt.Fprintf(w.w, "fen %s\nmove %s\nscore %d\nply %d\nresult %d\ne\n",
+		fen.Encode(e.Position), e.Move, e.Score, e.Ply, e.Result)
+	return err
+}
+
+// Flush writes any buffered data to the underlying writer.
+func (w *Writer) Flush() error {
+	return w.w.Flush()
+}
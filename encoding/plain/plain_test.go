@@ -0,0 +1,112 @@
+package plain
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/clfs/lento/encoding/binpos"
+	"github.com/clfs/lento/encoding/fen"
+)
+
+const sample = `fen rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1
+move e2e4
+score 31
+ply 0
+result 0
+e
+fen rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 1
+move c7c5
+score -28
+ply 1
+result 0
+e
+fen 8/1P6/8/8/8/2k5/8/4K3 w - - 0 61
+move b7b8q
+score 1200
+ply 120
+result 1
+e
+`
+
+func readAll(t *testing.T, s string) []Entry {
+	t.Helper()
+
+	var res []Entry
+	r := NewReader(strings.NewReader(s))
+	for {
+		e, err := r.Read()
+		if err == io.EOF {
+			return res
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		res = append(res, e)
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	entries := readAll(t, sample)
+	if n := len(entries); n != 3 {
+		t.Fatalf("want 3 entries, got %d", n)
+	}
+
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	for _, e := range entries {
+		if err := w.Write(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := buf.String(); got != sample {
+		t.Errorf("changed in round trip:\n%s", got)
+	}
+}
+
+func TestRead_Invalid(t *testing.T) {
+	cases := map[string]string{
+		"missing key":   "fen 8/8/8/8/8/8/8/K6k w - - 0 1\nmove a1a2\nscore 0\nply 0\ne\n",
+		"duplicate key": "fen 8/8/8/8/8/8/8/K6k w - - 0 1\nmove a1a2\nmove a1b1\nscore 0\nply 0\nresult 0\ne\n",
+		"unknown key":   "fen 8/8/8/8/8/8/8/K6k w - - 0 1\ndepth 9\n",
+		"bad fen":       "fen 8/8/8 w - - 0 1\n",
+		"bad move":      "fen 8/8/8/8/8/8/8/K6k w - - 0 1\nmove a1\n",
+		"bad result":    "fen 8/8/8/8/8/8/8/K6k w - - 0 1\nmove a1a2\nscore 0\nply 0\nresult 2\ne\n",
+		"truncated":     "fen 8/8/8/8/8/8/8/K6k w - - 0 1\nmove a1a2\n",
+	}
+	for name, s := range cases {
+		r := NewReader(strings.NewReader(s))
+		if _, err := r.Read(); err == nil || err == io.EOF {
+			t.Errorf("%s: want error, got %v", name, err)
+		}
+	}
+}
+
+func TestEntry_Record(t *testing.T) {
+	cases := []struct {
+		fen    string
+		result int
+		want   binpos.Result
+	}{
+		{fen.Starting, 1, binpos.WhiteWin},
+		{fen.Starting, -1, binpos.BlackWin},
+		{fen.Starting, 0, binpos.Draw},
+		{"rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 1", 1, binpos.BlackWin},
+		{"rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 1", -1, binpos.WhiteWin},
+	}
+	for _, c := range cases {
+		e := Entry{Position: fen.MustDecode(c.fen), Score: 40000, Result: c.result}
+		r := e.Record()
+		if r.Result != c.want {
+			t.Errorf("%s, %d: want %d, got %d", c.fen, c.result, c.want, r.Result)
+		}
+		if r.Score != 32767 {
+			t.Errorf("%s: want clamped score, got %d", c.fen, r.Score)
+		}
+	}
+}
@@ -0,0 +1,235 @@
+// Package san implements encoding and decoding Standard Algebraic Notation.
+//
+// This package follows "Standard: Portable Game Notation Specification and
+// Implementation Guide", revision 1994.03.12, §8.2.3. Decoding is lenient:
+// it accepts zeros for castling, omitted or superfluous disambiguation, an
+// omitted "=" before promotion pieces, and trailing check, mate and
+// annotation symbols, none of which are validated.
+//
+// Drops, as in Crazyhouse, are written like "N@f3" or "P@e4". Decoding also
+// accepts "@e4" for pawn drops.
+package san
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/clfs/lento/core"
+)
+
+var pieceLetters = map[core.PieceType]string{
+	core.Knight: "N",
+	core.Bishop: "B",
+	core.Rook:   "R",
+	core.Queen:  "Q",
+	core.King:   "K",
+}
+
+// Encode encodes a legal move to SAN.
+func Encode(p core.Position, m core.Move) string {
+	var sb strings.Builder
+
+	b := p.Board()
+	from, to := m.From(), m.To()
+	held, _ := b.Get(from)
+	pt := held.Type()
+
+	isCapture := b.IsOccupied(to) || (pt == core.Pawn && from.File() != to.File())
+
+	dropped, isDrop := m.Drop()
+
+	switch {
+	case isDrop:
+		if dropped == core.Pawn {
+			sb.WriteByte('P')
+		} else {
+			sb.WriteString(pieceLetters[dropped])
+		}
+		sb.WriteByte('@')
+		sb.WriteString(to.String())
+	case pt == core.King && int(to)-int(from) == 2:
+		sb.WriteString("O-O")
+	case pt == core.King && int(from)-int(to) == 2:
+		sb.WriteString("O-O-O")
+	case pt == core.Pawn:
+		if isCapture {
+			sb.WriteByte('a' + byte(from.File()))
+			sb.WriteByte('x')
+		}
+		sb.WriteString(to.String())
+		if become, ok := m.Promotion(); ok {
+			sb.WriteByte('=')
+			sb.WriteString(pieceLetters[become])
+		}
+	default:
+		sb.WriteString(pieceLetters[pt])
+		sb.WriteString(disambiguate(p, m, held))
+		if isCapture {
+			sb.WriteByte('x')
+		}
+		sb.WriteString(to.String())
+	}
+
+	p.Move(m)
+	if p.InCheck() {
+		if len(p.LegalMoves()) == 0 {
+			sb.WriteByte('#')
+		} else {
+			sb.WriteByte('+')
+		}
+	}
+
+	return sb.String()
+}
+
+// disambiguate returns the file, rank or square of m's origin, whichever is
+// the first to uniquely identify m among the legal moves of identical pieces
+// to the same square.
+func disambiguate(p core.Position, m core.Move, held core.Piece) string {
+	b := p.Board()
+
+	var sameFile, sameRank, ambiguous bool
+	for _, other := range p.LegalMoves() {
+		if other.To() != m.To() || other.From() == m.From() {
+			continue
+		}
+		if piece, _ := b.Get(other.From()); piece != held {
+			continue
+		}
+		ambiguous = true
+		sameFile = sameFile || other.From().File() == m.From().File()
+		sameRank = sameRank || other.From().Rank() == m.From().Rank()
+	}
+
+	from := m.From().String()
+	switch {
+	case !ambiguous:
+		return ""
+	case !sameFile:
+		return from[:1]
+	case !sameRank:
+		return from[1:]
+	default:
+		return from
+	}
+}
+
+var (
+	sanRegexp  = regexp.MustCompile(`^([NBRQK])?([a-h])?([1-8])?x?([a-h][1-8])(?:=?([NBRQK]))?$`)
+	dropRegexp = regexp.MustCompile(`^([PNBRQ])?@([a-h][1-8])$`)
+)
+
+// Decode decodes a move from SAN, returning an error if the move is illegal
+// or ambiguous.
+func Decode(p core.Position, s string) (core.Move, error) {
+	trimmed := strings.TrimRight(s, "+#!?")
+
+	switch trimmed {
+	case "O-O", "0-0":
+		return decodeCastling(p, s, core.FileG)
+	case "O-O-O", "0-0-0":
+		return decodeCastling(p, s, core.FileC)
+	}
+
+	if dm := dropRegexp.FindStringSubmatch(trimmed); dm != nil {
+		return decodeDrop(p, s, dm[1], dm[2])
+	}
+
+	sm := sanRegexp.FindStringSubmatch(trimmed)
+	if sm == nil {
+		return core.Move{}, fmt.Errorf("bad move: %q", s)
+	}
+
+	pt := core.Pawn
+	for t, letter := range pieceLetters {
+		if sm[1] == letter {
+			pt = t
+		}
+	}
+
+	to, err := core.ParseSquare(sm[4])
+	if err != nil {
+		return core.Move{}, err
+	}
+
+	var (
+		promotion    core.PieceType
+		hasPromotion = sm[5] != ""
+	)
+	for t, letter := range pieceLetters {
+		if sm[5] == letter {
+			promotion = t
+		}
+	}
+
+	b := p.Board()
+
+	var (
+		res   core.Move
+		found int
+	)
+	for _, m := range p.LegalMoves() {
+		from := m.From()
+		if m.To() != to {
+			continue
+		}
+		if piece, _ := b.Get(from); piece.Type() != pt {
+			continue
+		}
+		if sm[2] != "" && from.File() != core.File(sm[2][0]-'a') {
+			continue
+		}
+		if sm[3] != "" && from.Rank() != core.Rank(sm[3][0]-'1') {
+			continue
+		}
+		if become, ok := m.Promotion(); ok != hasPromotion || become != promotion {
+			continue
+		}
+		res = m
+		found++
+	}
+
+	switch found {
+	case 0:
+		return core.Move{}, fmt.Errorf("illegal move: %q", s)
+	case 1:
+		return res, nil
+	default:
+		return core.Move{}, fmt.Errorf("ambiguous move: %q", s)
+	}
+}
+
+func decodeDrop(p core.Position, s, letter, square string) (core.Move, error) {
+	pt := core.Pawn
+	for t, l := range pieceLetters {
+		if letter == l {
+			pt = t
+		}
+	}
+	to, err := core.ParseSquare(square)
+	if err != nil {
+		return core.Move{}, err
+	}
+	m := core.NewDropMove(pt, to)
+	if !p.IsLegal(m) {
+		return core.Move{}, fmt.Errorf("illegal move: %q", s)
+	}
+	return m, nil
+}
+
+func decodeCastling(p core.Position, s string, kingFile core.File) (core.Move, error) {
+	r := core.Rank1
+	if p.SideToMove() == core.Black {
+		r = core.Rank8
+	}
+
+	m := core.NewMove(core.NewSquare(core.FileE, r), core.NewSquare(kingFile, r))
+
+	b := p.Board()
+	if piece, _ := b.Get(m.From()); piece.Type() != core.King || !p.IsLegal(m) {
+		return core.Move{}, fmt.Errorf("illegal move: %q", s)
+	}
+
+	return m, nil
+}
@@ -0,0 +1,173 @@
+package san
+
+import (
+	"testing"
+
+	"github.com/clfs/lento/core"
+	"github.com/clfs/lento/encoding/fen"
+)
+
+func TestEncode(t *testing.T) {
+	cases := []struct {
+		fen  string
+		move string // UCI
+		want string
+	}{
+		{fen.Starting, "e2e4", "e4"},
+		{fen.Starting, "g1f3", "Nf3"},
+		{"r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1", "e1g1", "O-O"},
+		{"r3k2r/8/8/8/8/8/8/R3K2R b KQkq - 0 1", "e8c8", "O-O-O"},
+		{"r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1", "a1a8", "Rxa8+"},
+		{"rnbqkbnr/ppp1pppp/8/3pP3/8/8/PPPP1PPP/RNBQKBNR w KQkq d6 0 2", "e5d6", "exd6"},
+		{"8/1P6/8/8/8/8/8/k1K5 w - - 0 1", "b7b8q", "b8=Q"},
+		{"8/1P6/8/8/8/8/8/k1K5 w - - 0 1", "b7b8n", "b8=N"},
+		{"7k/8/8/8/8/8/8/R3R1K1 w - - 0 1", "a1d1", "Rad1"},
+		{"7k/8/8/8/R7/8/8/R5K1 w - - 0 1", "a1a2", "R1a2"},
+		{"8/7k/8/Q3Q3/8/8/8/Q5K1 w - - 0 1", "a5e1", "Qa5e1"},
+		{"6k1/5ppp/8/8/8/8/8/R5K1 w - - 0 1", "a1a8", "Ra8#"},
+	}
+	for _, c := range cases {
+		p := fen.MustDecode(c.fen)
+		m, err := core.ParseMove(c.move)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := Encode(p, m); c.want != got {
+			t.Errorf("%s, %s: want %q, got %q", c.fen, c.move, c.want, got)
+		}
+	}
+}
+
+func TestDecode(t *testing.T) {
+	cases := []struct {
+		fen  string
+		san  string
+		want string // UCI
+	}{
+		{fen.Starting, "e4", "e2e4"},
+		{fen.Starting, "Nf3!", "g1f3"},
+		{fen.Starting, "Ng1f3", "g1f3"},
+		{"r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1", "0-0", "e1g1"},
+		{"r3k2r/8/8/8/8/8/8/R3K2R b KQkq - 0 1", "O-O-O", "e8c8"},
+		{"8/1P6/8/8/8/8/8/k1K5 w - - 0 1", "b8Q", "b7b8q"},
+		{"8/1P6/8/8/8/8/8/k1K5 w - - 0 1", "b8=R", "b7b8r"},
+		{"7k/8/8/8/8/8/8/R3R1K1 w - - 0 1", "Rad1", "a1d1"},
+		{"6k1/5ppp/8/8/8/8/8/R5K1 w - - 0 1", "Ra8#", "a1a8"},
+	}
+	for _, c := range cases {
+		p := fen.MustDecode(c.fen)
+		got, err := Decode(p, c.san)
+		if err != nil {
+			t.Errorf("%s, %s: error: %v", c.fen, c.san, err)
+			continue
+		}
+		if got.String() != c.want {
+			t.Errorf("%s, %s: want %s, got %s", c.fen, c.san, c.want, got)
+		}
+	}
+}
+
+func TestDecode_Invalid(t *testing.T) {
+	cases := []struct {
+		fen string
+		san string
+	}{
+		{fen.Starting, ""},
+		{fen.Starting, "e5"},
+		{fen.Starting, "O-O"},
+		{fen.Starting, "Nd2"},
+		{"7k/8/8/8/8/8/8/R3R1K1 w - - 0 1", "Rd1"}, // ambiguous
+		{"8/1P6/8/8/8/8/8/k1K5 w - - 0 1", "b8"},   // missing promotion
+		{"8/1P6/8/8/8/8/8/k1K5 w - - 0 1", "b8=K"},
+	}
+	for _, c := range cases {
+		if _, err := Decode(fen.MustDecode(c.fen), c.san); err == nil {
+			t.Errorf("%s, %q: no error", c.fen, c.san)
+		}
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	for _, s := range []string{
+		fen.Starting,
+		"r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1",
+		"r3k2r/Pppp1ppp/1b3nbN/nP6/BBP1P3/q4N2/Pp1P2PP/R2Q1RK1 w kq - 0 1",
+		"rnbq1k1r/pp1Pbppp/2p5/8/2B5/8/PPP1NnPP/RNBQK2R w KQ - 1 8",
+	} {
+		p := fen.MustDecode(s)
+		for _, m := range p.LegalMoves() {
+			enc := Encode(p, m)
+			got, err := Decode(p, enc)
+			if err != nil {
+				t.Errorf("%s, %s: error: %v", s, enc, err)
+				continue
+			}
+			if m != got {
+				t.Errorf("%s, %s: want %s, got %s", s, enc, m, got)
+			}
+		}
+	}
+}
+
+func TestDrops(t *testing.T) {
+	p, err := fen.DecodeVariant("k7/8/1K6/8/8/8/8/8[QPp] w - - 0 1", core.Crazyhouse)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	encodes := map[string]string{
+		"Q@b7": "Q@b7#",
+		"Q@e4": "Q@e4+",
+		"P@e4": "P@e4",
+	}
+	for uci, want := range encodes {
+		m, err := core.ParseMove(uci)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := Encode(p, m); want != got {
+			t.Errorf("%s: want %q, got %q", uci, want, got)
+		}
+	}
+
+	decodes := map[string]string{
+		"Q@b7#": "Q@b7",
+		"P@e4":  "P@e4",
+		"@e4":   "P@e4",
+	}
+	for s, want := range decodes {
+		got, err := Decode(p, s)
+		if err != nil {
+			t.Errorf("%q: error: %v", s, err)
+			continue
+		}
+		if got.String() != want {
+			t.Errorf("%q: want %s, got %s", s, want, got)
+		}
+	}
+
+	for _, s := range []string{"N@e4", "P@e8", "Q@a8", "@a1"} {
+		if _, err := Decode(p, s); err == nil {
+			t.Errorf("%q: no error", s)
+		}
+	}
+}
+
+func TestKingPromotion(t *testing.T) {
+	p, err := fen.DecodeVariant("8/P7/8/8/8/8/8/7k w - - 0 1", core.Antichess)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := core.NewPromotionMove(core.A7, core.A8, core.King)
+	if got := Encode(p, m); got != "a8=K" {
+		t.Errorf("want %q, got %q", "a8=K", got)
+	}
+	if got, err := Decode(p, "a8=K"); err != nil || got != m {
+		t.Errorf("want %v, got %v (%v)", m, got, err)
+	}
+
+	// Kings are only promoted to in Antichess.
+	if _, err := Decode(fen.MustDecode("8/P7/8/8/8/8/8/K6k w - - 0 1"), "a8=K"); err == nil {
+		t.Error("standard chess: no error")
+	}
+}
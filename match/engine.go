@@ -0,0 +1,140 @@
+package match
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/clfs/lento/core"
+	"github.com/clfs/lento/uci"
+	"github.com/clfs/lento/uci/client"
+)
+
+// An EngineConfig describes how to start a UCI engine.
+type EngineConfig struct {
+	// Name identifies the engine in results. If empty, the name the engine
+	// reports is used.
+	Name string
+	// Path is the engine executable.
+	Path string
+	// Args are passed to the engine executable.
+	Args []string
+	// Options are set with "setoption" after the handshake.
+	Options map[string]string
+}
+
+// errTimeout is returned when an engine doesn't respond in time.
+var errTimeout = errors.New("engine timed out")
+
+// handshakeTimeout bounds how long engines may take to respond to commands
+// outside of searches.
+const handshakeTimeout = 10 * time.Second
+
+// An engine is a running UCI engine process.
+type engine struct {
+	name string
+	*client.Client
+}
+
+// startEngine starts an engine, completes the UCI handshake and sets its
+// options.
+func startEngine(ctx context.Context, cfg EngineConfig) (*engine, error) {
+	ctx, cancel := context.WithTimeout(ctx, handshakeTimeout)
+	defer cancel()
+
+	c, err := client.Start(ctx, cfg.Path, cfg.Args...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", cfg.Path, err)
+	}
+
+	e := &engine{name: cfg.Name, Client: c}
+	if e.name == "" {
+		e.name = c.Name()
+	}
+
+	for name, value := range cfg.Options {
+		if err := c.SetOption(name, value); err != nil {
+			c.Close()
+			return nil, fmt.Errorf("%s: %v", cfg.Path, err)
+		}
+	}
+	if err := c.IsReady(ctx); err != nil {
+		c.Close()
+		return nil, fmt.Errorf("%s: %v", cfg.Path, err)
+	}
+
+	return e, nil
+}
+
+// newGame tells the engine that the next search is from a different game.
+func (e *engine) newGame() error {
+	ctx, cancel := context.WithTimeout(context.Background(), handshakeTimeout)
+	defer cancel()
+	return e.NewGame(ctx)
+}
+
+// A clock stores both players' remaining time and increments.
+type clock struct {
+	wtime, btime time.Duration
+	winc, binc   time.Duration
+}
+
+// remaining returns c's remaining time.
+func (cl *clock) remaining(c core.Color) *time.Duration {
+	if c == core.White {
+		return &cl.wtime
+	}
+	return &cl.btime
+}
+
+// increment returns c's increment.
+func (cl *clock) increment(c core.Color) time.Duration {
+	if c == core.White {
+		return cl.winc
+	}
+	return cl.binc
+}
+
+// bestMove asks the engine for a move in the position after playing moves
+// from start, with us to move. It returns errTimeout if the engine exceeds
+// its remaining time by more than margin, after which the engine must be
+// closed. If ctx is canceled, the search is stopped and ctx.Err() returned.
+func (e *engine) bestMove(ctx context.Context, start core.Position, moves []core.Move, cl clock, us core.Color, margin time.Duration) (core.Move, time.Duration, error) {
+	if err := e.SetPosition(start, moves); err != nil {
+		return core.Move{}, 0, err
+	}
+
+	searchStart := time.Now()
+	s, err := e.Go(ctx, uci.Limits{
+		WTime: cl.wtime,
+		BTime: cl.btime,
+		WInc:  cl.winc,
+		BInc:  cl.binc,
+	})
+	if err != nil {
+		return core.Move{}, 0, err
+	}
+
+	type result struct {
+		res uci.Result
+		err error
+	}
+	done := make(chan result, 1)
+	go func() {
+		res, err := s.Wait()
+		done <- result{res, err}
+	}()
+
+	timer := time.NewTimer(*cl.remaining(us) + margin)
+	defer timer.Stop()
+
+	select {
+	case r := <-done:
+		return r.res.BestMove, time.Since(searchStart), r.err
+	case <-timer.C:
+		return core.Move{}, time.Since(searchStart), errTimeout
+	case <-ctx.Done():
+		return core.Move{}, time.Since(searchStart), ctx.Err()
+	}
+}
@@ -0,0 +1,358 @@
+// Package match plays matches between UCI engines.
+//
+// Each opening is played twice, once with each engine as White. Games are
+// adjudicated with [core.Game], and engines that make illegal moves, crash or
+// run out of time lose.
+package match
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/clfs/lento/core"
+	"github.com/clfs/lento/encoding/fen"
+	"github.com/clfs/lento/encoding/pgn"
+)
+
+// A TimeControl is a base time plus an increment per move.
+type TimeControl struct {
+	Base      time.Duration
+	Increment time.Duration
+}
+
+// ParseTimeControl parses a time control in seconds, like "10+0.1" or "60".
+func ParseTimeControl(s string) (TimeControl, error) {
+	base, inc, found := strings.Cut(s, "+")
+	if !found {
+		inc = "0"
+	}
+
+	b, err := strconv.ParseFloat(base, 64)
+	if err != nil || b <= 0 {
+		return TimeControl{}, fmt.Errorf("bad time control: %q", s)
+	}
+	i, err := strconv.ParseFloat(inc, 64)
+	if err != nil || i < 0 {
+		return TimeControl{}, fmt.Errorf("bad time control: %q", s)
+	}
+
+	return TimeControl{
+		Base:      time.Duration(b * float64(time.Second)),
+		Increment: time.Duration(i * float64(time.Second)),
+	}, nil
+}
+
+// String returns the time control in PGN TimeControl tag format, like "10+0.1".
+func (tc TimeControl) String() string {
+	s := strconv.FormatFloat(tc.Base.Seconds(), 'f', -1, 64)
+	if tc.Increment > 0 {
+		s += "+" + strconv.FormatFloat(tc.Increment.Seconds(), 'f', -1, 64)
+	}
+	return s
+}
+
+// A Config configures a match.
+type Config struct {
+	// Engines are the two engines to play. Results are reported from the
+	// first engine's perspective.
+	Engines [2]EngineConfig
+	// Openings are the starting positions, each played with both colors. If
+	// empty, the standard starting position is used.
+	Openings []core.Position
+	// Games is the number of games to play, rounded up to an even number.
+	Games int
+	// TimeControl is the time control for both engines.
+	TimeControl TimeControl
+	// TimeMargin is how far an engine may go over its remaining time on a
+	// move without forfeiting, to allow for process and pipe latency. An
+	// engine that uses it is left with no time, plus its increment.
+	TimeMargin time.Duration
+	// Concurrency is the number of games to play at once. Each concurrent
+	// game runs its own pair of engine processes.
+	Concurrency int
+	// SPRT, if not nil, stops the match early once it reaches a verdict.
+	SPRT *SPRT
+	// Event names the match in PGN output.
+	Event string
+}
+
+// A Game is a finished game.
+type Game struct {
+	// Round is the game's number, starting from 1.
+	Round int
+	// PGN is the game with its tags. The White and Black tags name the
+	// engines.
+	PGN pgn.Game
+	// Reason describes why the game ended, like "checkmate" or "time forfeit".
+	Reason string
+	// FirstIsWhite is true if the first engine played White.
+	FirstIsWhite bool
+}
+
+// Run plays a match, calling report after each game in the order the games
+// finish. It stops after all games are played, the SPRT reaches a verdict, or
+// ctx is canceled.
+func Run(ctx context.Context, cfg Config, report func(Game, Stats)) (Stats, error) {
+	if cfg.Games <= 0 {
+		return Stats{}, errors.New("no games to play")
+	}
+
+	openings := cfg.Openings
+	if len(openings) == 0 {
+		openings = []core.Position{core.NewPosition()}
+	}
+
+	parent := ctx
+	ctx, cancel := context.WithCancel(parent)
+	defer cancel()
+
+	type job struct {
+		round        int
+		opening      core.Position
+		firstIsWhite bool
+	}
+
+	jobs := make(chan job)
+	go func() {
+		defer close(jobs)
+		for i := 0; i < (cfg.Games+1)/2*2; i++ {
+			j := job{
+				round:        i + 1,
+				opening:      openings[i/2%len(openings)],
+				firstIsWhite: i%2 == 0,
+			}
+			select {
+			case jobs <- j:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	type result struct {
+		game Game
+		err  error
+	}
+
+	results := make(chan result)
+	var wg sync.WaitGroup
+	for i := 0; i < max(cfg.Concurrency, 1); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w := worker{cfg: cfg}
+			defer w.close()
+			for j := range jobs {
+				g, err := w.play(ctx, j.round, j.opening, j.firstIsWhite)
+				select {
+				case results <- result{g, err}:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	var (
+		stats Stats
+		err   error
+	)
+	for r := range results {
+		if r.err != nil {
+			if err == nil && ctx.Err() == nil {
+				err = r.err
+			}
+			cancel()
+			continue
+		}
+
+		switch r.game.PGN.Result {
+		case core.WhiteWins:
+			if r.game.FirstIsWhite {
+				stats.Wins++
+			} else {
+				stats.Losses++
+			}
+		case core.BlackWins:
+			if r.game.FirstIsWhite {
+				stats.Losses++
+			} else {
+				stats.Wins++
+			}
+		default:
+			stats.Draws++
+		}
+
+		if report != nil {
+			report(r.game, stats)
+		}
+
+		if cfg.SPRT != nil && cfg.SPRT.Verdict(stats) != Continue {
+			cancel()
+		}
+	}
+
+	if err != nil {
+		return stats, err
+	}
+	return stats, parent.Err()
+}
+
+// A worker plays games with its own pair of engines, restarting them as
+// needed.
+type worker struct {
+	cfg     Config
+	engines [2]*engine
+}
+
+func (w *worker) close() {
+	for i, e := range w.engines {
+		if e != nil {
+			e.Close()
+			w.engines[i] = nil
+		}
+	}
+}
+
+// play plays one game. Engine failures lose the game rather than returning
+// an error; errors are only returned if an engine can't be started.
+func (w *worker) play(ctx context.Context, round int, opening core.Position, firstIsWhite bool) (Game, error) {
+	for i, e := range w.engines {
+		if e != nil {
+			if err := e.newGame(); err == nil {
+				continue
+			}
+			e.Close()
+		}
+		started, err := startEngine(ctx, w.cfg.Engines[i])
+		if err != nil {
+			return Game{}, err
+		}
+		w.engines[i] = started
+	}
+
+	white, black := w.engines[0], w.engines[1]
+	if !firstIsWhite {
+		white, black = black, white
+	}
+
+	tc := w.cfg.TimeControl
+	cl := clock{
+		wtime: tc.Base,
+		btime: tc.Base,
+		winc:  tc.Increment,
+		binc:  tc.Increment,
+	}
+
+	var (
+		g           = core.NewGame(opening)
+		result      core.Result
+		reason      string
+		termination = "normal" // PGN Termination tag
+	)
+
+	// loses ends the game with a loss for c.
+	loses := func(c core.Color, why, term string) {
+		result, reason, termination = core.WhiteWins, why, term
+		if c == core.White {
+			result = core.BlackWins
+		}
+	}
+
+	for result == core.NoResult {
+		if r, term := g.Result(); r != core.NoResult {
+			result, reason = r, term.String()
+			break
+		}
+
+		p := g.Position()
+		us := p.SideToMove()
+		e := white
+		if us == core.Black {
+			e = black
+		}
+
+		m, elapsed, err := e.bestMove(ctx, opening, g.Moves(), cl, us, w.cfg.TimeMargin)
+		if ctx.Err() != nil {
+			return Game{}, ctx.Err()
+		}
+		switch {
+		case errors.Is(err, errTimeout):
+			loses(us, "time forfeit", "time forfeit")
+			w.discard(e)
+			continue
+		case err != nil:
+			loses(us, fmt.Sprintf("engine error: %v", err), "rules infraction")
+			w.discard(e)
+			continue
+		}
+
+		remaining := cl.remaining(us)
+		*remaining -= elapsed
+		if *remaining < -w.cfg.TimeMargin {
+			loses(us, "time forfeit", "time forfeit")
+			continue
+		}
+		*remaining = max(*remaining, 0) + cl.increment(us)
+
+		if err := g.Move(m); err != nil {
+			loses(us, fmt.Sprintf("illegal move %v", m), "rules infraction")
+		}
+	}
+
+	event := w.cfg.Event
+	if event == "" {
+		event = "?"
+	}
+
+	tags := []pgn.Tag{
+		{Name: "Event", Value: event},
+		{Name: "Site", Value: "?"},
+		{Name: "Date", Value: time.Now().Format("2006.01.02")},
+		{Name: "Round", Value: strconv.Itoa(round)},
+		{Name: "White", Value: white.name},
+		{Name: "Black", Value: black.name},
+		{Name: "Result", Value: result.String()},
+	}
+	if opening != core.NewPosition() {
+		tags = append(tags,
+			pgn.Tag{Name: "SetUp", Value: "1"},
+			pgn.Tag{Name: "FEN", Value: fen.Encode(opening)},
+		)
+	}
+	tags = append(tags,
+		pgn.Tag{Name: "TimeControl", Value: tc.String()},
+		pgn.Tag{Name: "Termination", Value: termination},
+	)
+
+	return Game{
+		Round: round,
+		PGN: pgn.Game{
+			Tags:     tags,
+			Position: opening,
+			Moves:    g.Moves(),
+			Result:   result,
+		},
+		Reason:       reason,
+		FirstIsWhite: firstIsWhite,
+	}, nil
+}
+
+// discard closes a misbehaving engine so it is restarted for the next game.
+func (w *worker) discard(e *engine) {
+	for i := range w.engines {
+		if w.engines[i] == e {
+			e.Close()
+			w.engines[i] = nil
+		}
+	}
+}
@@ -0,0 +1,252 @@
+package match
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/clfs/lento/core"
+	"github.com/clfs/lento/encoding/fen"
+)
+
+// If LENTO_FAKE_ENGINE is set, the test binary acts as a UCI engine instead,
+// with its behavior chosen by its first argument.
+func TestMain(m *testing.M) {
+	if os.Getenv("LENTO_FAKE_ENGINE") != "" {
+		fakeEngine(os.Args[1])
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// fakeEngine speaks just enough UCI to play games. Modes:
+//
+//   - "first" plays the first legal move.
+//   - "greedy" mates if it can, otherwise captures if it can.
+//   - "illegal" plays an illegal move.
+//   - "slow" never replies to "go".
+//   - "late" plays like "first", but takes 400ms over its first move.
+func fakeEngine(mode string) {
+	var p core.Position
+	s := bufio.NewScanner(os.Stdin)
+	for s.Scan() {
+		fields := strings.Fields(s.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		switch fields[0] {
+		case "uci":
+			fmt.Printf("id name %s\nuciok\n", mode)
+		case "isready":
+			fmt.Println("readyok")
+		case "position":
+			p = fakePosition(fields[1:])
+		case "go":
+			switch mode {
+			case "slow":
+				// Never reply.
+			case "illegal":
+				fmt.Println("bestmove a1h8")
+			case "late":
+				if p.FullmoveNumber() == 1 {
+					time.Sleep(400 * time.Millisecond)
+				}
+				fmt.Printf("bestmove %v\n", fakeMove(p, mode))
+			default:
+				fmt.Printf("info depth 1\nbestmove %v\n", fakeMove(p, mode))
+			}
+		case "quit":
+			return
+		}
+	}
+}
+
+func fakePosition(args []string) core.Position {
+	var p core.Position
+	if args[0] == "startpos" {
+		p, args = core.NewPosition(), args[1:]
+	} else {
+		p, args = fen.MustDecode(strings.Join(args[1:7], " ")), args[7:]
+	}
+	if len(args) > 0 && args[0] == "moves" {
+		for _, s := range args[1:] {
+			m, err := core.ParseMove(s)
+			if err != nil {
+				panic(err)
+			}
+			p.Move(m)
+		}
+	}
+	return p
+}
+
+func fakeMove(p core.Position, mode string) core.Move {
+	moves := p.LegalMoves()
+	if mode == "greedy" {
+		b := p.Board()
+		for _, m := range moves {
+			q := p
+			q.Move(m)
+			if q.IsCheckmate() {
+				return m
+			}
+		}
+		for _, m := range moves {
+			if b.IsOccupied(m.To()) {
+				return m
+			}
+		}
+	}
+	return moves[0]
+}
+
+func fakeEngineConfig(t *testing.T, mode string) EngineConfig {
+	t.Setenv("LENTO_FAKE_ENGINE", "1")
+	return EngineConfig{Path: os.Args[0], Args: []string{mode}}
+}
+
+func TestRun(t *testing.T) {
+	cfg := Config{
+		Engines: [2]EngineConfig{
+			fakeEngineConfig(t, "greedy"),
+			fakeEngineConfig(t, "first"),
+		},
+		Openings: []core.Position{
+			core.NewPosition(),
+			fen.MustDecode("rnbqkbnr/pppp1ppp/8/4p3/4P3/8/PPPP1PPP/RNBQKBNR w KQkq e6 0 2"),
+		},
+		Games:       4,
+		TimeControl: TimeControl{Base: 10 * time.Second, Increment: 100 * time.Millisecond},
+		Concurrency: 2,
+	}
+
+	var games []Game
+	stats, err := Run(context.Background(), cfg, func(g Game, _ Stats) {
+		games = append(games, g)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n := stats.Games(); n != 4 {
+		t.Errorf("want 4 games, got %d", n)
+	}
+	if n := len(games); n != 4 {
+		t.Fatalf("want 4 reports, got %d", n)
+	}
+
+	for _, g := range games {
+		white, _ := g.PGN.Tag("White")
+		if want := map[bool]string{true: "greedy", false: "first"}[g.FirstIsWhite]; white != want {
+			t.Errorf("round %d: want White %q, got %q", g.Round, want, white)
+		}
+		if g.PGN.Result == core.NoResult {
+			t.Errorf("round %d: no result", g.Round)
+		}
+
+		// Replaying the game must reach the same result.
+		cg := core.NewGame(g.PGN.Position)
+		for _, m := range g.PGN.Moves {
+			if err := cg.Move(m); err != nil {
+				t.Fatalf("round %d: %v", g.Round, err)
+			}
+		}
+		if r, _ := cg.Result(); r != g.PGN.Result {
+			t.Errorf("round %d: want %v, got %v (%s)", g.Round, r, g.PGN.Result, g.Reason)
+		}
+	}
+}
+
+func TestRun_Forfeits(t *testing.T) {
+	cases := map[string]string{
+		"illegal": "illegal move a1h8",
+		"slow":    "time forfeit",
+		"late":    "time forfeit",
+	}
+	for mode, reason := range cases {
+		cfg := Config{
+			Engines: [2]EngineConfig{
+				fakeEngineConfig(t, "first"),
+				fakeEngineConfig(t, mode),
+			},
+			Games:       2,
+			TimeControl: TimeControl{Base: 200 * time.Millisecond},
+			Concurrency: 2,
+		}
+
+		stats, err := Run(context.Background(), cfg, func(g Game, _ Stats) {
+			if g.Reason != reason {
+				t.Errorf("%s: want reason %q, got %q", mode, reason, g.Reason)
+			}
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := (Stats{Wins: 2}); want != stats {
+			t.Errorf("%s: want %+v, got %+v", mode, want, stats)
+		}
+	}
+}
+
+func TestRun_TimeMargin(t *testing.T) {
+	cfg := Config{
+		Engines: [2]EngineConfig{
+			fakeEngineConfig(t, "greedy"),
+			fakeEngineConfig(t, "late"),
+		},
+		Games:       2,
+		TimeControl: TimeControl{Base: 200 * time.Millisecond, Increment: 100 * time.Millisecond},
+		TimeMargin:  time.Second,
+		Concurrency: 2,
+	}
+	_, err := Run(context.Background(), cfg, func(g Game, _ Stats) {
+		if g.Reason == "time forfeit" {
+			t.Errorf("round %d: time forfeit within the margin", g.Round)
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRun_BadEngine(t *testing.T) {
+	cfg := Config{
+		Engines: [2]EngineConfig{
+			fakeEngineConfig(t, "first"),
+			{Path: "/nonexistent/engine"},
+		},
+		Games:       2,
+		TimeControl: TimeControl{Base: time.Second},
+	}
+	if _, err := Run(context.Background(), cfg, nil); err == nil {
+		t.Error("no error")
+	}
+}
+
+func TestParseTimeControl(t *testing.T) {
+	cases := map[string]TimeControl{
+		"10+0.1": {10 * time.Second, 100 * time.Millisecond},
+		"60":     {Base: 60 * time.Second},
+		"0.5+0":  {Base: 500 * time.Millisecond},
+	}
+	for s, want := range cases {
+		got, err := ParseTimeControl(s)
+		if err != nil {
+			t.Errorf("%q: error: %v", s, err)
+			continue
+		}
+		if want != got {
+			t.Errorf("%q: want %+v, got %+v", s, want, got)
+		}
+	}
+
+	for _, s := range []string{"", "x", "0", "10+", "-1+1", "10+-1"} {
+		if _, err := ParseTimeControl(s); err == nil {
+			t.Errorf("%q: no error", s)
+		}
+	}
+}
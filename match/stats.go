@@ -0,0 +1,139 @@
+package match
+
+import "math"
+
+// Stats are the results of a match from the first engine's perspective.
+type Stats struct {
+	Wins, Draws, Losses int
+}
+
+// Games returns the number of games played.
+func (s Stats) Games() int {
+	return s.Wins + s.Draws + s.Losses
+}
+
+// Score returns the first engine's score, from 0 to 1.
+func (s Stats) Score() float64 {
+	n := s.Games()
+	if n == 0 {
+		return 0.5
+	}
+	return (float64(s.Wins) + float64(s.Draws)/2) / float64(n)
+}
+
+// variance returns the per-game variance of the score.
+func (s Stats) variance() float64 {
+	n := float64(s.Games())
+	if n == 0 {
+		return 0
+	}
+	w, d, l := float64(s.Wins)/n, float64(s.Draws)/n, float64(s.Losses)/n
+	mu := w + d/2
+	return w*(1-mu)*(1-mu) + d*(0.5-mu)*(0.5-mu) + l*(0-mu)*(0-mu)
+}
+
+// Elo returns the estimated Elo difference and the half-width of its 95%
+// confidence interval.
+func (s Stats) Elo() (diff, margin float64) {
+	n := s.Games()
+	if n == 0 {
+		return 0, 0
+	}
+
+	score := s.Score()
+	dev := math.Sqrt(s.variance() / float64(n))
+
+	const z = 1.959963984540054 // 97.5th percentile of the standard normal
+	lo := scoreToElo(score - z*dev)
+	hi := scoreToElo(score + z*dev)
+
+	return scoreToElo(score), (hi - lo) / 2
+}
+
+// LOS returns the likelihood of superiority: the probability that the first
+// engine is stronger than the second.
+func (s Stats) LOS() float64 {
+	if s.Wins+s.Losses == 0 {
+		return 0.5
+	}
+	w, l := float64(s.Wins), float64(s.Losses)
+	return 0.5 * (1 + math.Erf((w-l)/math.Sqrt(2*(w+l))))
+}
+
+// scoreToElo converts an expected score to an Elo difference. Scores of 0 and
+// 1 map to infinities.
+func scoreToElo(score float64) float64 {
+	return -400 * math.Log10(1/score-1)
+}
+
+// eloToScore converts an Elo difference to an expected score.
+func eloToScore(elo float64) float64 {
+	return 1 / (1 + math.Pow(10, -elo/400))
+}
+
+// An SPRT is a sequential probability ratio test between the hypotheses that
+// the Elo difference is Elo0 (H0) or Elo1 (H1).
+type SPRT struct {
+	Elo0, Elo1 float64
+	// Alpha and Beta are the acceptable rates of false positives and false
+	// negatives.
+	Alpha, Beta float64
+}
+
+// A Verdict is the state of an [SPRT].
+type Verdict uint8
+
+// [Verdict] constants.
+const (
+	Continue Verdict = iota
+	AcceptH0
+	AcceptH1
+)
+
+// String returns a description of the verdict.
+func (v Verdict) String() string {
+	switch v {
+	case AcceptH0:
+		return "H0 accepted"
+	case AcceptH1:
+		return "H1 accepted"
+	default:
+		return "continue"
+	}
+}
+
+// Bounds returns the lower and upper bounds of the log-likelihood ratio.
+func (t SPRT) Bounds() (lower, upper float64) {
+	return math.Log(t.Beta / (1 - t.Alpha)), math.Log((1 - t.Beta) / t.Alpha)
+}
+
+// LLR returns the log-likelihood ratio of H1 against H0, using the
+// generalized SPRT normal approximation for trinomial results.
+//
+// LLR returns 0 until at least one win, draw and loss have been played.
+func (t SPRT) LLR(s Stats) float64 {
+	if s.Wins == 0 || s.Draws == 0 || s.Losses == 0 {
+		return 0
+	}
+
+	n := float64(s.Games())
+	s0, s1 := eloToScore(t.Elo0), eloToScore(t.Elo1)
+	score := s.Score()
+	variance := s.variance() / n
+
+	return (s1 - s0) * (2*score - s0 - s1) / (2 * variance)
+}
+
+// Verdict returns the verdict of the test for s.
+func (t SPRT) Verdict(s Stats) Verdict {
+	llr := t.LLR(s)
+	lower, upper := t.Bounds()
+	switch {
+	case llr >= upper:
+		return AcceptH1
+	case llr <= lower:
+		return AcceptH0
+	default:
+		return Continue
+	}
+}
@@ -0,0 +1,69 @@
+package match
+
+import (
+	"math"
+	"testing"
+)
+
+func approx(a, b, tolerance float64) bool {
+	return math.Abs(a-b) <= tolerance
+}
+
+func TestStats_Elo(t *testing.T) {
+	cases := []struct {
+		stats  Stats
+		elo    float64
+		margin float64
+	}{
+		{Stats{Wins: 10, Draws: 10, Losses: 10}, 0, 104.6},
+		{Stats{Wins: 60, Draws: 0, Losses: 40}, 70.4, 70.6},
+		{Stats{Wins: 300, Draws: 400, Losses: 300}, 0, 16.7},
+	}
+	for _, c := range cases {
+		elo, margin := c.stats.Elo()
+		if !approx(elo, c.elo, 0.1) || !approx(margin, c.margin, 0.1) {
+			t.Errorf("%+v: want %.1f +/- %.1f, got %.1f +/- %.1f", c.stats, c.elo, c.margin, elo, margin)
+		}
+	}
+}
+
+func TestStats_LOS(t *testing.T) {
+	cases := []struct {
+		stats Stats
+		want  float64
+	}{
+		{Stats{}, 0.5},
+		{Stats{Wins: 10, Losses: 10}, 0.5},
+		{Stats{Wins: 60, Losses: 40}, 0.977},
+		{Stats{Wins: 40, Draws: 100, Losses: 60}, 0.023},
+	}
+	for _, c := range cases {
+		if got := c.stats.LOS(); !approx(got, c.want, 0.001) {
+			t.Errorf("%+v: want %.3f, got %.3f", c.stats, c.want, got)
+		}
+	}
+}
+
+func TestSPRT(t *testing.T) {
+	sprt := SPRT{Elo0: 0, Elo1: 5, Alpha: 0.05, Beta: 0.05}
+
+	lower, upper := sprt.Bounds()
+	if !approx(lower, -2.944, 0.001) || !approx(upper, 2.944, 0.001) {
+		t.Errorf("bounds: got (%.3f, %.3f)", lower, upper)
+	}
+
+	cases := []struct {
+		stats Stats
+		want  Verdict
+	}{
+		{Stats{Wins: 10, Draws: 10, Losses: 10}, Continue},
+		{Stats{Wins: 3000, Draws: 4000, Losses: 2000}, AcceptH1},
+		{Stats{Wins: 2000, Draws: 4000, Losses: 3000}, AcceptH0},
+		{Stats{Wins: 100}, Continue}, // LLR needs wins, draws and losses
+	}
+	for _, c := range cases {
+		if got := sprt.Verdict(c.stats); c.want != got {
+			t.Errorf("%+v: want %v, got %v (LLR %.3f)", c.stats, c.want, got, sprt.LLR(c.stats))
+		}
+	}
+}
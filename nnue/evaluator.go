@@ -0,0 +1,177 @@
+package nnue
+
+import "github.com/clfs/lento/core"
+
+// An accumulator stores the hidden layer inputs for both perspectives,
+// indexed by color (0 for White, 1 for Black).
+type accumulator [2][]int16
+
+// An Evaluator evaluates positions with a [Network], keeping a stack of
+// accumulators that are updated incrementally as moves are made and unmade.
+type Evaluator struct {
+	net *Network
+	// stack holds the accumulators. The first n are in use, and the rest are
+	// kept for reuse.
+	stack []accumulator
+	n     int
+}
+
+// initialDepth is the number of accumulators an evaluator starts with. The
+// stack grows past it as needed.
+const initialDepth = 32
+
+// NewEvaluator returns a new evaluator for b.
+func NewEvaluator(net *Network, b core.Board) *Evaluator {
+	e := &Evaluator{net: net, stack: make([]accumulator, initialDepth)}
+	buf := make([]int16, 2*initialDepth*net.HiddenSize)
+	for i := range e.stack {
+		for j := range e.stack[i] {
+			e.stack[i][j], buf = buf[:net.HiddenSize:net.HiddenSize], buf[net.HiddenSize:]
+		}
+	}
+	e.Reset(b)
+	return e
+}
+
+// Reset discards all accumulators and refreshes from b.
+func (e *Evaluator) Reset(b core.Board) {
+	acc := e.stack[0]
+	for i := range acc {
+		copy(acc[i], e.net.FeatureBiases)
+	}
+	for s := core.A1; s <= core.H8; s++ {
+		if p, ok := b.Get(s); ok {
+			e.add(acc, p, s)
+		}
+	}
+	e.n = 1
+}
+
+// Move makes m on p and updates the accumulators to match.
+// [Evaluator.Pop] reverts the accumulator update, but not the move on p.
+//
+// The update follows from m itself, except in [core.Atomic], where captures
+// explode the pieces around them and the boards are compared instead.
+func (e *Evaluator) Move(p *core.Position, m core.Move) {
+	b := p.Board()
+	if p.Variant() == core.Atomic {
+		p.Move(m)
+		e.Push(b, p.Board())
+		return
+	}
+
+	acc := e.push()
+	us := p.SideToMove()
+	p.Move(m)
+
+	if pt, ok := m.Drop(); ok {
+		e.add(acc, core.NewPiece(us, pt), m.To())
+		return
+	}
+
+	from, to := m.From(), m.To()
+	held, _ := b.Get(from)
+	e.sub(acc, held, from)
+	if captured, ok := b.Get(to); ok {
+		e.sub(acc, captured, to)
+	} else if held.Type() == core.Pawn && from.File() != to.File() {
+		// En passant: the captured pawn is beside the moving one.
+		e.sub(acc, core.NewPiece(us.Other(), core.Pawn), core.NewSquare(to.File(), from.Rank()))
+	}
+	if pt, ok := m.Promotion(); ok {
+		e.add(acc, core.NewPiece(us, pt), to)
+	} else {
+		e.add(acc, held, to)
+	}
+
+	// Castling moves the king two squares, and the rook with it.
+	if held.Type() != core.King {
+		return
+	}
+	for _, c := range [...]struct{ from, to, rookFrom, rookTo core.Square }{
+		{core.E1, core.G1, core.H1, core.F1},
+		{core.E1, core.C1, core.A1, core.D1},
+		{core.E8, core.G8, core.H8, core.F8},
+		{core.E8, core.C8, core.A8, core.D8},
+	} {
+		if from == c.from && to == c.to {
+			rook := core.NewPiece(us, core.Rook)
+			e.sub(acc, rook, c.rookFrom)
+			e.add(acc, rook, c.rookTo)
+		}
+	}
+}
+
+// Push updates the accumulators for a move that turned before into after.
+//
+// Only the squares that changed are updated, so Push handles captures,
+// castling, en passant and promotions alike.
+func (e *Evaluator) Push(before, after core.Board) {
+	acc := e.push()
+	for s := core.A1; s <= core.H8; s++ {
+		old, hadOld := before.Get(s)
+		cur, hasCur := after.Get(s)
+		if hadOld == hasCur && old == cur {
+			continue
+		}
+		if hadOld {
+			e.sub(acc, old, s)
+		}
+		if hasCur {
+			e.add(acc, cur, s)
+		}
+	}
+}
+
+// Pop reverts the most recent call to [Evaluator.Push] or [Evaluator.Move].
+// It is invalid to call Pop more times than Push.
+func (e *Evaluator) Pop() {
+	e.n--
+}
+
+// Evaluate returns the evaluation of the current board in centipawns, from
+// the perspective of stm.
+func (e *Evaluator) Evaluate(stm core.Color) int {
+	top := e.stack[e.n-1]
+	if stm == core.White {
+		return e.net.output(top[0], top[1])
+	}
+	return e.net.output(top[1], top[0])
+}
+
+// push pushes a copy of the top accumulator and returns it, reusing a slot
+// from an earlier push when there is one.
+func (e *Evaluator) push() accumulator {
+	if e.n == len(e.stack) {
+		e.stack = append(e.stack, accumulator{
+			make([]int16, e.net.HiddenSize),
+			make([]int16, e.net.HiddenSize),
+		})
+	}
+	acc := e.stack[e.n]
+	for i := range acc {
+		copy(acc[i], e.stack[e.n-1][i])
+	}
+	e.n++
+	return acc
+}
+
+func (e *Evaluator) add(acc accumulator, p core.Piece, s core.Square) {
+	n := e.net.HiddenSize
+	for i, c := range []core.Color{core.White, core.Black} {
+		row := e.net.FeatureWeights[feature(c, p, s)*n:][:n]
+		for j, w := range row {
+			acc[i][j] += w
+		}
+	}
+}
+
+func (e *Evaluator) sub(acc accumulator, p core.Piece, s core.Square) {
+	n := e.net.HiddenSize
+	for i, c := range []core.Color{core.White, core.Black} {
+		row := e.net.FeatureWeights[feature(c, p, s)*n:][:n]
+		for j, w := range row {
+			acc[i][j] -= w
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package nnue
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Version is the network file format version written by [Encode].
+//
+// Network files are laid out as follows, with all integers little-endian:
+//
+//   - Magic: the four bytes "LNUE".
+//   - Version: uint32, currently 1.
+//   - Hidden size: uint32.
+//   - Feature weights: InputSize*N int16 values, one row of N per feature.
+//   - Feature biases: N int16 values.
+//   - Output weights: 2*N int16 values.
+//   - Output bias: one int16 value.
+const Version = 1
+
+const magic = "LNUE"
+
+// maxHiddenSize bounds the hidden size accepted by [Decode].
+const maxHiddenSize = 1 << 16
+
+// Decode reads a network in the versioned file format.
+func Decode(r io.Reader) (*Network, error) {
+	br := bufio.NewReader(r)
+
+	var header struct {
+		Magic   [4]byte
+		Version uint32
+		Hidden  uint32
+	}
+	if err := binary.Read(br, binary.LittleEndian, &header); err != nil {
+		return nil, fmt.Errorf("bad header: %v", err)
+	}
+	if string(header.Magic[:]) != magic {
+		return nil, fmt.Errorf("bad magic: %q", header.Magic)
+	}
+	if header.Version != Version {
+		return nil, fmt.Errorf("unsupported version: %d", header.Version)
+	}
+	if header.Hidden == 0 || header.Hidden > maxHiddenSize {
+		return nil, fmt.Errorf("bad hidden size: %d", header.Hidden)
+	}
+
+	// The weights are read in chunks, so a corrupt hidden size fails at the
+	// end of the data instead of allocating for all of it up front.
+	hidden := int(header.Hidden)
+	n := &Network{HiddenSize: hidden}
+	for _, w := range []struct {
+		data *[]int16
+		n    int
+	}{
+		{&n.FeatureWeights, InputSize * hidden},
+		{&n.FeatureBiases, hidden},
+		{&n.OutputWeights, 2 * hidden},
+	} {
+		var err error
+		if *w.data, err = readInt16s(br, w.n); err != nil {
+			return nil, fmt.Errorf("bad weights: %v", err)
+		}
+	}
+	if err := binary.Read(br, binary.LittleEndian, &n.OutputBias); err != nil {
+		return nil, fmt.Errorf("bad weights: %v", err)
+	}
+
+	if _, err := br.ReadByte(); !errors.Is(err, io.EOF) {
+		return nil, errors.New("trailing data")
+	}
+
+	return n, nil
+}
+
+// readInt16s reads n little-endian int16 values from r, growing the result as
+// the values arrive.
+func readInt16s(r io.Reader, n int) ([]int16, error) {
+	const chunk = 1 << 16
+	res := make([]int16, 0, min(n, chunk))
+	for len(res) < n {
+		buf := make([]int16, min(n-len(res), chunk))
+		if err := binary.Read(r, binary.LittleEndian, buf); err != nil {
+			return nil, err
+		}
+		res = append(res, buf...)
+	}
+	return res, nil
+}
+
+// Encode writes a network in the versioned file format.
+func Encode(w io.Writer, n *Network) error {
+	bw := bufio.NewWriter(w)
+
+	for _, data := range []any{
+		[]byte(magic),
+		uint32(Version),
+		uint32(n.HiddenSize),
+		n.FeatureWeights,
+		n.FeatureBiases,
+		n.OutputWeights,
+		n.OutputBias,
+	} {
+		if err := binary.Write(bw, binary.LittleEndian, data); err != nil {
+			return err
+		}
+	}
+
+	return bw.Flush()
+}
@@ -0,0 +1,110 @@
+// Package nnue implements an efficiently updatable neural network evaluation.
+//
+// A [Network] has a 768->N->1 architecture. The 768 inputs are one-hot
+// (color, piece type, square) features, seen from each side's perspective.
+// Each perspective has its own hidden accumulator of N neurons, which share
+// weights. The side to move's accumulator and the other side's accumulator are
+// concatenated, passed through a squared clipped ReLU (SCReLU) and combined
+// into a single output.
+//
+// Accumulators are updated incrementally as moves are made and unmade; see
+// [Evaluator].
+package nnue
+
+import "github.com/clfs/lento/core"
+
+// Quantization constants.
+const (
+	// QA is the quantization factor of the feature transformer.
+	QA = 255
+	// QB is the quantization factor of the output layer.
+	QB = 64
+	// Scale converts the network output to centipawns.
+	Scale = 400
+)
+
+// InputSize is the number of input features.
+const InputSize = 768
+
+// A Network stores the quantized weights of a 768->N->1 network.
+type Network struct {
+	// HiddenSize is the number of neurons in each perspective's accumulator.
+	HiddenSize int
+	// FeatureWeights stores InputSize rows of HiddenSize weights each.
+	FeatureWeights []int16
+	// FeatureBiases stores HiddenSize biases.
+	FeatureBiases []int16
+	// OutputWeights stores 2*HiddenSize weights. The first half applies to
+	// the side to move's accumulator, the second half to the other side's.
+	OutputWeights []int16
+	// OutputBias is the output layer's bias.
+	OutputBias int16
+}
+
+// NewNetwork returns a network with all weights set to zero.
+func NewNetwork(hiddenSize int) *Network {
+	return &Network{
+		HiddenSize:     hiddenSize,
+		FeatureWeights: make([]int16, InputSize*hiddenSize),
+		FeatureBiases:  make([]int16, hiddenSize),
+		OutputWeights:  make([]int16, 2*hiddenSize),
+	}
+}
+
+// feature returns the input index of p on s, seen from perspective c.
+func feature(c core.Color, p core.Piece, s core.Square) int {
+	side := 0
+	if p.Color() != c {
+		side = 1
+	}
+	if c == core.Black {
+		s ^= 56 // flip vertically
+	}
+	return side*384 + int(p.Type())*64 + int(s)
+}
+
+// Reference evaluates b from scratch, without incremental updates.
+//
+// The result is in centipawns, from the perspective of stm. It is always
+// equal to the result of [Evaluator.Evaluate] for the same board.
+func (n *Network) Reference(b core.Board, stm core.Color) int {
+	var acc [2][]int16
+	for i, c := range []core.Color{core.White, core.Black} {
+		acc[i] = make([]int16, n.HiddenSize)
+		copy(acc[i], n.FeatureBiases)
+		for s := core.A1; s <= core.H8; s++ {
+			p, ok := b.Get(s)
+			if !ok {
+				continue
+			}
+			row := n.FeatureWeights[feature(c, p, s)*n.HiddenSize:]
+			for j := range acc[i] {
+				acc[i][j] += row[j]
+			}
+		}
+	}
+	if stm == core.White {
+		return n.output(acc[0], acc[1])
+	}
+	return n.output(acc[1], acc[0])
+}
+
+// output computes the network output from both perspectives' accumulators.
+func (n *Network) output(us, them []int16) int {
+	var sum int64
+	for i, v := range us {
+		sum += screlu(v) * int64(n.OutputWeights[i])
+	}
+	for i, v := range them {
+		sum += screlu(v) * int64(n.OutputWeights[n.HiddenSize+i])
+	}
+	sum /= QA
+	sum += int64(n.OutputBias)
+	return int(sum * Scale / (QA * QB))
+}
+
+// screlu is the squared clipped ReLU activation function.
+func screlu(v int16) int64 {
+	x := int64(min(max(v, 0), QA))
+	return x * x
+}
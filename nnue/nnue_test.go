@@ -0,0 +1,161 @@
+package nnue
+
+import (
+	"bytes"
+	"math/rand/v2"
+	"reflect"
+	"runtime"
+	"testing"
+
+	"github.com/clfs/lento/core"
+	"github.com/clfs/lento/encoding/fen"
+)
+
+func randomNetwork(hiddenSize int) *Network {
+	r := rand.New(rand.NewPCG(1, 2))
+	n := NewNetwork(hiddenSize)
+	for _, s := range [][]int16{n.FeatureWeights, n.FeatureBiases, n.OutputWeights} {
+		for i := range s {
+			s[i] = int16(r.IntN(255) - 127)
+		}
+	}
+	n.OutputBias = int16(r.IntN(255) - 127)
+	return n
+}
+
+func TestEncodeDecode(t *testing.T) {
+	want := randomNetwork(16)
+
+	var buf bytes.Buffer
+	if err := Encode(&buf, want); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := Decode(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("changed in round trip")
+	}
+}
+
+func TestDecode_Invalid(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Encode(&buf, randomNetwork(4)); err != nil {
+		t.Fatal(err)
+	}
+	valid := buf.Bytes()
+
+	cases := map[string][]byte{
+		"empty":     nil,
+		"bad magic": append([]byte("XXXX"), valid[4:]...),
+		"truncated": valid[:len(valid)-1],
+		"trailing":  append(bytes.Clone(valid), 0),
+	}
+	for name, b := range cases {
+		if _, err := Decode(bytes.NewReader(b)); err == nil {
+			t.Errorf("%s: no error", name)
+		}
+	}
+}
+
+func TestEvaluator(t *testing.T) {
+	net := randomNetwork(32)
+	p := fen.MustDecode("r3k2r/1P6/8/3pP3/8/8/8/R3K2R w KQkq d6 0 1")
+	e := NewEvaluator(net, p.Board())
+
+	moves := []core.Move{
+		core.NewMove(core.E5, core.D6), // en passant
+		core.NewMove(core.E8, core.G8), // castling
+		core.NewPromotionMove(core.B7, core.A8, core.Queen),
+		core.NewMove(core.F8, core.A8), // capture
+		core.NewMove(core.E1, core.C1), // castling
+	}
+
+	check := func(b core.Board) {
+		t.Helper()
+		for _, c := range []core.Color{core.White, core.Black} {
+			want := net.Reference(b, c)
+			if got := e.Evaluate(c); want != got {
+				t.Errorf("%s: want %d, got %d", fen.EncodeBoard(b), want, got)
+			}
+		}
+	}
+
+	boards := []core.Board{p.Board()}
+	check(p.Board())
+	for _, m := range moves {
+		e.Move(&p, m)
+		boards = append(boards, p.Board())
+		check(p.Board())
+	}
+	for i := len(moves) - 1; i >= 0; i-- {
+		e.Pop()
+		check(boards[i])
+	}
+}
+
+func TestEvaluator_RandomGames(t *testing.T) {
+	net := randomNetwork(8)
+	r := rand.New(rand.NewPCG(3, 4))
+
+	// Games run past the evaluator's initial stack depth.
+	for _, v := range []core.Variant{core.Standard, core.Crazyhouse, core.Atomic} {
+		for range 10 {
+			p := core.NewPosition(core.WithVariant(v))
+			e := NewEvaluator(net, p.Board())
+			boards := []core.Board{p.Board()}
+			for range 2 * initialDepth {
+				moves := p.LegalMoves()
+				if len(moves) == 0 {
+					break
+				}
+				m := moves[r.IntN(len(moves))]
+				e.Move(&p, m)
+				boards = append(boards, p.Board())
+				if want, got := net.Reference(p.Board(), core.White), e.Evaluate(core.White); want != got {
+					t.Fatalf("%v after %v: want %d, got %d", v, m, want, got)
+				}
+			}
+			for i := len(boards) - 2; i >= 0; i-- {
+				e.Pop()
+				if want, got := net.Reference(boards[i], core.Black), e.Evaluate(core.Black); want != got {
+					t.Fatalf("%v after popping to ply %d: want %d, got %d", v, i, want, got)
+				}
+			}
+		}
+	}
+}
+
+func TestEvaluator_Allocs(t *testing.T) {
+	p := core.NewPosition()
+	e := NewEvaluator(randomNetwork(8), p.Board())
+	m := core.NewMove(core.E2, core.E4)
+
+	allocs := testing.AllocsPerRun(100, func() {
+		q := p
+		e.Move(&q, m)
+		e.Pop()
+	})
+	if allocs != 0 {
+		t.Errorf("want no allocations, got %v", allocs)
+	}
+}
+
+func TestDecode_HugeHiddenSize(t *testing.T) {
+	// The header claims the largest hidden size, about 100 MB of weights,
+	// but no weights follow.
+	b := []byte("LNUE\x01\x00\x00\x00\x00\x00\x01\x00")
+
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	if _, err := Decode(bytes.NewReader(b)); err == nil {
+		t.Error("no error")
+	}
+	runtime.ReadMemStats(&after)
+	if n := after.TotalAlloc - before.TotalAlloc; n > 1<<20 {
+		t.Errorf("allocated %d bytes", n)
+	}
+}
@@ -0,0 +1,346 @@
+// Package problem solves chess problems: direct mates, helpmates and
+// selfmates.
+//
+// Solving is exhaustive, so besides the solutions, a [Report] shows the flaws
+// that composers look for: cooks, which are unintended extra solutions, short
+// solutions in fewer moves than stipulated, and duals, where the attacking
+// side has more than one way to go on.
+package problem
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/clfs/lento/core"
+	"github.com/clfs/lento/encoding/fen"
+)
+
+// A Kind is a kind of problem.
+type Kind uint8
+
+// [Kind] constants.
+const (
+	// DirectMate problems ask the side to move to force mate, whatever the
+	// defense.
+	DirectMate Kind = iota
+	// Helpmate problems ask both sides to cooperate so that the side to move
+	// is mated. The side to move plays first.
+	Helpmate
+	// Selfmate problems ask the side to move to force the other side to give
+	// mate, against its resistance.
+	Selfmate
+)
+
+// A Stipulation is what a problem asks for.
+type Stipulation struct {
+	Kind Kind
+	// Moves is the number of moves for each side.
+	Moves int
+}
+
+// String returns the stipulation in the usual notation, like "#2", "h3#" or
+// "s4#".
+func (s Stipulation) String() string {
+	switch s.Kind {
+	case Helpmate:
+		return fmt.Sprintf("h%d#", s.Moves)
+	case Selfmate:
+		return fmt.Sprintf("s%d#", s.Moves)
+	default:
+		return fmt.Sprintf("#%d", s.Moves)
+	}
+}
+
+var stipulationRegexp = regexp.MustCompile(`^([hs]?)(?:#([1-9][0-9]*)|([1-9][0-9]*)#)$`)
+
+// ParseStipulation parses a stipulation, like "#2", "h3#" or "s4#". The
+// number may also follow the "#", like "h#3".
+func ParseStipulation(s string) (Stipulation, error) {
+	m := stipulationRegexp.FindStringSubmatch(s)
+	if m == nil {
+		return Stipulation{}, fmt.Errorf("bad stipulation: %q", s)
+	}
+	n, err := strconv.Atoi(m[2] + m[3])
+	if err != nil {
+		return Stipulation{}, fmt.Errorf("bad stipulation: %q", s)
+	}
+	st := Stipulation{Kind: DirectMate, Moves: n}
+	switch m[1] {
+	case "h":
+		st.Kind = Helpmate
+	case "s":
+		st.Kind = Selfmate
+	}
+	return st, nil
+}
+
+// A Problem is a position and a stipulation.
+type Problem struct {
+	Position    core.Position
+	Stipulation Stipulation
+}
+
+// Parse parses a problem written as FEN followed by a stipulation, like
+// "8/8/8/8/8/1K6/8/k1Q5 w - - 0 1 #2".
+func Parse(s string) (Problem, error) {
+	i := strings.LastIndexByte(s, ' ')
+	if i < 0 {
+		return Problem{}, fmt.Errorf("bad problem: %q", s)
+	}
+	st, err := ParseStipulation(s[i+1:])
+	if err != nil {
+		return Problem{}, err
+	}
+	p, err := fen.Decode(s[:i])
+	if err != nil {
+		return Problem{}, err
+	}
+	return Problem{Position: p, Stipulation: st}, nil
+}
+
+// A Report describes the solutions to a problem.
+type Report struct {
+	// Solutions are the solutions in the stipulated number of moves. In
+	// direct mates and selfmates, more than one is a cook. Helpmates are
+	// often composed with several solutions.
+	Solutions []Solution
+	// Short are the solutions in fewer moves than stipulated.
+	Short []Solution
+	// Nodes is the number of positions searched.
+	Nodes int
+}
+
+// A Solution solves a problem.
+type Solution struct {
+	// Moves are the key move, in direct mates and selfmates, or the whole
+	// line of play, in helpmates.
+	Moves []core.Move
+	// Length is the number of moves for each side.
+	Length int
+	// Duals are the places, after the key move of a direct mate or
+	// selfmate, where the attacking side has more than one move that still
+	// solves the problem. Helpmates have none: each line is its own
+	// solution.
+	Duals []Dual
+}
+
+// A Dual is a choice of moves for the attacking side.
+type Dual struct {
+	// Line is the play from the problem position up to the choice.
+	Line []core.Move
+	// Moves are the moves that still solve the problem.
+	Moves []core.Move
+}
+
+// Solve finds every solution to pr. If ctx is done before the search ends,
+// Solve returns ctx's error.
+func Solve(ctx context.Context, pr Problem) (Report, error) {
+	n := pr.Stipulation.Moves
+	if n <= 0 {
+		return Report{}, fmt.Errorf("bad move count: %d", n)
+	}
+
+	s := &search{ctx: ctx}
+	var r Report
+	switch pr.Stipulation.Kind {
+	case Helpmate:
+		for k := 1; k <= n; k++ {
+			for _, line := range s.helpmates(pr.Position, k, nil) {
+				sol := Solution{Moves: line, Length: k}
+				if k < n {
+					r.Short = append(r.Short, sol)
+				} else {
+					r.Solutions = append(r.Solutions, sol)
+				}
+			}
+		}
+	default:
+		wins := s.mateAfter
+		if pr.Stipulation.Kind == Selfmate {
+			wins = s.selfmateAfter
+		}
+		for _, key := range pr.Position.LegalMoves() {
+			q := pr.Position
+			q.Move(key)
+			for k := 1; k <= n; k++ {
+				if !wins(q, k) {
+					continue
+				}
+				sol := Solution{
+					Moves:  []core.Move{key},
+					Length: k,
+					Duals:  s.duals([]core.Move{key}, q, k, wins),
+				}
+				if k < n {
+					r.Short = append(r.Short, sol)
+				} else {
+					r.Solutions = append(r.Solutions, sol)
+				}
+				break
+			}
+		}
+	}
+
+	if s.err != nil {
+		return Report{}, s.err
+	}
+	r.Nodes = s.nodes
+	return r, nil
+}
+
+type search struct {
+	ctx   context.Context
+	nodes int
+	err   error // set once ctx is done
+}
+
+// visit counts a position, returning false once the search must stop.
+func (s *search) visit() bool {
+	s.nodes++
+	if s.err == nil && s.nodes%4096 == 0 {
+		s.err = s.ctx.Err()
+	}
+	return s.err == nil
+}
+
+// forcesMate returns true if the side to move in p can force mate in at most
+// n moves.
+func (s *search) forcesMate(p core.Position, n int) bool {
+	for _, m := range p.LegalMoves() {
+		q := p
+		q.Move(m)
+		if s.mateAfter(q, n) {
+			return true
+		}
+	}
+	return false
+}
+
+// mateAfter returns true if the attacker, who just moved to reach q, forces
+// mate in at most n moves counting that move.
+func (s *search) mateAfter(q core.Position, n int) bool {
+	if !s.visit() {
+		return false
+	}
+	if q.IsCheckmate() {
+		return true
+	}
+	if n == 1 {
+		return false
+	}
+	defenses := q.LegalMoves()
+	if len(defenses) == 0 {
+		return false
+	}
+	for _, d := range defenses {
+		r := q
+		r.Move(d)
+		if !s.forcesMate(r, n-1) {
+			return false
+		}
+	}
+	return true
+}
+
+// forcesSelfmate returns true if the side to move in p can force the other
+// side to give mate in at most n moves.
+func (s *search) forcesSelfmate(p core.Position, n int) bool {
+	for _, m := range p.LegalMoves() {
+		q := p
+		q.Move(m)
+		if s.selfmateAfter(q, n) {
+			return true
+		}
+	}
+	return false
+}
+
+// selfmateAfter returns true if the attacker, who just moved to reach q,
+// forces the defender to give mate in at most n moves counting that move.
+func (s *search) selfmateAfter(q core.Position, n int) bool {
+	if !s.visit() {
+		return false
+	}
+	defenses := q.LegalMoves()
+	if len(defenses) == 0 {
+		return false
+	}
+	for _, d := range defenses {
+		r := q
+		r.Move(d)
+		if r.IsCheckmate() {
+			continue
+		}
+		if n == 1 || !s.forcesSelfmate(r, n-1) {
+			return false
+		}
+	}
+	return true
+}
+
+// duals returns the duals in the play after line, which reaches q and solves
+// the problem in n moves according to wins.
+func (s *search) duals(line []core.Move, q core.Position, n int, wins func(core.Position, int) bool) []Dual {
+	if n == 1 {
+		return nil
+	}
+	var res []Dual
+	for _, d := range q.LegalMoves() {
+		r := q
+		r.Move(d)
+		if len(r.LegalMoves()) == 0 {
+			continue // the defense ended the game
+		}
+
+		var alts []core.Move
+		for _, m := range r.LegalMoves() {
+			x := r
+			x.Move(m)
+			if wins(x, n-1) {
+				alts = append(alts, m)
+			}
+		}
+
+		l := append(slices.Clone(line), d)
+		if len(alts) > 1 {
+			res = append(res, Dual{Line: l, Moves: alts})
+		}
+		for _, m := range alts {
+			x := r
+			x.Move(m)
+			res = append(res, s.duals(append(slices.Clone(l), m), x, n-1, wins)...)
+		}
+	}
+	return res
+}
+
+// helpmates returns the lines, each following line, in which the side to
+// move in p is mated on the other side's nth move.
+func (s *search) helpmates(p core.Position, n int, line []core.Move) [][]core.Move {
+	var res [][]core.Move
+	for _, b := range p.LegalMoves() {
+		q := p
+		q.Move(b)
+		for _, w := range q.LegalMoves() {
+			r := q
+			r.Move(w)
+			if !s.visit() {
+				return nil
+			}
+			l := append(slices.Clone(line), b, w)
+			switch {
+			case n == 1:
+				if r.IsCheckmate() {
+					res = append(res, l)
+				}
+			case len(r.LegalMoves()) > 0:
+				res = append(res, s.helpmates(r, n-1, l)...)
+			}
+		}
+	}
+	return res
+}
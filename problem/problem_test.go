@@ -0,0 +1,167 @@
+package problem
+
+import (
+	"context"
+	"reflect"
+	"slices"
+	"testing"
+
+	"github.com/clfs/lento/core"
+)
+
+func parseMoves(t *testing.T, moves ...string) []core.Move {
+	t.Helper()
+	var res []core.Move
+	for _, s := range moves {
+		m, err := core.ParseMove(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res = append(res, m)
+	}
+	return res
+}
+
+func solve(t *testing.T, s string) Report {
+	t.Helper()
+	pr, err := Parse(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := Solve(context.Background(), pr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+func TestParseStipulation(t *testing.T) {
+	cases := []struct {
+		in   string
+		want Stipulation
+		out  string
+	}{
+		{"#2", Stipulation{DirectMate, 2}, "#2"},
+		{"2#", Stipulation{DirectMate, 2}, "#2"},
+		{"h3#", Stipulation{Helpmate, 3}, "h3#"},
+		{"h#3", Stipulation{Helpmate, 3}, "h3#"},
+		{"s12#", Stipulation{Selfmate, 12}, "s12#"},
+	}
+	for _, tc := range cases {
+		got, err := ParseStipulation(tc.in)
+		if err != nil {
+			t.Errorf("%q: %v", tc.in, err)
+			continue
+		}
+		if got != tc.want {
+			t.Errorf("%q: want %+v, got %+v", tc.in, tc.want, got)
+		}
+		if got.String() != tc.out {
+			t.Errorf("%q: want %q, got %q", tc.in, tc.out, got.String())
+		}
+	}
+}
+
+func TestParseStipulation_Invalid(t *testing.T) {
+	for _, s := range []string{"", "#", "#0", "x2#", "h2", "#2#", "s#02"} {
+		if _, err := ParseStipulation(s); err == nil {
+			t.Errorf("%q: no error", s)
+		}
+	}
+}
+
+func TestParse_Invalid(t *testing.T) {
+	for _, s := range []string{
+		"#2",
+		"8/8/8/8/8/1K6/8/k1Q5 w - - 0 1",
+		"8/8/8/8/8/1K6/8/k1Q5 w - - 0 #2",
+	} {
+		if _, err := Parse(s); err == nil {
+			t.Errorf("%q: no error", s)
+		}
+	}
+}
+
+func TestSolve_DirectMate(t *testing.T) {
+	r := solve(t, "r2qkb1r/pp2nppp/3p4/2pNN1B1/2BnP3/3P4/PPP2PPP/R2bK2R w KQkq - 1 1 #2")
+	want := []Solution{{Moves: parseMoves(t, "d5f6"), Length: 2}}
+	if !reflect.DeepEqual(want, r.Solutions) {
+		t.Errorf("want solutions %v, got %v", want, r.Solutions)
+	}
+	if len(r.Short) != 0 {
+		t.Errorf("want no short solutions, got %v", r.Short)
+	}
+}
+
+func TestSolve_Flaws(t *testing.T) {
+	r := solve(t, "8/8/8/8/8/1K6/8/k1Q5 w - - 0 1 #2")
+
+	// Every queen move along the first rank, except to b1, mates at once.
+	var short []core.Move
+	for _, sol := range r.Short {
+		if sol.Length != 1 {
+			t.Errorf("short solution %v: want length 1, got %d", sol.Moves, sol.Length)
+		}
+		short = append(short, sol.Moves...)
+	}
+	if m := parseMoves(t, "c1h1")[0]; !slices.Contains(short, m) {
+		t.Errorf("want short solution %v, got %v", m, short)
+	}
+
+	// After 1. Qd2 Kb1, White mates with 2. Qd1, 2. Qe1 or 2. Qb2.
+	i := slices.IndexFunc(r.Solutions, func(sol Solution) bool {
+		return sol.Moves[0] == parseMoves(t, "c1d2")[0]
+	})
+	if i < 0 {
+		t.Fatalf("want solution c1d2, got %v", r.Solutions)
+	}
+	want := []Dual{{
+		Line:  parseMoves(t, "c1d2", "a1b1"),
+		Moves: parseMoves(t, "d2d1", "d2e1", "d2b2"),
+	}}
+	if got := r.Solutions[i].Duals; !reflect.DeepEqual(want, got) {
+		t.Errorf("want duals %v, got %v", want, got)
+	}
+}
+
+func TestSolve_Helpmate(t *testing.T) {
+	r := solve(t, "6rk/6pp/8/8/8/8/8/4K2R b K - 0 1 h2#")
+	want := []Solution{{Moves: parseMoves(t, "h7h5", "h1h5"), Length: 1}}
+	if !reflect.DeepEqual(want, r.Short) {
+		t.Errorf("want short solutions %v, got %v", want, r.Short)
+	}
+	if len(r.Solutions) == 0 {
+		t.Fatal("no solutions")
+	}
+	for _, sol := range r.Solutions {
+		if len(sol.Moves) != 4 {
+			t.Errorf("solution %v: want 4 moves", sol.Moves)
+		}
+	}
+}
+
+func TestSolve_Selfmate(t *testing.T) {
+	// 1. Qc4+ dxc4 uncovers the bishop on a8.
+	r := solve(t, "b7/8/8/p2p4/k7/p7/7P/5QBK w - - 0 1 s1#")
+	want := []Solution{{Moves: parseMoves(t, "f1c4"), Length: 1}}
+	if !reflect.DeepEqual(want, r.Solutions) {
+		t.Errorf("want solutions %v, got %v", want, r.Solutions)
+	}
+
+	r = solve(t, "b7/8/8/p2p4/k7/p7/7P/5QBK w - - 0 1 s2#")
+	if len(r.Solutions) != 0 || !reflect.DeepEqual(want, r.Short) {
+		t.Errorf("want only the short solution %v, got %v and %v", want, r.Solutions, r.Short)
+	}
+}
+
+func TestSolve_Canceled(t *testing.T) {
+	pr, err := Parse("r2qkb1r/pp2nppp/3p4/2pNN1B1/2BnP3/3P4/PPP2PPP/R2bK2R w KQkq - 1 1 #4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := Solve(ctx, pr); err != context.Canceled {
+		t.Errorf("want context.Canceled, got %v", err)
+	}
+}
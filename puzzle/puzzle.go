@@ -0,0 +1,344 @@
+// Package puzzle finds tactical puzzles in games, using a UCI engine to judge
+// positions.
+//
+// A puzzle starts where the side to move, the solver, has exactly one move
+// that wins: by the engine's MultiPV search, the best move is winning and the
+// second best isn't. The solution goes on, with the engine's replies for the
+// opponent, for as long as each of the solver's moves is just as unique, and
+// ends on a solver move. A mating puzzle must end in mate.
+package puzzle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/clfs/lento/core"
+	"github.com/clfs/lento/encoding/pgn"
+	"github.com/clfs/lento/uci"
+	"github.com/clfs/lento/uci/client"
+)
+
+// A Config configures a [Finder].
+type Config struct {
+	// Limits bound each search.
+	Limits uci.Limits
+	// MinScore is the least score, in centipawns, at which a move is winning.
+	// The default is 200.
+	MinScore int
+	// MaxMoves bounds the number of solver moves in a solution. The default
+	// is 5.
+	MaxMoves int
+}
+
+// A Puzzle is a position and its solution.
+type Puzzle struct {
+	Position core.Position
+	// Moves are the solution, with the solver's moves alternating with the
+	// opponent's replies. The first and last moves are the solver's.
+	Moves []core.Move
+	// Themes describe the tactics in the solution; see [Themes].
+	Themes []string
+}
+
+// A Finder finds puzzles, searching with an engine.
+type Finder struct {
+	c   *client.Client
+	cfg Config
+}
+
+// NewFinder returns a finder that searches with c, which must support the
+// MultiPV option. The finder sets MultiPV to 2, and must be the only user of
+// c.
+func NewFinder(c *client.Client, cfg Config) (*Finder, error) {
+	if _, ok := c.Option("MultiPV"); !ok {
+		return nil, errors.New("engine doesn't support multipv")
+	}
+	if err := c.SetOption("MultiPV", "2"); err != nil {
+		return nil, err
+	}
+	if cfg.MinScore <= 0 {
+		cfg.MinScore = 200
+	}
+	if cfg.MaxMoves <= 0 {
+		cfg.MaxMoves = 5
+	}
+	return &Finder{c: c, cfg: cfg}, nil
+}
+
+// Find returns the puzzles in the positions of g, in order. Positions reached
+// by following the solution of an earlier puzzle are skipped.
+func (f *Finder) Find(ctx context.Context, g pgn.Game) ([]Puzzle, error) {
+	var res []Puzzle
+	p := g.Position
+	skip := 0
+	for i := 0; i <= len(g.Moves); i++ {
+		if skip > 0 {
+			skip--
+		} else {
+			pz, ok, err := f.Solve(ctx, p)
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				res = append(res, pz)
+				for skip < len(pz.Moves)-1 && i+skip < len(g.Moves) && g.Moves[i+skip] == pz.Moves[skip] {
+					skip++
+				}
+			}
+		}
+		if i < len(g.Moves) {
+			p.Move(g.Moves[i])
+		}
+	}
+	return res, nil
+}
+
+// Solve returns the puzzle starting at p, or false if p isn't the start of a
+// puzzle. Positions with only one legal move aren't.
+func (f *Finder) Solve(ctx context.Context, p core.Position) (Puzzle, bool, error) {
+	if len(p.LegalMoves()) < 2 {
+		return Puzzle{}, false, nil
+	}
+	lines, err := f.analyze(ctx, p)
+	if err != nil || !f.decisive(lines) {
+		return Puzzle{}, false, err
+	}
+	mating := lines[0].score.Mate > 0
+
+	pz := Puzzle{Position: p, Moves: []core.Move{lines[0].move}}
+	q := p
+	q.Move(lines[0].move)
+	for n := 1; n < f.cfg.MaxMoves && len(q.LegalMoves()) > 0; n++ {
+		replies, err := f.analyze(ctx, q)
+		if err != nil {
+			return Puzzle{}, false, err
+		}
+		if len(replies) == 0 {
+			break
+		}
+		r := q
+		r.Move(replies[0].move)
+		lines, err := f.analyze(ctx, r)
+		if err != nil {
+			return Puzzle{}, false, err
+		}
+		if !f.decisive(lines) {
+			break
+		}
+		pz.Moves = append(pz.Moves, replies[0].move, lines[0].move)
+		q = r
+		q.Move(lines[0].move)
+	}
+
+	if mating && !q.IsCheckmate() {
+		return Puzzle{}, false, nil
+	}
+	pz.Themes = Themes(p, pz.Moves)
+	return pz, true, nil
+}
+
+// A line is the start of a principal variation.
+type line struct {
+	score uci.Score
+	move  core.Move
+}
+
+// decisive returns true if the best of lines wins and no other does. When the
+// best line mates, other lines may win, but not by mate.
+func (f *Finder) decisive(lines []line) bool {
+	if len(lines) == 0 {
+		return false
+	}
+	best := lines[0].score
+	if best.Mate > 0 {
+		return len(lines) == 1 || lines[1].score.Mate <= 0
+	}
+	if best.Mate < 0 || best.CP < f.cfg.MinScore {
+		return false
+	}
+	if len(lines) == 1 {
+		return true
+	}
+	second := lines[1].score
+	return second.Mate < 0 || second.Mate == 0 && second.CP < f.cfg.MinScore
+}
+
+// analyze searches p and returns the engine's lines, best first. Lines with
+// bounded scores or illegal moves are left out.
+func (f *Finder) analyze(ctx context.Context, p core.Position) ([]line, error) {
+	if err := f.c.SetPosition(p, nil); err != nil {
+		return nil, err
+	}
+	s, err := f.c.Go(ctx, f.cfg.Limits)
+	if err != nil {
+		return nil, err
+	}
+
+	var lines [2]*line
+	for info := range s.Info {
+		n := max(info.MultiPV, 1)
+		if n > len(lines) || info.Score == nil || info.Score.Lower || info.Score.Upper ||
+			len(info.PV) == 0 || !p.IsLegal(info.PV[0]) {
+			continue
+		}
+		lines[n-1] = &line{score: *info.Score, move: info.PV[0]}
+	}
+	if _, err := s.Wait(); err != nil {
+		return nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var res []line
+	for _, l := range lines {
+		if l == nil {
+			break
+		}
+		res = append(res, *l)
+	}
+	return res, nil
+}
+
+// Themes returns the themes of the solution moves from p, which alternate
+// between the solver and the opponent:
+//
+//   - "mateInN" if the solution ends in mate after N solver moves.
+//   - "fork" if a solver piece attacks two targets at once, and later takes
+//     one. Targets are the king, pieces worth more than the attacker, and
+//     undefended pieces other than pawns.
+//   - "pin" if a solver move pins a piece to the king or to a piece worth
+//     more.
+//   - "promotion" if the solver promotes a pawn.
+func Themes(p core.Position, moves []core.Move) []string {
+	var res []string
+	var fork, pin, promotion bool
+
+	q := p
+	for i, m := range moves {
+		q.Move(m)
+		if i%2 != 0 {
+			continue
+		}
+		if _, ok := m.Promotion(); ok {
+			promotion = true
+		}
+		b := q.Board()
+		fork = fork || forks(&b, m.To(), moves[i+1:])
+		pin = pin || pins(&b, m.To())
+	}
+
+	if q.IsCheckmate() {
+		res = append(res, fmt.Sprintf("mateIn%d", (len(moves)+1)/2))
+	}
+	if fork {
+		res = append(res, "fork")
+	}
+	if pin {
+		res = append(res, "pin")
+	}
+	if promotion {
+		res = append(res, "promotion")
+	}
+	return res
+}
+
+// values are the piece values in pawns, indexed by piece type. Kings are
+// worth more than everything else together.
+var values = [...]int{
+	core.Pawn:   1,
+	core.Knight: 3,
+	core.Bishop: 3,
+	core.Rook:   5,
+	core.Queen:  9,
+	core.King:   100,
+}
+
+// forks returns true if the piece on s attacks two targets in b, and takes
+// one of them in the rest of the solution.
+func forks(b *core.Board, s core.Square, rest []core.Move) bool {
+	attacker, _ := b.Get(s)
+	if attacker.Type() == core.King {
+		return false
+	}
+	them := attacker.Color().Other()
+
+	var targets []core.Square
+	for t := core.Square(0); t < 64; t++ {
+		victim, ok := b.Get(t)
+		if !ok || victim.Color() != them || !attacks(b, s, t) {
+			continue
+		}
+		if victim.Type() == core.King || values[victim.Type()] > values[attacker.Type()] ||
+			victim.Type() != core.Pawn && !b.IsAttacked(t, them) {
+			targets = append(targets, t)
+		}
+	}
+	if len(targets) < 2 {
+		return false
+	}
+
+	// Follow the attacker through the solver's later moves.
+	for i := 0; i < len(rest); i++ {
+		m := rest[i]
+		switch {
+		case i%2 == 0:
+			continue // the opponent's move
+		case m.From() != s:
+			continue
+		case slices.Contains(targets, m.To()):
+			return true
+		}
+		s = m.To()
+	}
+	return false
+}
+
+// pins returns true if the piece on s, a bishop, rook or queen, pins a piece
+// in b to the king or to a piece worth more.
+func pins(b *core.Board, s core.Square) bool {
+	attacker, _ := b.Get(s)
+	switch attacker.Type() {
+	case core.Bishop, core.Rook, core.Queen:
+	default:
+		return false
+	}
+	them := attacker.Color().Other()
+
+	for t := core.Square(0); t < 64; t++ {
+		pinned, ok := b.Get(t)
+		if !ok || pinned.Color() != them || pinned.Type() == core.King || !attacks(b, s, t) {
+			continue
+		}
+		without := *b
+		without.Clear(t)
+		for u := core.Square(0); u < 64; u++ {
+			behind, ok := b.Get(u)
+			if !ok || behind.Color() != them || values[behind.Type()] <= values[pinned.Type()] {
+				continue
+			}
+			if attacks(&without, s, u) && !attacks(b, s, u) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// attacks returns true if the piece on s attacks t in b.
+func attacks(b *core.Board, s, t core.Square) bool {
+	attacker, _ := b.Get(s)
+	us := attacker.Color()
+
+	// Turn the attacker's allies into enemy pawns, which block the same
+	// lines but attack nothing of theirs.
+	only := *b
+	for u := core.Square(0); u < 64; u++ {
+		if p, ok := b.Get(u); ok && u != s && p.Color() == us {
+			only.Set(core.NewPiece(us.Other(), core.Pawn), u)
+		}
+	}
+	return only.IsAttacked(t, us)
+}
@@ -0,0 +1,277 @@
+package puzzle
+
+import (
+	"bufio"
+	"cmp"
+	"context"
+	"fmt"
+	"os"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/clfs/lento/core"
+	"github.com/clfs/lento/encoding/fen"
+	"github.com/clfs/lento/encoding/pgn"
+	"github.com/clfs/lento/uci"
+	"github.com/clfs/lento/uci/client"
+)
+
+// If LENTO_FAKE_ENGINE is set, the test binary acts as a UCI engine instead,
+// with its behavior chosen by its first argument.
+func TestMain(m *testing.M) {
+	if os.Getenv("LENTO_FAKE_ENGINE") != "" {
+		fakeEngine(os.Args[1])
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// fakeEngine searches every root move to the requested depth by counting
+// material, and reports the best as its lines. Modes:
+//
+//   - "multipv" supports the MultiPV option.
+//   - "single" doesn't.
+func fakeEngine(mode string) {
+	var (
+		p       = core.NewPosition()
+		multiPV = 1
+	)
+
+	s := bufio.NewScanner(os.Stdin)
+	for s.Scan() {
+		fields := strings.Fields(s.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		switch fields[0] {
+		case "uci":
+			fmt.Printf("id name %s\n", mode)
+			if mode == "multipv" {
+				fmt.Println("option name MultiPV type spin default 1 min 1 max 500")
+			}
+			fmt.Println("uciok")
+		case "isready":
+			fmt.Println("readyok")
+		case "setoption":
+			fmt.Sscanf(s.Text(), "setoption name MultiPV value %d", &multiPV)
+		case "position":
+			p = core.NewPosition()
+			if fields[1] == "fen" {
+				p = fen.MustDecode(strings.Join(fields[2:8], " "))
+			}
+		case "go":
+			depth := 1
+			if i := slices.Index(fields, "depth"); i > 0 {
+				fmt.Sscan(fields[i+1], &depth)
+			}
+			fakeSearch(p, multiPV, depth)
+		case "quit":
+			return
+		}
+	}
+}
+
+const mateScore = 100_000
+
+func fakeSearch(p core.Position, multiPV, depth int) {
+	type scored struct {
+		m     core.Move
+		score int
+	}
+	var lines []scored
+	for _, m := range p.LegalMoves() {
+		q := p
+		q.Move(m)
+		lines = append(lines, scored{m, -negamax(q, depth-1, 1)})
+	}
+	slices.SortFunc(lines, func(a, b scored) int {
+		return cmp.Or(b.score-a.score, strings.Compare(a.m.String(), b.m.String()))
+	})
+
+	for i, l := range lines[:min(multiPV, len(lines))] {
+		score := uci.Score{CP: l.score}
+		switch {
+		case l.score > mateScore-100:
+			score = uci.Score{Mate: (mateScore - l.score + 1) / 2}
+		case l.score < -mateScore+100:
+			score = uci.Score{Mate: -(mateScore + l.score) / 2}
+		}
+		fmt.Printf("info depth %d multipv %d score %v pv %v\n", depth, i+1, score, l.m)
+	}
+	if len(lines) == 0 {
+		fmt.Println("bestmove (none)")
+		return
+	}
+	fmt.Printf("bestmove %v\n", lines[0].m)
+}
+
+// negamax returns the score of p for the side to move, searching depth plies
+// deep at ply plies from the root.
+func negamax(p core.Position, depth, ply int) int {
+	moves := p.LegalMoves()
+	switch {
+	case len(moves) == 0 && p.InCheck():
+		return -mateScore + ply
+	case len(moves) == 0:
+		return 0
+	case depth == 0:
+		return material(p)
+	}
+	best := -2 * mateScore
+	for _, m := range moves {
+		q := p
+		q.Move(m)
+		best = max(best, -negamax(q, depth-1, ply+1))
+	}
+	return best
+}
+
+// material returns the material balance of p in centipawns, for the side to
+// move.
+func material(p core.Position) int {
+	b := p.Board()
+	var score int
+	for pt := core.Pawn; pt < core.King; pt++ {
+		us := b.Bitboard(core.NewPiece(p.SideToMove(), pt)).Count()
+		them := b.Bitboard(core.NewPiece(p.SideToMove().Other(), pt)).Count()
+		score += 100 * values[pt] * (us - them)
+	}
+	return score
+}
+
+func newFinder(t *testing.T, mode string) (*Finder, error) {
+	t.Helper()
+	t.Setenv("LENTO_FAKE_ENGINE", "1")
+
+	c, err := client.Start(context.Background(), os.Args[0], mode)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { c.Close() })
+	return NewFinder(c, Config{Limits: uci.Limits{Depth: 3}})
+}
+
+func parseMoves(t *testing.T, moves ...string) []core.Move {
+	t.Helper()
+	var res []core.Move
+	for _, s := range moves {
+		m, err := core.ParseMove(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res = append(res, m)
+	}
+	return res
+}
+
+func TestFinder_Solve(t *testing.T) {
+	f, err := newFinder(t, "multipv")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name   string
+		fen    string
+		moves  []string // nil if there's no puzzle
+		themes []string
+	}{
+		{
+			name:   "back rank mate",
+			fen:    "6k1/5ppp/8/8/8/8/8/R5K1 w - - 0 1",
+			moves:  []string{"a1a8"},
+			themes: []string{"mateIn1"},
+		},
+		{
+			name:   "knight fork",
+			fen:    "r3k3/8/8/3N4/8/8/8/6K1 w - - 0 1",
+			moves:  []string{"d5c7", "e8d7", "c7a8"},
+			themes: []string{"fork"},
+		},
+		{
+			name: "starting position",
+			fen:  "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1",
+		},
+		{
+			// Both rooks mate.
+			name: "two solutions",
+			fen:  "6k1/5ppp/8/8/8/8/8/RR4K1 w - - 0 1",
+		},
+	}
+	for _, tc := range cases {
+		p := fen.MustDecode(tc.fen)
+		pz, ok, err := f.Solve(context.Background(), p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ok != (tc.moves != nil) {
+			t.Errorf("%s: want puzzle %t, got %t", tc.name, tc.moves != nil, ok)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		if want := parseMoves(t, tc.moves...); !reflect.DeepEqual(want, pz.Moves) {
+			t.Errorf("%s: want moves %v, got %v", tc.name, want, pz.Moves)
+		}
+		if !reflect.DeepEqual(tc.themes, pz.Themes) {
+			t.Errorf("%s: want themes %v, got %v", tc.name, tc.themes, pz.Themes)
+		}
+		if pz.Position != p {
+			t.Errorf("%s: got position %v", tc.name, fen.Encode(pz.Position))
+		}
+	}
+}
+
+func TestFinder_Find(t *testing.T) {
+	f, err := newFinder(t, "multipv")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	g, err := pgn.Decode(`[SetUp "1"]
+[FEN "r3k3/8/8/3N4/8/8/8/6K1 w - - 0 1"]
+
+1. Nc7+ Kd7 2. Nxa8 Kc6 *
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	puzzles, err := f.Find(context.Background(), g)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(puzzles) != 1 {
+		t.Fatalf("want 1 puzzle, got %d", len(puzzles))
+	}
+	if want := parseMoves(t, "d5c7", "e8d7", "c7a8"); !reflect.DeepEqual(want, puzzles[0].Moves) {
+		t.Errorf("want moves %v, got %v", want, puzzles[0].Moves)
+	}
+}
+
+func TestNewFinder_NoMultiPV(t *testing.T) {
+	if _, err := newFinder(t, "single"); err == nil {
+		t.Error("no error")
+	}
+}
+
+func TestThemes(t *testing.T) {
+	cases := []struct {
+		fen   string
+		moves []string
+		want  []string
+	}{
+		{"4k3/8/2n5/8/8/8/8/4KB2 w - - 0 1", []string{"f1b5"}, []string{"pin"}},
+		{"k7/4P3/1K6/8/8/8/8/8 w - - 0 1", []string{"e7e8q"}, []string{"mateIn1", "promotion"}},
+		// The knight attacks the king and rook, but takes neither.
+		{"r3k3/8/8/3N4/8/8/8/6K1 w - - 0 1", []string{"d5c7", "e8d7", "c7b5"}, nil},
+	}
+	for _, tc := range cases {
+		got := Themes(fen.MustDecode(tc.fen), parseMoves(t, tc.moves...))
+		if !reflect.DeepEqual(tc.want, got) {
+			t.Errorf("%s %v: want %v, got %v", tc.fen, tc.moves, tc.want, got)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package render
+
+import "github.com/clfs/lento/core"
+
+type options struct {
+	flip        bool
+	coordinates bool
+	colors      bool
+	highlights  []core.Square
+	arrows      []core.Move
+	squareSize  int
+}
+
+// An Option configures rendering.
+type Option interface {
+	apply(*options)
+}
+
+type flipOption bool
+
+func (f flipOption) apply(opts *options) {
+	opts.flip = bool(f)
+}
+
+// Flip draws the board from Black's side.
+func Flip() Option {
+	return flipOption(true)
+}
+
+type coordinatesOption bool
+
+func (c coordinatesOption) apply(opts *options) {
+	opts.coordinates = bool(c)
+}
+
+// WithCoordinates labels the files and ranks.
+func WithCoordinates() Option {
+	return coordinatesOption(true)
+}
+
+type colorsOption bool
+
+func (c colorsOption) apply(opts *options) {
+	opts.colors = bool(c)
+}
+
+// WithColors colours the squares of [Unicode] boards with ANSI escape
+// sequences. Highlighted squares are only shown with colours.
+func WithColors() Option {
+	return colorsOption(true)
+}
+
+type highlightsOption []core.Square
+
+func (h highlightsOption) apply(opts *options) {
+	opts.highlights = append(opts.highlights, h...)
+}
+
+// WithHighlights highlights squares in [SVG] diagrams and coloured [Unicode]
+// boards.
+func WithHighlights(squares ...core.Square) Option {
+	return highlightsOption(squares)
+}
+
+type arrowsOption []core.Move
+
+func (a arrowsOption) apply(opts *options) {
+	opts.arrows = append(opts.arrows, a...)
+}
+
+// WithArrows draws an arrow for each move in [SVG] diagrams.
+func WithArrows(moves ...core.Move) Option {
+	return arrowsOption(moves)
+}
+
+type squareSizeOption int
+
+func (s squareSizeOption) apply(opts *options) {
+	opts.squareSize = int(s)
+}
+
+// WithSquareSize sets the size of a square in [SVG] diagrams, in pixels. The
+// default is 45.
+func WithSquareSize(px int) Option {
+	return squareSizeOption(px)
+}
+
+func newOptions(opts []Option) options {
+	// Default options.
+	o := options{squareSize: 45}
+
+	// Custom options.
+	for _, opt := range opts {
+		opt.apply(&o)
+	}
+
+	return o
+}
@@ -0,0 +1,140 @@
+// Package render draws chess boards as text and images.
+//
+// [ASCII] and [Unicode] draw boards for terminals and logs, and [SVG] draws
+// standalone diagrams for documents and issue reports. To draw a
+// [core.Position], draw its board.
+package render
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/clfs/lento/core"
+)
+
+// pieceLetters and pieceGlyphs draw pieces, indexed by [core.Piece].
+var (
+	pieceLetters = []string{"P", "N", "B", "R", "Q", "K", "p", "n", "b", "r", "q", "k"}
+	pieceGlyphs  = []string{"♙", "♘", "♗", "♖", "♕", "♔", "♟", "♞", "♝", "♜", "♛", "♚"}
+)
+
+// squares returns the squares in drawing order: rank by rank from the top of
+// the board, and file by file from the left.
+func squares(flip bool) [8][8]core.Square {
+	var res [8][8]core.Square
+	for i := range 8 {
+		for j := range 8 {
+			f, r := core.File(j), core.Rank(7-i)
+			if flip {
+				f, r = core.File(7-j), core.Rank(i)
+			}
+			res[i][j] = core.NewSquare(f, r)
+		}
+	}
+	return res
+}
+
+// isLight returns true if s is a light square.
+func isLight(s core.Square) bool {
+	return (int(s.File())+int(s.Rank()))%2 == 1
+}
+
+// ASCII draws b with a letter for each piece, uppercase for White, and a dot
+// for each empty square.
+func ASCII(b core.Board, opts ...Option) string {
+	return text(b, pieceLetters, ".", newOptions(opts))
+}
+
+// Unicode draws b with chess glyphs, and a middle dot for each empty square.
+// With [WithColors], squares are coloured with ANSI escape sequences.
+func Unicode(b core.Board, opts ...Option) string {
+	o := newOptions(opts)
+	if o.colors {
+		return ansi(b, o)
+	}
+	return text(b, pieceGlyphs, "·", o)
+}
+
+func text(b core.Board, pieces []string, empty string, o options) string {
+	var sb strings.Builder
+	for _, rank := range squares(o.flip) {
+		if o.coordinates {
+			fmt.Fprintf(&sb, "%d ", rank[0].Rank()+1)
+		}
+		for j, s := range rank {
+			if j > 0 {
+				sb.WriteByte(' ')
+			}
+			if p, ok := b.Get(s); ok {
+				sb.WriteString(pieces[p])
+			} else {
+				sb.WriteString(empty)
+			}
+		}
+		sb.WriteByte('\n')
+	}
+	if o.coordinates {
+		sb.WriteString("  " + files(o.flip, " ") + "\n")
+	}
+	return sb.String()
+}
+
+// files returns the file letters in drawing order, joined by sep.
+func files(flip bool, sep string) string {
+	fs := []string{"a", "b", "c", "d", "e", "f", "g", "h"}
+	if flip {
+		slices.Reverse(fs)
+	}
+	return strings.Join(fs, sep)
+}
+
+// ANSI escape sequences for coloured boards, using the 256-colour palette.
+const (
+	ansiReset          = "\x1b[0m"
+	ansiLight          = "\x1b[48;5;223m"
+	ansiDark           = "\x1b[48;5;137m"
+	ansiLightHighlight = "\x1b[48;5;186m"
+	ansiDarkHighlight  = "\x1b[48;5;143m"
+	ansiWhitePiece     = "\x1b[38;5;231m"
+	ansiBlackPiece     = "\x1b[38;5;16m"
+)
+
+func ansi(b core.Board, o options) string {
+	var sb strings.Builder
+	for _, rank := range squares(o.flip) {
+		if o.coordinates {
+			fmt.Fprintf(&sb, "%d ", rank[0].Rank()+1)
+		}
+		for _, s := range rank {
+			highlighted := slices.Contains(o.highlights, s)
+			switch {
+			case isLight(s) && highlighted:
+				sb.WriteString(ansiLightHighlight)
+			case isLight(s):
+				sb.WriteString(ansiLight)
+			case highlighted:
+				sb.WriteString(ansiDarkHighlight)
+			default:
+				sb.WriteString(ansiDark)
+			}
+			// Draw both colours with the solid glyphs, which read better on
+			// coloured squares.
+			glyph := " "
+			if p, ok := b.Get(s); ok {
+				glyph = pieceGlyphs[core.NewPiece(core.Black, p.Type())]
+				if p.Color() == core.White {
+					glyph = ansiWhitePiece + glyph
+				} else {
+					glyph = ansiBlackPiece + glyph
+				}
+			}
+			sb.WriteString(" " + glyph + " ")
+		}
+		sb.WriteString(ansiReset + "\n")
+	}
+	if o.coordinates {
+		sb.WriteString("   " + files(o.flip, "  ") + "\n")
+	}
+	return sb.String()
+}
@@ -0,0 +1,165 @@
+package render
+
+import (
+	"encoding/xml"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/clfs/lento/core"
+	"github.com/clfs/lento/encoding/fen"
+)
+
+func TestASCII(t *testing.T) {
+	want := `8 r n b q k b n r
+7 p p p p p p p p
+6 . . . . . . . .
+5 . . . . . . . .
+4 . . . . . . . .
+3 . . . . . . . .
+2 P P P P P P P P
+1 R N B Q K B N R
+  a b c d e f g h
+`
+	if got := ASCII(core.NewBoard(), WithCoordinates()); want != got {
+		t.Errorf("want:\n%s\ngot:\n%s", want, got)
+	}
+}
+
+func TestUnicode_Flip(t *testing.T) {
+	p := fen.MustDecode("8/8/8/4k3/8/8/1P6/4K2R w K - 0 1")
+	want := `1 ♖ · · ♔ · · · ·
+2 · · · · · · ♙ ·
+3 · · · · · · · ·
+4 · · · · · · · ·
+5 · · · ♚ · · · ·
+6 · · · · · · · ·
+7 · · · · · · · ·
+8 · · · · · · · ·
+  h g f e d c b a
+`
+	if got := Unicode(p.Board(), Flip(), WithCoordinates()); want != got {
+		t.Errorf("want:\n%s\ngot:\n%s", want, got)
+	}
+}
+
+func TestUnicode_Colors(t *testing.T) {
+	got := Unicode(core.NewBoard(), WithColors(), WithHighlights(core.E2, core.E3))
+
+	if n := strings.Count(got, "\n"); n != 8 {
+		t.Errorf("want 8 lines, got %d", n)
+	}
+	if n := strings.Count(got, ansiReset); n != 8 {
+		t.Errorf("want 8 resets, got %d", n)
+	}
+	// e2 is light and e3 is dark.
+	if n := strings.Count(got, ansiLightHighlight); n != 1 {
+		t.Errorf("want 1 light highlight, got %d", n)
+	}
+	if n := strings.Count(got, ansiDarkHighlight); n != 1 {
+		t.Errorf("want 1 dark highlight, got %d", n)
+	}
+	if n := strings.Count(got, ansiWhitePiece); n != 16 {
+		t.Errorf("want 16 white pieces, got %d", n)
+	}
+}
+
+// svgElements parses an SVG diagram, returning its elements.
+func svgElements(t *testing.T, s string) []xml.StartElement {
+	t.Helper()
+	var res []xml.StartElement
+	d := xml.NewDecoder(strings.NewReader(s))
+	for {
+		tok, err := d.Token()
+		if errors.Is(err, io.EOF) {
+			return res
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if e, ok := tok.(xml.StartElement); ok {
+			res = append(res, e)
+		}
+	}
+}
+
+func attr(e xml.StartElement, name string) string {
+	for _, a := range e.Attr {
+		if a.Name.Local == name {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+func TestSVG(t *testing.T) {
+	got := SVG(core.NewBoard(),
+		WithHighlights(core.E2, core.E4),
+		WithArrows(core.NewMove(core.G1, core.F3)),
+	)
+
+	counts := make(map[string]int)
+	var line xml.StartElement
+	for _, e := range svgElements(t, got) {
+		counts[e.Name.Local]++
+		if e.Name.Local == "rect" && attr(e, "fill") == svgHighlight {
+			counts["highlight"]++
+		}
+		if e.Name.Local == "line" {
+			line = e
+		}
+	}
+
+	for name, want := range map[string]int{"svg": 1, "rect": 64, "highlight": 2, "text": 32, "line": 1, "marker": 1} {
+		if counts[name] != want {
+			t.Errorf("want %d %s, got %d", want, name, counts[name])
+		}
+	}
+
+	// The arrow starts at the centre of g1.
+	if x, y := attr(line, "x1"), attr(line, "y1"); x != "292.5" || y != "337.5" {
+		t.Errorf("want arrow from (292.5, 337.5), got (%s, %s)", x, y)
+	}
+}
+
+func TestSVG_CoordinatesAndFlip(t *testing.T) {
+	got := SVG(core.NewBoard(), WithCoordinates(), Flip(), WithSquareSize(10))
+
+	var svg, first xml.StartElement
+	var labels []string
+	d := xml.NewDecoder(strings.NewReader(got))
+	for {
+		tok, err := d.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		switch e := tok.(type) {
+		case xml.StartElement:
+			switch {
+			case e.Name.Local == "svg":
+				svg = e
+			case e.Name.Local == "rect" && attr(e, "x") == "5" && attr(e, "y") == "5":
+				first = e
+			}
+		case xml.CharData:
+			if s := strings.TrimSpace(string(e)); len(s) == 1 && s[0] < 0x80 {
+				labels = append(labels, s)
+			}
+		}
+	}
+
+	if w := attr(svg, "width"); w != "90" {
+		t.Errorf("want width 90, got %s", w)
+	}
+	// h1 is in the top left corner when flipped, and is light.
+	if fill := attr(first, "fill"); fill != svgLight {
+		t.Errorf("want light top left square, got %q", fill)
+	}
+	if got, want := strings.Join(labels, ""), "h1g2f3e4d5c6b7a8"; want != got {
+		t.Errorf("want labels %q, got %q", want, got)
+	}
+}
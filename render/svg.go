@@ -0,0 +1,118 @@
+package render
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+
+	"github.com/clfs/lento/core"
+)
+
+// Colours for SVG diagrams.
+const (
+	svgLight     = "#f0d9b5"
+	svgDark      = "#b58863"
+	svgHighlight = "#cdd26a"
+	svgArrow     = "#15781b"
+	svgLabel     = "#333333"
+)
+
+// SVG draws b as a standalone SVG diagram. Pieces are drawn as chess glyphs
+// in the viewer's fonts.
+func SVG(b core.Board, opts ...Option) string {
+	o := newOptions(opts)
+	size := float64(o.squareSize)
+
+	// With coordinates, leave a margin of half a square on each side.
+	margin := 0.0
+	if o.coordinates {
+		margin = size / 2
+	}
+	total := 8*size + 2*margin
+
+	// center returns the centre of s, in diagram coordinates.
+	center := func(s core.Square) (float64, float64) {
+		f, r := float64(s.File()), float64(7-s.Rank())
+		if o.flip {
+			f, r = 7-f, 7-r
+		}
+		return margin + (f+0.5)*size, margin + (r+0.5)*size
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, `<svg xmlns="http://www.w3.org/2000/svg" width="%g" height="%g" viewBox="0 0 %g %g">`+"\n",
+		total, total, total, total)
+
+	if len(o.arrows) > 0 {
+		fmt.Fprintf(&sb, `<defs><marker id="arrowhead" viewBox="0 0 4 4" refX="2" refY="2" markerWidth="4" markerHeight="4" orient="auto"><path d="M0,0 L4,2 L0,4 Z" fill="%s"/></marker></defs>`+"\n", svgArrow)
+	}
+
+	if o.coordinates {
+		fmt.Fprintf(&sb, `<rect width="%g" height="%g" fill="#ffffff"/>`+"\n", total, total)
+	}
+
+	for _, rank := range squares(o.flip) {
+		for _, s := range rank {
+			x, y := center(s)
+			fill := svgDark
+			switch {
+			case slices.Contains(o.highlights, s):
+				fill = svgHighlight
+			case isLight(s):
+				fill = svgLight
+			}
+			fmt.Fprintf(&sb, `<rect x="%g" y="%g" width="%g" height="%g" fill="%s"/>`+"\n",
+				x-size/2, y-size/2, size, size, fill)
+		}
+	}
+
+	if o.coordinates {
+		fs := files(o.flip, "")
+		for i := range 8 {
+			fmt.Fprintf(&sb, `<text x="%g" y="%g" font-size="%g" text-anchor="middle" dominant-baseline="central" font-family="sans-serif" fill="%s">%c</text>`+"\n",
+				margin+(float64(i)+0.5)*size, total-margin/2, size/3, svgLabel, fs[i])
+			r := 8 - i
+			if o.flip {
+				r = i + 1
+			}
+			fmt.Fprintf(&sb, `<text x="%g" y="%g" font-size="%g" text-anchor="middle" dominant-baseline="central" font-family="sans-serif" fill="%s">%d</text>`+"\n",
+				margin/2, margin+(float64(i)+0.5)*size, size/3, svgLabel, r)
+		}
+	}
+
+	for _, rank := range squares(o.flip) {
+		for _, s := range rank {
+			p, ok := b.Get(s)
+			if !ok {
+				continue
+			}
+			x, y := center(s)
+			// Both colours use the solid glyph, filled and outlined to tell
+			// them apart.
+			fill, stroke := "#000000", "#000000"
+			if p.Color() == core.White {
+				fill = "#ffffff"
+			}
+			fmt.Fprintf(&sb, `<text x="%g" y="%g" font-size="%g" text-anchor="middle" dominant-baseline="central" font-family="DejaVu Sans, Segoe UI Symbol, sans-serif" fill="%s" stroke="%s" stroke-width="%g">%s</text>`+"\n",
+				x, y, size*0.8, fill, stroke, size/45, pieceGlyphs[core.NewPiece(core.Black, p.Type())])
+		}
+	}
+
+	for _, m := range o.arrows {
+		x1, y1 := center(m.From())
+		x2, y2 := center(m.To())
+		// Stop short of the centre of the target square, so the tip of the
+		// arrowhead lands there.
+		if d := math.Hypot(x2-x1, y2-y1); d > 0 {
+			back := size / 3
+			x2 -= (x2 - x1) / d * back
+			y2 -= (y2 - y1) / d * back
+		}
+		fmt.Fprintf(&sb, `<line x1="%g" y1="%g" x2="%g" y2="%g" stroke="%s" stroke-width="%g" stroke-opacity="0.8" stroke-linecap="round" marker-end="url(#arrowhead)"/>`+"\n",
+			x1, y1, x2, y2, svgArrow, size/6)
+	}
+
+	sb.WriteString("</svg>\n")
+	return sb.String()
+}
@@ -0,0 +1,302 @@
+// Package solver proves and disproves forced wins with proof-number search.
+//
+// Unlike a search guided by an evaluation, proof-number search only answers
+// whether the side to move can force a win, and its answers are exact: a
+// proven win comes with a line of best play for both sides, and a disproof
+// means that there is no forced win within the ply limit. The search grows the
+// tree toward the positions that are cheapest to prove or refute, where the
+// defender has the fewest replies, which suits forcing sequences like long
+// chains of checks.
+//
+// A win is a checkmate, or a win by the position's variant rules, like a
+// third check in [core.ThreeCheck] or having no pieces left in
+// [core.Antichess]. Draws by repetition and the fifty-move rule are not
+// considered.
+package solver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/clfs/lento/core"
+)
+
+// A Config configures [Solve].
+type Config struct {
+	// MaxPly is the length of the longest win to look for, in plies. A mate
+	// in n moves is 2n-1 plies long.
+	MaxPly int
+	// MaxNodes, if positive, bounds the number of positions searched. If the
+	// search reaches it, the outcome is [Unknown].
+	MaxNodes int
+}
+
+// An Outcome is the answer to whether the side to move can force a win.
+type Outcome uint8
+
+// [Outcome] constants.
+const (
+	// Unknown means the search ran out of nodes before finding the answer.
+	Unknown Outcome = iota
+	// Proven means the side to move can force a win.
+	Proven
+	// Disproven means the side to move can't force a win within the ply
+	// limit.
+	Disproven
+)
+
+// String returns the outcome's name, like "proven".
+func (o Outcome) String() string {
+	switch o {
+	case Proven:
+		return "proven"
+	case Disproven:
+		return "disproven"
+	default:
+		return "unknown"
+	}
+}
+
+// A Result is the result of [Solve].
+type Result struct {
+	Outcome Outcome
+	// Line is the shortest forced win, if proven, where the defender resists
+	// for as long as possible.
+	Line []core.Move
+	// Nodes is the number of positions searched.
+	Nodes int
+}
+
+// Solve searches for a forced win for the side to move in p.
+//
+// It tries each ply limit up to cfg.MaxPly in turn, so that a proven win is
+// also the shortest. If ctx is done before the search ends, Solve returns
+// ctx's error.
+func Solve(ctx context.Context, p core.Position, cfg Config) (Result, error) {
+	if cfg.MaxPly <= 0 {
+		return Result{}, fmt.Errorf("bad max ply: %d", cfg.MaxPly)
+	}
+
+	s := &search{
+		ctx:      ctx,
+		attacker: p.SideToMove(),
+		maxNodes: cfg.MaxNodes,
+	}
+	s.win = core.WhiteWins
+	if s.attacker == core.Black {
+		s.win = core.BlackWins
+	}
+
+	// Wins come on the attacker's own moves, so only odd limits need to be
+	// tried, except in Antichess, where the defender can be forced to take
+	// the attacker's last piece.
+	step := 2
+	if p.Variant() == core.Antichess {
+		step = 1
+	}
+
+	for s.maxPly = 1; s.maxPly <= cfg.MaxPly; s.maxPly += step {
+		root := s.newNode(nil, p, core.Move{}, 0)
+		if err := s.run(root); err != nil {
+			if err == errNodeLimit {
+				return Result{Outcome: Unknown, Nodes: s.nodes}, nil
+			}
+			return Result{}, err
+		}
+		if root.pn == 0 {
+			return Result{Outcome: Proven, Line: root.line(), Nodes: s.nodes}, nil
+		}
+	}
+	return Result{Outcome: Disproven, Nodes: s.nodes}, nil
+}
+
+// infinity is the proof or disproof number of a node that can't be proven or
+// disproven.
+const infinity = math.MaxInt32
+
+// A node is a position in the search tree.
+type node struct {
+	p        core.Position
+	move     core.Move // the move that led here
+	ply      int
+	attacker bool // whether the attacker is to move
+	// The proof number is the least number of leaves that must be proven to
+	// prove this node, and the disproof number is the least number that must
+	// be disproven to disprove it.
+	pn, dn   int
+	parent   *node
+	children []*node // nil until expanded
+}
+
+// line returns the moves from n to the end of the shortest win, where the
+// defender picks the longest. n must be proven.
+func (n *node) line() []core.Move {
+	var res []core.Move
+	for n.children != nil {
+		var next *node
+		for _, c := range n.children {
+			if c.pn != 0 {
+				continue
+			}
+			if next == nil ||
+				n.attacker && c.distance() < next.distance() ||
+				!n.attacker && c.distance() > next.distance() {
+				next = c
+			}
+		}
+		res = append(res, next.move)
+		n = next
+	}
+	return res
+}
+
+// distance returns the number of plies from n to the end of the shortest
+// win, where the defender picks the longest. n must be proven.
+func (n *node) distance() int {
+	if n.children == nil {
+		return 0
+	}
+	d := -1
+	for _, c := range n.children {
+		if c.pn != 0 {
+			continue
+		}
+		cd := 1 + c.distance()
+		if d < 0 || n.attacker && cd < d || !n.attacker && cd > d {
+			d = cd
+		}
+	}
+	return d
+}
+
+// errNodeLimit is returned by [search.run] when the node limit is reached.
+var errNodeLimit = errors.New("node limit reached")
+
+type search struct {
+	ctx      context.Context
+	attacker core.Color
+	win      core.Result // the attacker's win
+	maxPly   int
+	maxNodes int
+	nodes    int
+}
+
+// run searches until root is proven or disproven.
+func (s *search) run(root *node) error {
+	for i := 0; root.pn != 0 && root.dn != 0; i++ {
+		if i%1024 == 0 {
+			if err := s.ctx.Err(); err != nil {
+				return err
+			}
+		}
+		if s.maxNodes > 0 && s.nodes >= s.maxNodes {
+			return errNodeLimit
+		}
+
+		n := mostProving(root)
+		s.expand(n)
+		for ; n != nil; n = n.parent {
+			n.update()
+		}
+	}
+	return nil
+}
+
+// newNode returns a new leaf for p, reached from parent by m.
+func (s *search) newNode(parent *node, p core.Position, m core.Move, ply int) *node {
+	s.nodes++
+	n := &node{
+		p:        p,
+		move:     m,
+		ply:      ply,
+		attacker: p.SideToMove() == s.attacker,
+		parent:   parent,
+	}
+
+	// Leaves start with the number of moves the side to move can choose
+	// from, so that positions with few replies are explored first.
+	moves := len(p.LegalMoves())
+	switch r := result(&p, moves); {
+	case r == s.win:
+		n.pn, n.dn = 0, infinity
+	case r != core.NoResult || ply >= s.maxPly:
+		n.pn, n.dn = infinity, 0
+	case n.attacker:
+		n.pn, n.dn = 1, moves
+	default:
+		n.pn, n.dn = moves, 1
+	}
+	return n
+}
+
+// result returns the result of the game at p, which has the given number of
+// legal moves, or [core.NoResult] if it isn't over.
+func result(p *core.Position, moves int) core.Result {
+	if r := p.VariantResult(); r != core.NoResult {
+		return r
+	}
+	switch {
+	case moves > 0:
+		return core.NoResult
+	case !p.InCheck():
+		return core.Draw
+	case p.SideToMove() == core.White:
+		return core.BlackWins
+	default:
+		return core.WhiteWins
+	}
+}
+
+// mostProving returns the leaf below n that would most help to prove or
+// disprove it.
+func mostProving(n *node) *node {
+	for n.children != nil {
+		next := n.children[0]
+		for _, c := range n.children[1:] {
+			if n.attacker && c.pn < next.pn || !n.attacker && c.dn < next.dn {
+				next = c
+			}
+		}
+		n = next
+	}
+	return n
+}
+
+// expand adds n's children.
+func (s *search) expand(n *node) {
+	moves := n.p.LegalMoves()
+	n.children = make([]*node, 0, len(moves))
+	for _, m := range moves {
+		q := n.p
+		q.Move(m)
+		n.children = append(n.children, s.newNode(n, q, m, n.ply+1))
+	}
+}
+
+// update recomputes n's proof and disproof numbers from its children. Once n
+// is disproven, its children are dropped, since they are no longer needed.
+func (n *node) update() {
+	if n.children == nil {
+		return
+	}
+
+	n.pn, n.dn = infinity, 0
+	if !n.attacker {
+		n.pn, n.dn = 0, infinity
+	}
+	for _, c := range n.children {
+		if n.attacker {
+			n.pn = min(n.pn, c.pn)
+			n.dn = min(n.dn+c.dn, infinity)
+		} else {
+			n.pn = min(n.pn+c.pn, infinity)
+			n.dn = min(n.dn, c.dn)
+		}
+	}
+
+	if n.dn == 0 {
+		n.children = nil
+	}
+}
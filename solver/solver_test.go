@@ -0,0 +1,130 @@
+package solver
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/clfs/lento/core"
+	"github.com/clfs/lento/encoding/fen"
+)
+
+func parseMoves(t *testing.T, moves ...string) []core.Move {
+	t.Helper()
+	var res []core.Move
+	for _, s := range moves {
+		m, err := core.ParseMove(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res = append(res, m)
+	}
+	return res
+}
+
+func TestSolve(t *testing.T) {
+	cases := []struct {
+		name    string
+		variant core.Variant
+		fen     string
+		maxPly  int
+		want    []string
+	}{
+		{
+			name:   "mate in 1",
+			fen:    "6k1/5ppp/8/8/8/8/8/R5K1 w - - 0 1",
+			maxPly: 5,
+			want:   []string{"a1a8"},
+		},
+		{
+			name:   "mate in 2",
+			fen:    "r2qkb1r/pp2nppp/3p4/2pNN1B1/2BnP3/3P4/PPP2PPP/R2bK2R w KQkq - 1 1",
+			maxPly: 3,
+			want:   []string{"d5f6", "g7f6", "c4f7"},
+		},
+		{
+			// Philidor's legacy: a smothered mate after a chain of checks.
+			// Black prefers 2... Kh8 to 2... Kf8 3. Qf7#.
+			name:   "mate in 4",
+			fen:    "r6k/6pp/8/6N1/2Q5/8/8/7K w - - 0 1",
+			maxPly: 9,
+			want:   []string{"g5f7", "h8g8", "f7h6", "g8h8", "c4g8", "a8g8", "h6f7"},
+		},
+		{
+			name:    "third check",
+			variant: core.ThreeCheck,
+			fen:     "4k3/8/8/8/8/8/8/R3K3 w - - 0 1 +2+0",
+			maxPly:  5,
+			want:    []string{"a1a8"},
+		},
+		{
+			// The defender is forced to take White's last piece.
+			name:    "antichess",
+			variant: core.Antichess,
+			fen:     "8/8/8/1p6/8/P7/8/8 w - - 0 1",
+			maxPly:  4,
+			want:    []string{"a3a4", "b5a4"},
+		},
+	}
+	for _, tc := range cases {
+		p, err := fen.DecodeVariant(tc.fen, tc.variant)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res, err := Solve(context.Background(), p, Config{MaxPly: tc.maxPly})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.Outcome != Proven {
+			t.Errorf("%s: want proven, got %v", tc.name, res.Outcome)
+			continue
+		}
+		if want := parseMoves(t, tc.want...); !slices.Equal(want, res.Line) {
+			t.Errorf("%s: want %v, got %v", tc.name, want, res.Line)
+		}
+	}
+}
+
+func TestSolve_Disproven(t *testing.T) {
+	cases := []struct {
+		name   string
+		fen    string
+		maxPly int
+	}{
+		{"too far", "4k3/8/8/8/8/8/8/4K2R w K - 0 1", 3},
+		// Every check lets the king escape to b8.
+		{"escape", "k7/8/1K6/8/8/8/8/1Q6 w - - 0 1", 1},
+		{"losing side", "6k1/5ppp/8/8/8/8/8/R5K1 b - - 0 1", 3},
+	}
+	for _, tc := range cases {
+		res, err := Solve(context.Background(), fen.MustDecode(tc.fen), Config{MaxPly: tc.maxPly})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.Outcome != Disproven || res.Line != nil {
+			t.Errorf("%s: want disproven, got %v %v", tc.name, res.Outcome, res.Line)
+		}
+	}
+}
+
+func TestSolve_Limits(t *testing.T) {
+	p := fen.MustDecode("r6k/6pp/8/6N1/2Q5/8/8/7K w - - 0 1")
+
+	res, err := Solve(context.Background(), p, Config{MaxPly: 9, MaxNodes: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Outcome != Unknown {
+		t.Errorf("node limit: want unknown, got %v", res.Outcome)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := Solve(ctx, p, Config{MaxPly: 9}); err != context.Canceled {
+		t.Errorf("canceled: want %v, got %v", context.Canceled, err)
+	}
+
+	if _, err := Solve(context.Background(), p, Config{}); err == nil {
+		t.Error("no max ply: no error")
+	}
+}
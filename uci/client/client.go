@@ -0,0 +1,263 @@
+// Package client drives UCI engines.
+//
+// A [Client] starts an engine process, completes the UCI handshake, and
+// exposes the engine's options, positions and searches through typed APIs.
+// Search information is streamed as parsed [uci.Info] values.
+package client
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/clfs/lento/core"
+	"github.com/clfs/lento/encoding/fen"
+	"github.com/clfs/lento/uci"
+)
+
+// ErrExited is returned when the engine process exits unexpectedly.
+var ErrExited = errors.New("engine exited")
+
+// A Client is a connection to a running UCI engine.
+//
+// A Client runs at most one search at a time. Its methods are not safe for
+// concurrent use, except for [Client.Close].
+type Client struct {
+	cmd   *exec.Cmd
+	stdin io.WriteCloser
+	lines chan string // closed when the engine's stdout is closed
+
+	name    string
+	author  string
+	options []uci.Option
+
+	closeOnce sync.Once
+	search    *Search // the current or most recent search
+}
+
+// Start starts an engine and completes the UCI handshake. The context only
+// bounds the handshake; use [Client.Close] to stop the engine.
+func Start(ctx context.Context, path string, args ...string) (*Client, error) {
+	cmd := exec.Command(path, args...)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	c := &Client{
+		cmd:   cmd,
+		stdin: stdin,
+		lines: make(chan string),
+	}
+
+	go func() {
+		defer close(c.lines)
+		s := bufio.NewScanner(stdout)
+		for s.Scan() {
+			c.lines <- strings.TrimSpace(s.Text())
+		}
+	}()
+
+	if err := c.handshake(ctx); err != nil {
+		c.Close()
+		return nil, err
+	}
+
+	return c, nil
+}
+
+func (c *Client) handshake(ctx context.Context) error {
+	if err := c.send("uci"); err != nil {
+		return err
+	}
+	return c.waitFor(ctx, func(line string) bool {
+		cmd, rest, _ := strings.Cut(line, " ")
+		switch cmd {
+		case "id":
+			key, value, _ := strings.Cut(rest, " ")
+			switch key {
+			case "name":
+				c.name = value
+			case "author":
+				c.author = value
+			}
+		case "option":
+			if o, err := uci.ParseOption(line); err == nil {
+				c.options = append(c.options, o)
+			}
+		}
+		return line == "uciok"
+	})
+}
+
+// Name returns the engine's name, as reported by "id name".
+func (c *Client) Name() string {
+	return c.name
+}
+
+// Author returns the engine's author, as reported by "id author".
+func (c *Client) Author() string {
+	return c.author
+}
+
+// Options returns the options the engine reported during the handshake.
+func (c *Client) Options() []uci.Option {
+	return append([]uci.Option(nil), c.options...)
+}
+
+// Option returns the option with the given name, if any. Names are matched
+// case-insensitively, as in UCI.
+func (c *Client) Option(name string) (uci.Option, bool) {
+	for _, o := range c.options {
+		if strings.EqualFold(o.Name, name) {
+			return o, true
+		}
+	}
+	return uci.Option{}, false
+}
+
+// SetOption sets an option. For button options, value is ignored.
+//
+// SetOption returns an error if the engine didn't report the option or the
+// value is invalid for it.
+func (c *Client) SetOption(name, value string) error {
+	if err := c.idle(); err != nil {
+		return err
+	}
+	o, ok := c.Option(name)
+	if !ok {
+		return fmt.Errorf("unknown option: %q", name)
+	}
+	if err := o.Validate(value); err != nil {
+		return err
+	}
+	if o.Type == uci.Button {
+		return c.send("setoption name %s", o.Name)
+	}
+	return c.send("setoption name %s value %s", o.Name, value)
+}
+
+// IsReady waits for the engine to finish processing previous commands.
+func (c *Client) IsReady(ctx context.Context) error {
+	if err := c.idle(); err != nil {
+		return err
+	}
+	if err := c.send("isready"); err != nil {
+		return err
+	}
+	return c.waitFor(ctx, func(line string) bool {
+		return line == "readyok"
+	})
+}
+
+// NewGame tells the engine that following positions are from a new game, then
+// waits for it to be ready.
+func (c *Client) NewGame(ctx context.Context) error {
+	if err := c.idle(); err != nil {
+		return err
+	}
+	if err := c.send("ucinewgame"); err != nil {
+		return err
+	}
+	return c.IsReady(ctx)
+}
+
+// SetPosition sets the position to search: start, followed by moves.
+func (c *Client) SetPosition(start core.Position, moves []core.Move) error {
+	if err := c.idle(); err != nil {
+		return err
+	}
+	var sb strings.Builder
+	if start == core.NewPosition(core.WithVariant(start.Variant())) {
+		sb.WriteString("position startpos")
+	} else {
+		fmt.Fprintf(&sb, "position fen %s", fen.Encode(start))
+	}
+	if len(moves) > 0 {
+		sb.WriteString(" moves")
+		for _, m := range moves {
+			fmt.Fprintf(&sb, " %v", m)
+		}
+	}
+	return c.send("%s", sb.String())
+}
+
+// quitTimeout bounds how long [Client.Close] waits for the engine to quit
+// before killing it.
+const quitTimeout = 5 * time.Second
+
+// Close asks the engine to quit, killing it if it doesn't quit in time. Any
+// running search ends with [ErrExited].
+func (c *Client) Close() error {
+	var err error
+	c.closeOnce.Do(func() {
+		c.send("quit")
+		c.stdin.Close()
+
+		drained := make(chan struct{})
+		go func() {
+			for range c.lines {
+				// Drain output so the engine can exit.
+			}
+			close(drained)
+		}()
+
+		select {
+		case <-drained:
+			err = c.cmd.Wait()
+		case <-time.After(quitTimeout):
+			c.cmd.Process.Kill()
+			<-drained
+			c.cmd.Wait()
+		}
+	})
+	return err
+}
+
+func (c *Client) send(format string, args ...any) error {
+	_, err := fmt.Fprintf(c.stdin, format+"\n", args...)
+	return err
+}
+
+// idle returns an error if a search is running. Commands other than those of
+// [Search] can't be sent until it ends.
+func (c *Client) idle() error {
+	if c.search != nil {
+		select {
+		case <-c.search.done:
+		default:
+			return errors.New("search in progress")
+		}
+	}
+	return nil
+}
+
+// waitFor reads lines until done returns true.
+func (c *Client) waitFor(ctx context.Context, done func(line string) bool) error {
+	for {
+		select {
+		case line, ok := <-c.lines:
+			if !ok {
+				return ErrExited
+			}
+			if done(line) {
+				return nil
+			}
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,310 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"maps"
+	"os"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/clfs/lento/core"
+	"github.com/clfs/lento/encoding/fen"
+	"github.com/clfs/lento/uci"
+)
+
+// If LENTO_FAKE_ENGINE is set, the test binary acts as a UCI engine instead.
+func TestMain(m *testing.M) {
+	if os.Getenv("LENTO_FAKE_ENGINE") != "" {
+		fakeEngine()
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// fakeEngine is a scripted UCI engine. It searches by reporting the first
+// legal moves as its principal variation, and reports the options it was
+// given with "info string".
+func fakeEngine() {
+	var (
+		p       = core.NewPosition()
+		options = make(map[string]struct{})
+		stop    = make(chan struct{}, 1)
+	)
+
+	s := bufio.NewScanner(os.Stdin)
+	for s.Scan() {
+		line := s.Text()
+		cmd, rest, _ := strings.Cut(line, " ")
+		switch cmd {
+		case "uci":
+			fmt.Print(`id name Fake Engine
+id author Lento Authors
+option name Hash type spin default 16 min 1 max 1024
+option name Ponder type check default false
+option name Style type combo default Normal var Solid var Normal var Risky
+option name Clear Hash type button
+option name Book File type string default <empty>
+option name Use Default Book type check default true
+uciok
+`)
+		case "setoption":
+			name, value, ok := strings.Cut(strings.TrimPrefix(rest, "name "), " value ")
+			if ok {
+				name += "=" + value
+			}
+			options[name] = struct{}{}
+		case "isready":
+			fmt.Println("readyok")
+		case "position":
+			p = fakePosition(strings.Fields(rest))
+		case "go":
+			names := slices.Sorted(maps.Keys(options))
+			go fakeSearch(p, names, strings.Contains(rest, "infinite"), stop)
+		case "stop":
+			select {
+			case stop <- struct{}{}:
+			default:
+			}
+		case "quit":
+			return
+		}
+	}
+}
+
+func fakePosition(args []string) core.Position {
+	var p core.Position
+	if args[0] == "startpos" {
+		p, args = core.NewPosition(), args[1:]
+	} else {
+		p, args = fen.MustDecode(strings.Join(args[1:7], " ")), args[7:]
+	}
+	if len(args) > 0 && args[0] == "moves" {
+		for _, s := range args[1:] {
+			m, err := core.ParseMove(s)
+			if err != nil {
+				panic(err)
+			}
+			p.Move(m)
+		}
+	}
+	return p
+}
+
+func fakeSearch(p core.Position, options []string, infinite bool, stop <-chan struct{}) {
+	fmt.Printf("info string options %s\n", strings.Join(options, ","))
+
+	var pv []string
+	q := p
+	for depth := 1; depth <= 3; depth++ {
+		m := q.LegalMoves()[0]
+		pv = append(pv, m.String())
+		q.Move(m)
+		fmt.Printf("info depth %d seldepth %d score cp %d nodes %d time %d pv %s\n",
+			depth, depth+1, 10*depth, 100*depth, depth, strings.Join(pv, " "))
+	}
+
+	if infinite {
+		<-stop
+	}
+	fmt.Printf("bestmove %s ponder %s\n", pv[0], pv[1])
+}
+
+func startFake(t *testing.T) *Client {
+	t.Helper()
+	t.Setenv("LENTO_FAKE_ENGINE", "1")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	c, err := Start(ctx, os.Args[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := c.Close(); err != nil {
+			t.Error(err)
+		}
+	})
+	return c
+}
+
+func TestStart(t *testing.T) {
+	c := startFake(t)
+
+	if got := c.Name(); got != "Fake Engine" {
+		t.Errorf("name: got %q", got)
+	}
+	if got := c.Author(); got != "Lento Authors" {
+		t.Errorf("author: got %q", got)
+	}
+
+	want := []uci.Option{
+		{Name: "Hash", Type: uci.Spin, Default: "16", Min: 1, Max: 1024},
+		{Name: "Ponder", Type: uci.Check, Default: "false"},
+		{Name: "Style", Type: uci.Combo, Default: "Normal", Vars: []string{"Solid", "Normal", "Risky"}},
+		{Name: "Clear Hash", Type: uci.Button},
+		{Name: "Book File", Type: uci.String},
+		{Name: "Use Default Book", Type: uci.Check, Default: "true"},
+	}
+	if got := c.Options(); !reflect.DeepEqual(want, got) {
+		t.Errorf("options:\nwant %+v\ngot  %+v", want, got)
+	}
+}
+
+func TestSetOption(t *testing.T) {
+	c := startFake(t)
+
+	for _, o := range [][2]string{
+		{"hash", "64"},
+		{"Style", "risky"},
+		{"Clear Hash", ""},
+		{"Book File", "/tmp/book.bin"},
+	} {
+		if err := c.SetOption(o[0], o[1]); err != nil {
+			t.Errorf("%v: %v", o, err)
+		}
+	}
+
+	for _, o := range [][2]string{
+		{"Threads", "2"},
+		{"Hash", "0"},
+		{"Hash", "x"},
+		{"Ponder", "yes"},
+		{"Style", "Wild"},
+	} {
+		if err := c.SetOption(o[0], o[1]); err == nil {
+			t.Errorf("%v: no error", o)
+		}
+	}
+
+	ctx := context.Background()
+	if err := c.IsReady(ctx); err != nil {
+		t.Fatal(err)
+	}
+	s, err := c.Go(ctx, uci.Limits{Depth: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	first := <-s.Info
+	want := "options Book File=/tmp/book.bin,Clear Hash,Hash=64,Style=risky"
+	if first.String != want {
+		t.Errorf("want %q, got %q", want, first.String)
+	}
+	if _, err := s.Wait(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGo(t *testing.T) {
+	c := startFake(t)
+	ctx := context.Background()
+
+	if err := c.NewGame(ctx); err != nil {
+		t.Fatal(err)
+	}
+	start := core.NewPosition()
+	moves := []core.Move{core.NewMove(core.E2, core.E4)}
+	if err := c.SetPosition(start, moves); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := c.Go(ctx, uci.Limits{WTime: time.Minute, BTime: time.Minute})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var infos []uci.Info
+	for info := range s.Info {
+		infos = append(infos, info)
+	}
+	res, err := s.Wait()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n := len(infos); n != 4 {
+		t.Fatalf("want 4 infos, got %d", n)
+	}
+	last := infos[3]
+	if last.Depth != 3 || last.SelDepth != 4 || last.Nodes != 300 || last.Time != 3*time.Millisecond {
+		t.Errorf("bad info: %+v", last)
+	}
+	if last.Score == nil || *last.Score != (uci.Score{CP: 30}) {
+		t.Errorf("bad score: %+v", last.Score)
+	}
+
+	// The PV must be legal from the searched position.
+	p := start
+	p.Move(moves[0])
+	for _, m := range last.PV {
+		if !p.IsLegal(m) {
+			t.Fatalf("illegal PV move %v in %v", m, last.PV)
+		}
+		p.Move(m)
+	}
+
+	if res.BestMove != last.PV[0] || res.Ponder != last.PV[1] {
+		t.Errorf("want %v ponder %v, got %+v", last.PV[0], last.PV[1], res)
+	}
+}
+
+func TestGo_Cancel(t *testing.T) {
+	c := startFake(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s, err := c.Go(ctx, uci.Limits{Infinite: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	<-s.Info
+	if _, err := c.Go(context.Background(), uci.Limits{Depth: 1}); err == nil {
+		t.Error("started a second search")
+	}
+	if err := c.IsReady(context.Background()); err == nil {
+		t.Error("isready during search")
+	}
+
+	cancel()
+	res, err := s.Wait()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.BestMove == (core.Move{}) {
+		t.Error("no best move")
+	}
+
+	// The client is usable again.
+	if err := c.IsReady(context.Background()); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestGo_CancelWithoutDraining(t *testing.T) {
+	c := startFake(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s, err := c.Go(ctx, uci.Limits{Infinite: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Nobody reads Info, so the search blocks until it's canceled.
+	cancel()
+	select {
+	case <-s.done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("search didn't end")
+	}
+	if s.err != nil {
+		t.Fatal(s.err)
+	}
+	if err := c.IsReady(context.Background()); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,113 @@
+package client
+
+import (
+	"context"
+	"strings"
+
+	"github.com/clfs/lento/uci"
+)
+
+// A Search is a running search.
+type Search struct {
+	// Info receives information as the engine reports it, and is closed when
+	// the search ends. It must be drained, or the search can't end; use
+	// [Search.Wait] to discard information. Once the search's context is
+	// canceled, information is discarded instead.
+	Info <-chan uci.Info
+
+	client *Client
+	done   chan struct{} // closed after Info is closed
+	result uci.Result
+	err    error
+}
+
+// Go starts a search of the current position.
+//
+// If ctx is canceled, the search is stopped as if by [Search.Stop].
+func (c *Client) Go(ctx context.Context, l uci.Limits) (*Search, error) {
+	if err := c.idle(); err != nil {
+		return nil, err
+	}
+
+	if err := c.send("%v", l); err != nil {
+		return nil, err
+	}
+
+	info := make(chan uci.Info)
+	s := &Search{
+		Info:   info,
+		client: c,
+		done:   make(chan struct{}),
+	}
+	c.search = s
+
+	go func() {
+		defer close(s.done)
+		defer close(info)
+		s.result, s.err = s.run(ctx, info)
+	}()
+
+	return s, nil
+}
+
+func (s *Search) run(ctx context.Context, info chan<- uci.Info) (uci.Result, error) {
+	canceled := ctx.Done()
+	stopped := false
+	for {
+		var (
+			line string
+			ok   bool
+		)
+		select {
+		case line, ok = <-s.client.lines:
+		case <-canceled:
+			s.client.send("stop")
+			canceled, stopped = nil, true // Keep reading until the engine reports its best move.
+			continue
+		}
+		if !ok {
+			return uci.Result{}, ErrExited
+		}
+
+		cmd, _, _ := strings.Cut(line, " ")
+		switch cmd {
+		case "info":
+			i, err := uci.ParseInfo(line)
+			if err != nil {
+				continue // Tolerate malformed info.
+			}
+			if stopped {
+				continue // Discard information after cancellation.
+			}
+			select {
+			case info <- i:
+			case <-canceled:
+				s.client.send("stop")
+				canceled, stopped = nil, true
+			}
+		case "bestmove":
+			return uci.ParseResult(line)
+		}
+	}
+}
+
+// Stop asks the engine to end the search as soon as possible.
+func (s *Search) Stop() error {
+	return s.client.send("stop")
+}
+
+// PonderHit tells the engine that the opponent played the expected move,
+// turning a ponder search into a normal search.
+func (s *Search) PonderHit() error {
+	return s.client.send("ponderhit")
+}
+
+// Wait waits for the search to end, discarding any unread information, and
+// returns its result.
+func (s *Search) Wait() (uci.Result, error) {
+	for range s.Info {
+		// Discard.
+	}
+	<-s.done
+	return s.result, s.err
+}
@@ -0,0 +1,146 @@
+package uci
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// An OptionType is the type of an engine option.
+type OptionType string
+
+// [OptionType] constants.
+const (
+	Check  OptionType = "check"
+	Spin   OptionType = "spin"
+	Combo  OptionType = "combo"
+	Button OptionType = "button"
+	String OptionType = "string"
+)
+
+// An Option is an engine option, as reported with "option".
+type Option struct {
+	Name    string
+	Type    OptionType
+	Default string
+	// Min and Max bound spin options.
+	Min, Max int
+	// Vars are the allowed values of combo options.
+	Vars []string
+}
+
+// String returns the "option" command for o.
+func (o Option) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "option name %s type %s", o.Name, o.Type)
+	switch o.Type {
+	case Check, Spin, Combo:
+		fmt.Fprintf(&sb, " default %s", o.Default)
+	case String:
+		d := o.Default
+		if d == "" {
+			d = "<empty>"
+		}
+		fmt.Fprintf(&sb, " default %s", d)
+	}
+	if o.Type == Spin {
+		fmt.Fprintf(&sb, " min %d max %d", o.Min, o.Max)
+	}
+	for _, v := range o.Vars {
+		fmt.Fprintf(&sb, " var %s", v)
+	}
+	return sb.String()
+}
+
+// optionKeywords separate the fields of an "option" command.
+var optionKeywords = []string{"name", "type", "default", "min", "max", "var"}
+
+// ParseOption parses an "option" command, like
+// "option name Hash type spin default 16 min 1 max 1024".
+func ParseOption(line string) (Option, error) {
+	var (
+		o      Option
+		key    string
+		values []string
+	)
+
+	cmd, args := cut(line)
+	if cmd != "option" {
+		return Option{}, fmt.Errorf("bad option: %q", line)
+	}
+
+	flush := func() error {
+		value := strings.Join(values, " ")
+		var err error
+		switch key {
+		case "name":
+			o.Name = value
+		case "type":
+			o.Type = OptionType(value)
+		case "default":
+			if value == "<empty>" {
+				value = ""
+			}
+			o.Default = value
+		case "min":
+			o.Min, err = strconv.Atoi(value)
+		case "max":
+			o.Max, err = strconv.Atoi(value)
+		case "var":
+			o.Vars = append(o.Vars, value)
+		}
+		values = nil
+		return err
+	}
+
+	for _, tok := range args {
+		// Option names may contain keywords, like "Use Default Book".
+		if slices.Contains(optionKeywords, tok) && !(key == "name" && tok != "type") {
+			if err := flush(); err != nil {
+				return Option{}, err
+			}
+			key = tok
+			continue
+		}
+		values = append(values, tok)
+	}
+	if err := flush(); err != nil {
+		return Option{}, err
+	}
+
+	if o.Name == "" {
+		return Option{}, fmt.Errorf("bad option: %q", line)
+	}
+	switch o.Type {
+	case Check, Spin, Combo, Button, String:
+	default:
+		return Option{}, fmt.Errorf("bad option type: %q", o.Type)
+	}
+
+	return o, nil
+}
+
+// Validate returns an error if value is invalid for o. Combo values are
+// matched case-insensitively, and button values are ignored.
+func (o Option) Validate(value string) error {
+	switch o.Type {
+	case Check:
+		if value != "true" && value != "false" {
+			return fmt.Errorf("%s: bad check value: %q", o.Name, value)
+		}
+	case Spin:
+		n, err := strconv.Atoi(value)
+		if err != nil || n < o.Min || n > o.Max {
+			return fmt.Errorf("%s: bad spin value: %q", o.Name, value)
+		}
+	case Combo:
+		for _, v := range o.Vars {
+			if strings.EqualFold(v, value) {
+				return nil
+			}
+		}
+		return fmt.Errorf("%s: bad combo value: %q", o.Name, value)
+	}
+	return nil
+}
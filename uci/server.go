@@ -0,0 +1,380 @@
+package uci
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/clfs/lento/core"
+	"github.com/clfs/lento/encoding/fen"
+)
+
+// An Engine searches positions for a [Server].
+//
+// The server never calls an engine's methods concurrently, and never calls
+// methods other than Stop while a search is running.
+type Engine interface {
+	// SetPosition sets the position to search: start, followed by moves. The
+	// moves are legal.
+	SetPosition(start core.Position, moves []core.Move)
+	// Go starts searching the current position within limits, and returns a
+	// channel that receives the result when the search ends. The search
+	// reports progress by calling info, from any goroutine, until then.
+	//
+	// The search may end before an infinite or ponder search is stopped; the
+	// server holds the result until the GUI allows it to be sent.
+	Go(limits Limits, info func(Info)) <-chan Result
+	// Stop asks the search started by the last call to Go to end as soon as
+	// possible. It may be called after the search has ended.
+	Stop()
+	// SetOption sets one of the server's options. The value is valid for the
+	// option, and is empty for buttons.
+	SetOption(name, value string) error
+}
+
+// A NewGamer is an [Engine] that wants to know when positions are from a new
+// game, as told by "ucinewgame".
+type NewGamer interface {
+	NewGame()
+}
+
+// A PonderHitter is an [Engine] that wants to know when the opponent played
+// the expected move during a ponder search, as told by "ponderhit". The
+// search continues as a normal search.
+type PonderHitter interface {
+	PonderHit()
+}
+
+// A Server exposes an [Engine] over UCI.
+//
+// The server tolerates malformed input: unknown tokens are skipped as the
+// protocol requires, and bad commands are reported with "info string" and
+// otherwise ignored. Commands that need the engine to be idle, like
+// "position" or "go", stop a running search first.
+type Server struct {
+	// Engine is the engine to expose.
+	Engine Engine
+	// Name and Author are reported with "id".
+	Name, Author string
+	// Options are the engine's options, reported with "option". Values set
+	// with "setoption" are validated against them before they reach the
+	// engine.
+	Options []Option
+	// Variants, if not empty, are the variants the engine plays, the default
+	// first. The server offers them with the UCI_Variant option and sets up
+	// positions in the chosen variant, which engines see as
+	// [core.Position.Variant].
+	Variants []core.Variant
+}
+
+// variantOption returns the UCI_Variant option, if the server offers one.
+func (s *Server) variantOption() (Option, bool) {
+	if len(s.Variants) == 0 {
+		return Option{}, false
+	}
+	o := Option{Name: "UCI_Variant", Type: Combo, Default: s.Variants[0].String()}
+	for _, v := range s.Variants {
+		o.Vars = append(o.Vars, v.String())
+	}
+	return o, true
+}
+
+// Serve reads commands from r and writes responses to w until "quit" or the
+// end of r, then stops any running search. It returns the first read or write
+// error.
+func (s *Server) Serve(r io.Reader, w io.Writer) error {
+	c := &conn{s: s, w: w}
+	if len(s.Variants) > 0 {
+		c.variant = s.Variants[0]
+	}
+	s.Engine.SetPosition(core.NewPosition(core.WithVariant(c.variant)), nil)
+
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		if !c.handle(sc.Text()) {
+			break
+		}
+	}
+	c.finish()
+
+	if err := sc.Err(); err != nil {
+		return err
+	}
+	return c.err
+}
+
+// A conn is the state of one call to [Server.Serve].
+type conn struct {
+	s *Server
+
+	mu  sync.Mutex // guards w and err
+	w   io.Writer
+	err error // the first write error
+
+	search  *search      // the running search, or nil
+	variant core.Variant // set with UCI_Variant
+}
+
+// A search is a running search.
+type search struct {
+	done        chan struct{} // closed after the result is written
+	release     chan struct{} // closed once the result may be written
+	releaseOnce sync.Once
+}
+
+func (sr *search) allow() {
+	sr.releaseOnce.Do(func() { close(sr.release) })
+}
+
+// commands are the commands the server understands, other than "quit".
+var commands = map[string]func(c *conn, args []string) error{
+	"uci":        (*conn).uci,
+	"debug":      func(*conn, []string) error { return nil },
+	"isready":    (*conn).isReady,
+	"setoption":  (*conn).setOption,
+	"register":   func(*conn, []string) error { return nil },
+	"ucinewgame": (*conn).newGame,
+	"position":   (*conn).position,
+	"go":         (*conn).goCmd,
+	"stop":       (*conn).stop,
+	"ponderhit":  (*conn).ponderHit,
+}
+
+// handle handles a command line, returning false if it was "quit".
+func (c *conn) handle(line string) bool {
+	fields := strings.Fields(line)
+
+	// Skip unknown tokens before the command, as the protocol requires.
+	for len(fields) > 0 && fields[0] != "quit" && commands[fields[0]] == nil {
+		fields = fields[1:]
+	}
+	if len(fields) == 0 {
+		if line = strings.TrimSpace(line); line != "" {
+			c.printf("info string unknown command: %q", line)
+		}
+		return true
+	}
+
+	if fields[0] == "quit" {
+		return false
+	}
+	if err := commands[fields[0]](c, fields[1:]); err != nil {
+		c.printf("info string %s: %v", fields[0], err)
+	}
+	return true
+}
+
+// printf writes a line of output.
+func (c *conn) printf(format string, args ...any) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err == nil {
+		_, c.err = fmt.Fprintf(c.w, format+"\n", args...)
+	}
+}
+
+// finish stops the running search, if any, and waits for its result to be
+// written.
+func (c *conn) finish() {
+	if c.search == nil {
+		return
+	}
+	c.s.Engine.Stop()
+	c.search.allow()
+	<-c.search.done
+	c.search = nil
+}
+
+func (c *conn) uci([]string) error {
+	if c.s.Name != "" {
+		c.printf("id name %s", c.s.Name)
+	}
+	if c.s.Author != "" {
+		c.printf("id author %s", c.s.Author)
+	}
+	for _, o := range c.s.Options {
+		c.printf("%v", o)
+	}
+	if o, ok := c.s.variantOption(); ok {
+		c.printf("%v", o)
+	}
+	c.printf("uciok")
+	return nil
+}
+
+func (c *conn) isReady([]string) error {
+	// Commands are handled in order, so everything before "isready" is done,
+	// even if a search is still running.
+	c.printf("readyok")
+	return nil
+}
+
+func (c *conn) setOption(args []string) error {
+	if len(args) < 2 || args[0] != "name" {
+		return fmt.Errorf("missing name")
+	}
+	name, value := strings.Join(args[1:], " "), ""
+	if i := slices.Index(args, "value"); i > 0 {
+		name, value = strings.Join(args[1:i], " "), strings.Join(args[i+1:], " ")
+	}
+
+	if o, ok := c.s.variantOption(); ok && strings.EqualFold(o.Name, name) {
+		if err := o.Validate(value); err != nil {
+			return err
+		}
+		v, err := core.ParseVariant(value)
+		if err != nil {
+			return err
+		}
+		c.finish()
+		c.variant = v
+		c.s.Engine.SetPosition(core.NewPosition(core.WithVariant(v)), nil)
+		return nil
+	}
+
+	i := slices.IndexFunc(c.s.Options, func(o Option) bool {
+		return strings.EqualFold(o.Name, name)
+	})
+	if i < 0 {
+		return fmt.Errorf("unknown option: %q", name)
+	}
+	o := c.s.Options[i]
+
+	switch o.Type {
+	case Button:
+		value = ""
+	case String:
+		if value == "<empty>" {
+			value = ""
+		}
+	}
+	if err := o.Validate(value); err != nil {
+		return err
+	}
+	if o.Type == Combo {
+		// Pass the value as the option spells it.
+		j := slices.IndexFunc(o.Vars, func(v string) bool {
+			return strings.EqualFold(v, value)
+		})
+		value = o.Vars[j]
+	}
+
+	c.finish()
+	return c.s.Engine.SetOption(o.Name, value)
+}
+
+func (c *conn) newGame([]string) error {
+	c.finish()
+	if ng, ok := c.s.Engine.(NewGamer); ok {
+		ng.NewGame()
+	}
+	return nil
+}
+
+func (c *conn) position(args []string) error {
+	start, moves, err := parsePosition(args, c.variant)
+	if err != nil {
+		return err
+	}
+	c.finish()
+	c.s.Engine.SetPosition(start, moves)
+	return nil
+}
+
+// parsePosition parses the arguments of a "position" command in variant v.
+func parsePosition(args []string, v core.Variant) (core.Position, []core.Move, error) {
+	var (
+		start core.Position
+		rest  []string
+	)
+	switch {
+	case len(args) > 0 && args[0] == "startpos":
+		start, rest = core.NewPosition(core.WithVariant(v)), args[1:]
+	case len(args) > 0 && args[0] == "fen":
+		i := slices.Index(args, "moves")
+		if i < 0 {
+			i = len(args)
+		}
+		p, err := fen.DecodeVariant(strings.Join(args[1:i], " "), v)
+		if err != nil {
+			return core.Position{}, nil, err
+		}
+		start, rest = p, args[i:]
+	default:
+		return core.Position{}, nil, fmt.Errorf("missing startpos or fen")
+	}
+
+	if len(rest) == 0 {
+		return start, nil, nil
+	}
+	if rest[0] != "moves" {
+		return core.Position{}, nil, fmt.Errorf("unexpected %q", rest[0])
+	}
+
+	var (
+		p     = start
+		moves []core.Move
+	)
+	for _, s := range rest[1:] {
+		m, err := core.ParseMove(s)
+		if err != nil {
+			return core.Position{}, nil, err
+		}
+		if !p.IsLegal(m) {
+			return core.Position{}, nil, fmt.Errorf("illegal move: %v", m)
+		}
+		p.Move(m)
+		moves = append(moves, m)
+	}
+	return start, moves, nil
+}
+
+func (c *conn) goCmd(args []string) error {
+	l, err := ParseLimits(strings.Join(append([]string{"go"}, args...), " "))
+	if err != nil {
+		return err
+	}
+	c.finish()
+
+	sr := &search{
+		done:    make(chan struct{}),
+		release: make(chan struct{}),
+	}
+	if !l.Infinite && !l.Ponder {
+		sr.allow()
+	}
+	c.search = sr
+
+	results := c.s.Engine.Go(l, func(info Info) {
+		c.printf("%s", info.Format())
+	})
+	go func() {
+		defer close(sr.done)
+		res := <-results
+		// The protocol forbids "bestmove" during infinite and ponder
+		// searches until "stop" or "ponderhit".
+		<-sr.release
+		c.printf("%v", res)
+	}()
+
+	return nil
+}
+
+func (c *conn) stop([]string) error {
+	c.finish()
+	return nil
+}
+
+func (c *conn) ponderHit([]string) error {
+	if c.search == nil {
+		return nil
+	}
+	if ph, ok := c.s.Engine.(PonderHitter); ok {
+		ph.PonderHit()
+	}
+	c.search.allow()
+	return nil
+}
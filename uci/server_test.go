@@ -0,0 +1,328 @@
+package uci
+
+import (
+	"bufio"
+	"io"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/clfs/lento/core"
+)
+
+// fakeEngine searches by playing the alphabetically first legal move at each
+// ply, reporting one info line per depth.
+type fakeEngine struct {
+	stop chan struct{} // closed by Stop; nil if no search is running
+
+	p          core.Position
+	options    []string // "name=value" for each SetOption call
+	newGames   int
+	ponderHits int
+}
+
+func (e *fakeEngine) SetPosition(start core.Position, moves []core.Move) {
+	e.p = start
+	for _, m := range moves {
+		e.p.Move(m)
+	}
+}
+
+func (e *fakeEngine) Go(l Limits, info func(Info)) <-chan Result {
+	stop := make(chan struct{})
+	e.stop = stop
+
+	depth := l.Depth
+	if depth == 0 {
+		depth = 2
+	}
+
+	results := make(chan Result, 1)
+	go func(p core.Position) {
+		var pv []core.Move
+		for d := 1; d <= depth; d++ {
+			moves := p.LegalMoves()
+			if len(moves) == 0 {
+				break
+			}
+			m := slices.MinFunc(moves, func(a, b core.Move) int {
+				return strings.Compare(a.String(), b.String())
+			})
+			pv = append(pv, m)
+			p.Move(m)
+			info(Info{Depth: d, Score: &Score{CP: 10 * d}, PV: slices.Clone(pv)})
+		}
+
+		if l.Infinite {
+			<-stop
+		}
+
+		var r Result
+		if len(pv) > 0 {
+			r.BestMove = pv[0]
+		}
+		if len(pv) > 1 {
+			r.Ponder = pv[1]
+		}
+		results <- r
+	}(e.p)
+	return results
+}
+
+func (e *fakeEngine) Stop() {
+	if e.stop != nil {
+		close(e.stop)
+		e.stop = nil
+	}
+}
+
+func (e *fakeEngine) SetOption(name, value string) error {
+	e.options = append(e.options, name+"="+value)
+	return nil
+}
+
+func (e *fakeEngine) NewGame() {
+	e.newGames++
+}
+
+func (e *fakeEngine) PonderHit() {
+	e.ponderHits++
+}
+
+var testOptions = []Option{
+	{Name: "Hash", Type: Spin, Default: "16", Min: 1, Max: 1024},
+	{Name: "Ponder", Type: Check, Default: "false"},
+	{Name: "Style", Type: Combo, Default: "Normal", Vars: []string{"Solid", "Normal", "Risky"}},
+	{Name: "Clear Hash", Type: Button},
+	{Name: "Book File", Type: String},
+}
+
+// runTranscript serves a fake engine with a scripted transcript. Lines
+// starting with "> " are sent to the server, and lines starting with "< " are
+// the expected responses, in order. After the transcript, the input is closed
+// and the server must not write anything else.
+func runTranscript(t *testing.T, transcript string) *fakeEngine {
+	t.Helper()
+	e := new(fakeEngine)
+	serveTranscript(t, &Server{Engine: e, Name: "Fake", Author: "Lento Authors", Options: testOptions}, transcript)
+	return e
+}
+
+// serveTranscript is like runTranscript, but serves s.
+func serveTranscript(t *testing.T, s *Server, transcript string) {
+	t.Helper()
+
+	inR, inW := io.Pipe()
+	outR, outW := io.Pipe()
+	errc := make(chan error, 1)
+	go func() {
+		err := s.Serve(inR, outW)
+		outW.Close()
+		inR.Close()
+		errc <- err
+	}()
+
+	out := bufio.NewReader(outR)
+	for _, line := range strings.Split(strings.TrimSpace(transcript), "\n") {
+		line = strings.TrimLeft(line, " \t")
+		switch {
+		case strings.HasPrefix(line, "> "):
+			if _, err := io.WriteString(inW, line[2:]+"\n"); err != nil {
+				t.Fatalf("write %q: %v", line[2:], err)
+			}
+		case strings.HasPrefix(line, "< "):
+			got, err := out.ReadString('\n')
+			if err != nil {
+				t.Fatalf("want %q, got error: %v", line[2:], err)
+			}
+			if got = strings.TrimSuffix(got, "\n"); got != line[2:] {
+				t.Fatalf("want %q, got %q", line[2:], got)
+			}
+		default:
+			t.Fatalf("bad transcript line: %q", line)
+		}
+	}
+
+	inW.Close()
+	rest, _ := io.ReadAll(out)
+	if err := <-errc; err != nil {
+		t.Fatal(err)
+	}
+	if len(rest) > 0 {
+		t.Fatalf("unexpected output: %q", rest)
+	}
+}
+
+func TestServer_Handshake(t *testing.T) {
+	runTranscript(t, `
+		> uci
+		< id name Fake
+		< id author Lento Authors
+		< option name Hash type spin default 16 min 1 max 1024
+		< option name Ponder type check default false
+		< option name Style type combo default Normal var Solid var Normal var Risky
+		< option name Clear Hash type button
+		< option name Book File type string default <empty>
+		< uciok
+		> isready
+		< readyok
+	`)
+}
+
+func TestServer_Go(t *testing.T) {
+	e := runTranscript(t, `
+		> ucinewgame
+		> position startpos moves e2e4
+		> go depth 2 wtime 1000 btime 1000
+		< info depth 1 score cp 10 pv a7a5
+		< info depth 2 score cp 20 pv a7a5 a2a3
+		< bestmove a7a5 ponder a2a3
+		> position fen 7k/8/8/8/8/8/8/K6R b - - 0 1 moves h8g8
+		> go depth 1
+		< info depth 1 score cp 10 pv a1a2
+		< bestmove a1a2
+		> position fen 7k/6Q1/6K1/8/8/8/8/8 b - - 0 1
+		> go
+		< bestmove (none)
+	`)
+	if e.newGames != 1 {
+		t.Errorf("want 1 new game, got %d", e.newGames)
+	}
+}
+
+func TestServer_Infinite(t *testing.T) {
+	runTranscript(t, `
+		> go infinite
+		< info depth 1 score cp 10 pv a2a3
+		< info depth 2 score cp 20 pv a2a3 a7a5
+		> isready
+		< readyok
+		> stop
+		< bestmove a2a3 ponder a7a5
+		> stop
+		> isready
+		< readyok
+	`)
+}
+
+func TestServer_Ponder(t *testing.T) {
+	e := runTranscript(t, `
+		> go ponder wtime 1000 btime 1000
+		< info depth 1 score cp 10 pv a2a3
+		< info depth 2 score cp 20 pv a2a3 a7a5
+		> isready
+		< readyok
+		> ponderhit
+		< bestmove a2a3 ponder a7a5
+	`)
+	if e.ponderHits != 1 {
+		t.Errorf("want 1 ponder hit, got %d", e.ponderHits)
+	}
+}
+
+func TestServer_InterruptedSearch(t *testing.T) {
+	// Commands that need an idle engine stop the search first.
+	runTranscript(t, `
+		> go infinite
+		< info depth 1 score cp 10 pv a2a3
+		< info depth 2 score cp 20 pv a2a3 a7a5
+		> position startpos moves a2a3
+		< bestmove a2a3 ponder a7a5
+		> go infinite depth 1
+		< info depth 1 score cp 10 pv a7a5
+		> setoption name Hash value 32
+		< bestmove a7a5
+	`)
+}
+
+func TestServer_Quit(t *testing.T) {
+	runTranscript(t, `
+		> go infinite depth 1
+		< info depth 1 score cp 10 pv a2a3
+		> quit
+		< bestmove a2a3
+	`)
+}
+
+func TestServer_SetOption(t *testing.T) {
+	e := runTranscript(t, `
+		> setoption name hash value 64
+		> setoption name Style value risky
+		> setoption name Clear Hash
+		> setoption name Book File value <empty>
+		> setoption name Book File value /tmp/my book.bin
+		> isready
+		< readyok
+	`)
+	want := []string{"Hash=64", "Style=Risky", "Clear Hash=", "Book File=", "Book File=/tmp/my book.bin"}
+	if !slices.Equal(want, e.options) {
+		t.Errorf("want %q, got %q", want, e.options)
+	}
+}
+
+func TestServer_Malformed(t *testing.T) {
+	e := runTranscript(t, `
+		> 
+		> joho debug on
+		> foo bar isready
+		< readyok
+		> blah
+		< info string unknown command: "blah"
+		> position
+		< info string position: missing startpos or fen
+		> position fen bad
+		< info string position: bad field count: 1
+		> position startpos moves e2e5
+		< info string position: illegal move: e2e5
+		> position startpos e2e4
+		< info string position: unexpected "e2e4"
+		> go depth x
+		< info string go: bad depth: "x"
+		> setoption Hash value 64
+		< info string setoption: missing name
+		> setoption name Nope value 1
+		< info string setoption: unknown option: "Nope"
+		> setoption name Hash value 0
+		< info string setoption: Hash: bad spin value: "0"
+		> setoption name Style value Wild
+		< info string setoption: Style: bad combo value: "Wild"
+		> go depth 1
+		< info depth 1 score cp 10 pv a2a3
+		< bestmove a2a3
+	`)
+	if len(e.options) > 0 {
+		t.Errorf("options set: %q", e.options)
+	}
+}
+
+func TestServer_Variant(t *testing.T) {
+	e := new(fakeEngine)
+	s := &Server{
+		Engine:   e,
+		Name:     "Fake",
+		Author:   "Lento Authors",
+		Variants: []core.Variant{core.Standard, core.Crazyhouse},
+	}
+	serveTranscript(t, s, `
+		> uci
+		< id name Fake
+		< id author Lento Authors
+		< option name UCI_Variant type combo default chess var chess var crazyhouse
+		< uciok
+		> setoption name UCI_Variant value Crazyhouse
+		> position fen 7k/8/8/8/8/8/8/K7[Q] w - - 0 1 moves Q@g7
+		> go depth 1
+		< info depth 1 score cp 10 pv h8g7
+		< bestmove h8g7
+		> setoption name UCI_Variant value atomic
+		< info string setoption: UCI_Variant: bad combo value: "atomic"
+		> isready
+		< readyok
+	`)
+	if e.p.Variant() != core.Crazyhouse {
+		t.Errorf("want crazyhouse, got %v", e.p.Variant())
+	}
+	if len(e.options) != 0 {
+		t.Errorf("want no engine options, got %q", e.options)
+	}
+}
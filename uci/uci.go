@@ -0,0 +1,388 @@
+// Package uci implements the Universal Chess Interface protocol.
+//
+// The package provides the protocol's messages as typed values, each of which
+// formats as and parses from its command line, and a [Server] that exposes
+// an [Engine] to a GUI. Package [github.com/clfs/lento/uci/client] drives
+// engines from the other side of the protocol.
+package uci
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/clfs/lento/core"
+)
+
+// cut splits a command line into its command and arguments, like "go" and
+// ["depth", "5"].
+func cut(line string) (string, []string) {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return "", nil
+	}
+	return fields[0], fields[1:]
+}
+
+// Limits constrain a search, as sent with "go". Zero fields are omitted.
+type Limits struct {
+	WTime, BTime time.Duration
+	WInc, BInc   time.Duration
+	MovesToGo    int
+	Depth        int
+	Nodes        int
+	Mate         int
+	MoveTime     time.Duration
+	Infinite     bool
+	Ponder       bool
+	// SearchMoves restricts the search to these moves.
+	SearchMoves []core.Move
+}
+
+// String returns the "go" command for l.
+func (l Limits) String() string {
+	var sb strings.Builder
+	sb.WriteString("go")
+	if l.Ponder {
+		sb.WriteString(" ponder")
+	}
+	for _, f := range []struct {
+		name string
+		d    time.Duration
+	}{
+		{"wtime", l.WTime},
+		{"btime", l.BTime},
+		{"winc", l.WInc},
+		{"binc", l.BInc},
+		{"movetime", l.MoveTime},
+	} {
+		if f.d > 0 {
+			fmt.Fprintf(&sb, " %s %d", f.name, f.d.Milliseconds())
+		}
+	}
+	for _, f := range []struct {
+		name string
+		n    int
+	}{
+		{"movestogo", l.MovesToGo},
+		{"depth", l.Depth},
+		{"nodes", l.Nodes},
+		{"mate", l.Mate},
+	} {
+		if f.n > 0 {
+			fmt.Fprintf(&sb, " %s %d", f.name, f.n)
+		}
+	}
+	if l.Infinite {
+		sb.WriteString(" infinite")
+	}
+	if len(l.SearchMoves) > 0 {
+		sb.WriteString(" searchmoves")
+		for _, m := range l.SearchMoves {
+			fmt.Fprintf(&sb, " %v", m)
+		}
+	}
+	return sb.String()
+}
+
+// ParseLimits parses a "go" command. Unknown tokens are ignored.
+func ParseLimits(line string) (Limits, error) {
+	var l Limits
+	cmd, args := cut(line)
+	if cmd != "go" {
+		return Limits{}, fmt.Errorf("bad go: %q", line)
+	}
+
+	for i := 0; i < len(args); i++ {
+		switch key := args[i]; key {
+		case "wtime", "btime", "winc", "binc", "movetime",
+			"movestogo", "depth", "nodes", "mate":
+			if i+1 >= len(args) {
+				return Limits{}, fmt.Errorf("missing value for %q", key)
+			}
+			i++
+			// Some GUIs send negative times once a player's flag falls.
+			n, err := strconv.Atoi(args[i])
+			if err != nil {
+				return Limits{}, fmt.Errorf("bad %s: %q", key, args[i])
+			}
+			d := time.Duration(max(n, 0)) * time.Millisecond
+			switch key {
+			case "wtime":
+				l.WTime = d
+			case "btime":
+				l.BTime = d
+			case "winc":
+				l.WInc = d
+			case "binc":
+				l.BInc = d
+			case "movetime":
+				l.MoveTime = d
+			case "movestogo":
+				l.MovesToGo = n
+			case "depth":
+				l.Depth = n
+			case "nodes":
+				l.Nodes = n
+			case "mate":
+				l.Mate = n
+			}
+		case "infinite":
+			l.Infinite = true
+		case "ponder":
+			l.Ponder = true
+		case "searchmoves":
+			for i+1 < len(args) {
+				m, err := core.ParseMove(args[i+1])
+				if err != nil {
+					break
+				}
+				l.SearchMoves = append(l.SearchMoves, m)
+				i++
+			}
+		}
+	}
+
+	return l, nil
+}
+
+// A Score is a search score from the engine's point of view.
+type Score struct {
+	// CP is the score in centipawns, if Mate is 0.
+	CP int
+	// Mate is the number of moves to mate, negative if the engine is getting
+	// mated, or 0 if no mate was found.
+	Mate int
+	// Lower and Upper are true if the score is only a lower or upper bound.
+	Lower, Upper bool
+}
+
+// String returns the arguments of "score" for s, like "cp 35" or "mate -2".
+func (s Score) String() string {
+	str := fmt.Sprintf("cp %d", s.CP)
+	if s.Mate != 0 {
+		str = fmt.Sprintf("mate %d", s.Mate)
+	}
+	switch {
+	case s.Lower:
+		str += " lowerbound"
+	case s.Upper:
+		str += " upperbound"
+	}
+	return str
+}
+
+// Info is search information, as reported with "info". Fields the engine
+// didn't report are zero.
+type Info struct {
+	Depth          int
+	SelDepth       int
+	Time           time.Duration
+	Nodes          int
+	NPS            int
+	MultiPV        int
+	Score          *Score // nil if not reported
+	PV             []core.Move
+	CurrMove       core.Move
+	CurrMoveNumber int
+	HashFull       int
+	TBHits         int
+	// String is free-form text reported with "info string".
+	String string
+}
+
+// Format returns the "info" command for info. It isn't named String, since
+// Info has a String field.
+func (info Info) Format() string {
+	var sb strings.Builder
+	sb.WriteString("info")
+	for _, f := range []struct {
+		name string
+		n    int
+	}{
+		{"depth", info.Depth},
+		{"seldepth", info.SelDepth},
+		{"multipv", info.MultiPV},
+	} {
+		if f.n > 0 {
+			fmt.Fprintf(&sb, " %s %d", f.name, f.n)
+		}
+	}
+	if info.Score != nil {
+		fmt.Fprintf(&sb, " score %v", *info.Score)
+	}
+	for _, f := range []struct {
+		name string
+		n    int
+	}{
+		{"nodes", info.Nodes},
+		{"nps", info.NPS},
+		{"hashfull", info.HashFull},
+		{"tbhits", info.TBHits},
+		{"time", int(info.Time.Milliseconds())},
+	} {
+		if f.n > 0 {
+			fmt.Fprintf(&sb, " %s %d", f.name, f.n)
+		}
+	}
+	if info.CurrMove != (core.Move{}) {
+		fmt.Fprintf(&sb, " currmove %v", info.CurrMove)
+	}
+	if info.CurrMoveNumber > 0 {
+		fmt.Fprintf(&sb, " currmovenumber %d", info.CurrMoveNumber)
+	}
+	if len(info.PV) > 0 {
+		sb.WriteString(" pv")
+		for _, m := range info.PV {
+			fmt.Fprintf(&sb, " %v", m)
+		}
+	}
+	// The string consumes the rest of the line, so it must come last.
+	if info.String != "" {
+		fmt.Fprintf(&sb, " string %s", info.String)
+	}
+	return sb.String()
+}
+
+// ParseInfo parses an "info" command. Unknown tokens, like "refutation" and
+// "currline", are ignored along with their values.
+func ParseInfo(line string) (Info, error) {
+	var info Info
+	cmd, args := cut(line)
+	if cmd != "info" {
+		return Info{}, fmt.Errorf("bad info: %q", line)
+	}
+
+	intArg := func(i int) (int, error) {
+		if i+1 >= len(args) {
+			return 0, fmt.Errorf("missing value for %q", args[i])
+		}
+		return strconv.Atoi(args[i+1])
+	}
+
+	for i := 0; i < len(args); i++ {
+		var (
+			n   int
+			err error
+		)
+		switch key := args[i]; key {
+		case "depth", "seldepth", "time", "nodes", "nps", "multipv",
+			"currmovenumber", "hashfull", "tbhits", "cpuload":
+			n, err = intArg(i)
+			i++
+			switch key {
+			case "depth":
+				info.Depth = n
+			case "seldepth":
+				info.SelDepth = n
+			case "time":
+				info.Time = time.Duration(n) * time.Millisecond
+			case "nodes":
+				info.Nodes = n
+			case "nps":
+				info.NPS = n
+			case "multipv":
+				info.MultiPV = n
+			case "currmovenumber":
+				info.CurrMoveNumber = n
+			case "hashfull":
+				info.HashFull = n
+			case "tbhits":
+				info.TBHits = n
+			}
+		case "score":
+			info.Score = new(Score)
+		scoreLoop:
+			for i+1 < len(args) && err == nil {
+				switch args[i+1] {
+				case "cp":
+					info.Score.CP, err = intArg(i + 1)
+					i += 2
+				case "mate":
+					info.Score.Mate, err = intArg(i + 1)
+					i += 2
+				case "lowerbound":
+					info.Score.Lower = true
+					i++
+				case "upperbound":
+					info.Score.Upper = true
+					i++
+				default:
+					break scoreLoop
+				}
+			}
+		case "currmove":
+			if i+1 < len(args) {
+				info.CurrMove, err = core.ParseMove(args[i+1])
+				i++
+			}
+		case "pv":
+			for i+1 < len(args) {
+				m, perr := core.ParseMove(args[i+1])
+				if perr != nil {
+					break
+				}
+				info.PV = append(info.PV, m)
+				i++
+			}
+		case "string":
+			info.String = strings.Join(args[i+1:], " ")
+			i = len(args)
+		}
+		if err != nil {
+			return Info{}, fmt.Errorf("bad info: %q: %v", line, err)
+		}
+	}
+
+	return info, nil
+}
+
+// A Result is the result of a search, as reported with "bestmove".
+type Result struct {
+	// BestMove is the move to play, or the null move if there are no legal
+	// moves.
+	BestMove core.Move
+	// Ponder is the move the engine expects in reply, or the null move if
+	// none was given.
+	Ponder core.Move
+}
+
+// String returns the "bestmove" command for r.
+func (r Result) String() string {
+	if r.BestMove == (core.Move{}) {
+		return "bestmove (none)"
+	}
+	if r.Ponder == (core.Move{}) {
+		return fmt.Sprintf("bestmove %v", r.BestMove)
+	}
+	return fmt.Sprintf("bestmove %v ponder %v", r.BestMove, r.Ponder)
+}
+
+// ParseResult parses a "bestmove" command.
+func ParseResult(line string) (Result, error) {
+	var r Result
+	cmd, args := cut(line)
+	if cmd != "bestmove" || len(args) == 0 {
+		return r, fmt.Errorf("bad bestmove: %q", line)
+	}
+
+	// Engines report "(none)" or "0000" when there are no legal moves.
+	if args[0] != "(none)" {
+		m, err := core.ParseMove(args[0])
+		if err != nil {
+			return r, err
+		}
+		r.BestMove = m
+	}
+
+	if len(args) == 3 && args[1] == "ponder" {
+		m, err := core.ParseMove(args[2])
+		if err != nil {
+			return r, err
+		}
+		r.Ponder = m
+	}
+
+	return r, nil
+}
@@ -0,0 +1,181 @@
+package uci
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/clfs/lento/core"
+)
+
+func TestParseInfo(t *testing.T) {
+	cases := map[string]Info{
+		"info depth 12 seldepth 20 multipv 2 score mate -3 lowerbound nodes 5000 nps 100000 hashfull 10 tbhits 2 time 50 pv e2e4 e7e5": {
+			Depth: 12, SelDepth: 20, MultiPV: 2,
+			Score: &Score{Mate: -3, Lower: true},
+			Nodes: 5000, NPS: 100000, HashFull: 10, TBHits: 2, Time: 50 * time.Millisecond,
+			PV: []core.Move{core.NewMove(core.E2, core.E4), core.NewMove(core.E7, core.E5)},
+		},
+		"info currmove e7e8q currmovenumber 3": {
+			CurrMove:       core.NewPromotionMove(core.E7, core.E8, core.Queen),
+			CurrMoveNumber: 3,
+		},
+		"info string hello   world": {String: "hello world"},
+		"info refutation d1h5 g6h5": {},
+	}
+	for s, want := range cases {
+		got, err := ParseInfo(s)
+		if err != nil {
+			t.Errorf("%q: error: %v", s, err)
+			continue
+		}
+		if !reflect.DeepEqual(want, got) {
+			t.Errorf("%q:\nwant %+v\ngot  %+v", s, want, got)
+		}
+	}
+
+	for _, s := range []string{"info depth", "info depth x", "info score cp", "depth 1"} {
+		if _, err := ParseInfo(s); err == nil {
+			t.Errorf("%q: no error", s)
+		}
+	}
+}
+
+func TestInfo_Format(t *testing.T) {
+	for _, s := range []string{
+		"info depth 12 seldepth 20 multipv 2 score mate -3 lowerbound nodes 5000 nps 100000 hashfull 10 tbhits 2 time 50 pv e2e4 e7e5",
+		"info depth 1 score cp 0 upperbound",
+		"info currmove e7e8q currmovenumber 3",
+		"info string hello world",
+	} {
+		info, err := ParseInfo(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := info.Format(); s != got {
+			t.Errorf("want %q, got %q", s, got)
+		}
+	}
+}
+
+func TestLimits_RoundTrip(t *testing.T) {
+	for _, s := range []string{
+		"go",
+		"go infinite",
+		"go ponder wtime 60000 btime 30000 winc 1000 movestogo 20 searchmoves e2e4 d2d4",
+		"go movetime 500 depth 10 nodes 100000 mate 3",
+	} {
+		l, err := ParseLimits(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := l.String(); s != got {
+			t.Errorf("want %q, got %q", s, got)
+		}
+	}
+}
+
+func TestParseLimits(t *testing.T) {
+	got, err := ParseLimits("go wtime -20 btime 500 bogus depth 3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Limits{BTime: 500 * time.Millisecond, Depth: 3}
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("want %+v, got %+v", want, got)
+	}
+
+	for _, s := range []string{"go depth", "go depth x", "stop"} {
+		if _, err := ParseLimits(s); err == nil {
+			t.Errorf("%q: no error", s)
+		}
+	}
+}
+
+func TestResult_RoundTrip(t *testing.T) {
+	for _, s := range []string{
+		"bestmove e2e4",
+		"bestmove e7e8q ponder d2d1n",
+		"bestmove (none)",
+	} {
+		r, err := ParseResult(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := r.String(); s != got {
+			t.Errorf("want %q, got %q", s, got)
+		}
+	}
+
+	for _, s := range []string{"bestmove", "bestmove e2", "info depth 1"} {
+		if _, err := ParseResult(s); err == nil {
+			t.Errorf("%q: no error", s)
+		}
+	}
+}
+
+func TestParseOption(t *testing.T) {
+	cases := map[string]Option{
+		"option name Hash type spin default 16 min 1 max 1024": {
+			Name: "Hash", Type: Spin, Default: "16", Min: 1, Max: 1024,
+		},
+		"option name Use Default Book type check default true": {
+			Name: "Use Default Book", Type: Check, Default: "true",
+		},
+		"option name Style type combo default Normal var Solid var Normal var Risky": {
+			Name: "Style", Type: Combo, Default: "Normal", Vars: []string{"Solid", "Normal", "Risky"},
+		},
+		"option name Clear Hash type button": {
+			Name: "Clear Hash", Type: Button,
+		},
+		"option name NalimovPath type string default <empty>": {
+			Name: "NalimovPath", Type: String,
+		},
+	}
+	for s, want := range cases {
+		got, err := ParseOption(s)
+		if err != nil {
+			t.Errorf("%q: error: %v", s, err)
+			continue
+		}
+		if !reflect.DeepEqual(want, got) {
+			t.Errorf("%q:\nwant %+v\ngot  %+v", s, want, got)
+		}
+		if s != got.String() {
+			t.Errorf("round trip: want %q, got %q", s, got.String())
+		}
+	}
+
+	for _, s := range []string{
+		"option type spin",
+		"option name Hash type integer",
+		"option name Hash type spin min x",
+		"name Hash type check",
+	} {
+		if _, err := ParseOption(s); err == nil {
+			t.Errorf("%q: no error", s)
+		}
+	}
+}
+
+func TestOption_Validate(t *testing.T) {
+	for _, tc := range []struct {
+		o     Option
+		value string
+		ok    bool
+	}{
+		{testOptions[0], "1024", true},
+		{testOptions[0], "1025", false},
+		{testOptions[0], "x", false},
+		{testOptions[1], "true", true},
+		{testOptions[1], "yes", false},
+		{testOptions[2], "SOLID", true},
+		{testOptions[2], "Wild", false},
+		{testOptions[3], "anything", true},
+		{testOptions[4], "", true},
+	} {
+		if err := tc.o.Validate(tc.value); (err == nil) != tc.ok {
+			t.Errorf("%s: %q: got error %v", tc.o.Name, tc.value, err)
+		}
+	}
+}
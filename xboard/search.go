@@ -0,0 +1,143 @@
+package xboard
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/clfs/lento/core"
+	"github.com/clfs/lento/encoding/san"
+	"github.com/clfs/lento/uci"
+)
+
+// A result is the result of a search.
+type result struct {
+	res      uci.Result
+	analysis bool
+}
+
+// think starts a search for the engine's move.
+func (c *conn) think() {
+	c.start(c.limits(), false)
+}
+
+// restartAnalysis starts analyzing the current position, if in analyze mode.
+func (c *conn) restartAnalysis() error {
+	if c.analyze {
+		c.start(uci.Limits{Infinite: true}, true)
+	}
+	return nil
+}
+
+// limits returns the limits for the engine's next move.
+func (c *conn) limits() uci.Limits {
+	l := uci.Limits{Depth: c.sd}
+	if c.st > 0 {
+		l.MoveTime = c.st
+		return l
+	}
+
+	l.WTime, l.BTime = c.own, c.opp
+	if c.color == core.Black {
+		l.WTime, l.BTime = l.BTime, l.WTime
+	}
+	l.WInc, l.BInc = c.inc, c.inc
+	if c.mps > 0 {
+		// The side to move has made this many moves since the start of the
+		// game, counting those before the starting position.
+		p := c.game.Position()
+		played := p.FullmoveNumber() - 1
+		l.MovesToGo = c.mps - played%c.mps
+	}
+	return l
+}
+
+// start starts a search of the current position. Analysis never moves, and
+// always reports its thinking.
+func (c *conn) start(l uci.Limits, analysis bool) {
+	c.mu.Lock()
+	c.last = uci.Info{}
+	c.mu.Unlock()
+
+	var (
+		p    = c.game.Position()
+		post = c.post || analysis
+	)
+	c.searching = true
+	results := c.s.Engine.Go(l, func(info uci.Info) {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		c.last = info
+		if post && info.Depth > 0 && len(info.PV) > 0 {
+			c.printfLocked("%s", thinking(p, info))
+		}
+	})
+	go func() {
+		c.results <- result{<-results, analysis}
+	}()
+}
+
+// stop stops the running search, if any, and waits for it to end. If discard
+// is true, the search's move isn't played.
+func (c *conn) stop(discard bool) {
+	if !c.searching {
+		return
+	}
+	c.s.Engine.Stop()
+	r := <-c.results
+	if discard {
+		c.searching = false
+		return
+	}
+	c.done(r)
+}
+
+// done handles the result of a search, playing the engine's move.
+func (c *conn) done(r result) {
+	c.searching = false
+	if r.analysis {
+		return
+	}
+
+	m := r.res.BestMove
+	if err := c.game.Move(m); err != nil {
+		c.printf("tellusererror illegal engine move: %v", m)
+		c.force = true
+		return
+	}
+	c.sync()
+	c.printf("move %v", m)
+	c.gameOver()
+}
+
+// mateScore is the score of a mate in 0 in thinking output. A mate in n moves
+// scores mateScore + n, and being mated in n moves scores -(mateScore + n).
+const mateScore = 100000
+
+// thinking formats info as a line of thinking output: the depth, the score in
+// centipawns, the time in centiseconds, the node count and the principal
+// variation in SAN, starting from p.
+func thinking(p core.Position, info uci.Info) string {
+	var score int
+	if s := info.Score; s != nil {
+		switch {
+		case s.Mate > 0:
+			score = mateScore + s.Mate
+		case s.Mate < 0:
+			score = -mateScore + s.Mate
+		default:
+			score = s.CP
+		}
+	}
+
+	var pv []string
+	for _, m := range info.PV {
+		if !p.IsLegal(m) {
+			break
+		}
+		pv = append(pv, san.Encode(p, m))
+		p.Move(m)
+	}
+
+	return fmt.Sprintf("%d %d %d %d %s",
+		info.Depth, score, info.Time.Milliseconds()/10, info.Nodes, strings.Join(pv, " "))
+}
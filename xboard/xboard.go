@@ -0,0 +1,582 @@
+// Package xboard implements version 2 of the Chess Engine Communication
+// Protocol (CECP), spoken by XBoard, WinBoard and many older tournament
+// tools.
+//
+// A [Server] exposes the same [uci.Engine] as [uci.Server], so one engine
+// can speak both protocols. Unlike UCI, CECP leaves the game to the engine:
+// the server keeps the game, decides when the engine moves, manages clocks
+// and detects the end of the game.
+package xboard
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/clfs/lento/core"
+	"github.com/clfs/lento/encoding/fen"
+	"github.com/clfs/lento/encoding/san"
+	"github.com/clfs/lento/uci"
+)
+
+// A Server exposes a [uci.Engine] over CECP.
+//
+// The server expects the "usermove" feature, so moves from the GUI are
+// prefixed with "usermove". Unknown and malformed commands are reported with
+// "Error" and otherwise ignored.
+type Server struct {
+	// Engine is the engine to expose.
+	Engine uci.Engine
+	// Name is reported with the "myname" feature.
+	Name string
+	// Options are the engine's options, reported with "option" features and
+	// set with the "option" command.
+	Options []uci.Option
+}
+
+// Serve reads commands from r and writes responses to w until "quit" or the
+// end of r, then stops any running search. It returns the first read or write
+// error.
+func (s *Server) Serve(r io.Reader, w io.Writer) error {
+	c := &conn{
+		s:       s,
+		w:       w,
+		results: make(chan result),
+	}
+	c.reset(core.NewPosition())
+
+	lines := make(chan string)
+	quit := make(chan struct{})
+	defer close(quit)
+
+	var readErr error
+	go func() {
+		defer close(lines)
+		sc := bufio.NewScanner(r)
+		for sc.Scan() {
+			select {
+			case lines <- sc.Text():
+			case <-quit:
+				return
+			}
+		}
+		readErr = sc.Err()
+	}()
+
+	for running := true; running; {
+		select {
+		case line, ok := <-lines:
+			running = ok && c.handle(line)
+		case r := <-c.results:
+			c.done(r)
+		}
+	}
+	c.stop(true)
+
+	// lines is closed once the reader is done; otherwise the reader is still
+	// blocked and hasn't failed.
+	select {
+	case _, ok := <-lines:
+		if !ok && readErr != nil {
+			return readErr
+		}
+	default:
+	}
+	return c.err
+}
+
+// A conn is the state of one call to [Server.Serve]. It is only used by the
+// goroutine running Serve, apart from output and search progress.
+type conn struct {
+	s *Server
+
+	mu   sync.Mutex // guards w, err and last
+	w    io.Writer
+	err  error    // the first write error
+	last uci.Info // the last progress reported by the running search
+
+	game    *core.Game
+	force   bool       // true if the engine only tracks moves
+	color   core.Color // the side the engine plays
+	analyze bool       // true in analyze mode
+	post    bool       // true if thinking output is on
+
+	// Time controls, in the terms of "level", "st" and "sd".
+	mps       int
+	base, inc time.Duration
+	st        time.Duration
+	sd        int
+	own, opp  time.Duration // remaining time on the engine's and opponent's clocks
+
+	searching bool
+	results   chan result // receives the result of each search
+}
+
+// commands are the commands the server understands, other than "quit".
+var commands = map[string]func(c *conn, args []string) error{
+	"xboard":    ignore,
+	"protover":  (*conn).protover,
+	"accepted":  ignore,
+	"rejected":  ignore,
+	"new":       (*conn).newGame,
+	"variant":   (*conn).variant,
+	"force":     (*conn).forceCmd,
+	"go":        (*conn).goCmd,
+	"playother": (*conn).playOther,
+	"level":     (*conn).level,
+	"st":        (*conn).stCmd,
+	"sd":        (*conn).sdCmd,
+	"time":      (*conn).timeCmd,
+	"otim":      (*conn).otim,
+	"usermove":  (*conn).userMove,
+	"?":         (*conn).moveNow,
+	"ping":      (*conn).ping,
+	"draw":      ignore,
+	"result":    (*conn).result,
+	"setboard":  (*conn).setBoard,
+	"undo":      (*conn).undo,
+	"remove":    (*conn).remove,
+	"hard":      ignore,
+	"easy":      ignore,
+	"post":      (*conn).postCmd,
+	"nopost":    (*conn).noPost,
+	"analyze":   (*conn).analyzeCmd,
+	"exit":      (*conn).exit,
+	".":         (*conn).status,
+	"option":    (*conn).option,
+	"computer":  ignore,
+	"name":      ignore,
+	"rating":    ignore,
+	"ics":       ignore,
+	"random":    ignore,
+}
+
+func ignore(*conn, []string) error { return nil }
+
+// handle handles a command line, returning false if it was "quit".
+func (c *conn) handle(line string) bool {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return true
+	}
+	if fields[0] == "quit" {
+		return false
+	}
+
+	cmd, ok := commands[fields[0]]
+	if !ok {
+		c.printf("Error (unknown command): %s", fields[0])
+		return true
+	}
+	if err := cmd(c, fields[1:]); err != nil {
+		c.printf("Error (%v): %s", err, line)
+	}
+	return true
+}
+
+// printf writes a line of output.
+func (c *conn) printf(format string, args ...any) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.printfLocked(format, args...)
+}
+
+func (c *conn) printfLocked(format string, args ...any) {
+	if c.err == nil {
+		_, c.err = fmt.Fprintf(c.w, format+"\n", args...)
+	}
+}
+
+// reset starts a new game from p.
+func (c *conn) reset(p core.Position) {
+	c.game = core.NewGame(p)
+	c.s.Engine.SetPosition(p, nil)
+}
+
+// sync tells the engine the current position.
+func (c *conn) sync() {
+	c.s.Engine.SetPosition(c.game.StartingPosition(), c.game.Moves())
+}
+
+func (c *conn) protover(args []string) error {
+	features := []string{
+		fmt.Sprintf("myname=%q", c.s.Name),
+		"setboard=1", "usermove=1", "ping=1", "playother=1", "analyze=1",
+		"time=1", "draw=0", "sigint=0", "sigterm=0", "reuse=1",
+		"colors=0", "san=0", `variants="normal"`,
+	}
+	for _, o := range c.s.Options {
+		features = append(features, fmt.Sprintf("option=%q", optionFeature(o)))
+	}
+	c.printf("feature %s", strings.Join(features, " "))
+	c.printf("feature done=1")
+	return nil
+}
+
+// optionFeature returns the value of the "option" feature for o, like
+// "Hash -spin 16 1 1024".
+func optionFeature(o uci.Option) string {
+	switch o.Type {
+	case uci.Check:
+		d := "0"
+		if o.Default == "true" {
+			d = "1"
+		}
+		return fmt.Sprintf("%s -check %s", o.Name, d)
+	case uci.Spin:
+		return fmt.Sprintf("%s -spin %s %d %d", o.Name, o.Default, o.Min, o.Max)
+	case uci.Combo:
+		vars := make([]string, len(o.Vars))
+		for i, v := range o.Vars {
+			if v == o.Default {
+				v = "*" + v
+			}
+			vars[i] = v
+		}
+		return fmt.Sprintf("%s -combo %s", o.Name, strings.Join(vars, " /// "))
+	case uci.Button:
+		return fmt.Sprintf("%s -button", o.Name)
+	default:
+		return fmt.Sprintf("%s -string %s", o.Name, o.Default)
+	}
+}
+
+func (c *conn) newGame([]string) error {
+	c.stop(true)
+	c.reset(core.NewPosition())
+	c.force, c.color = false, core.Black
+	c.own, c.opp = c.base, c.base
+	c.sd = 0
+	if ng, ok := c.s.Engine.(uci.NewGamer); ok {
+		ng.NewGame()
+	}
+	return c.restartAnalysis()
+}
+
+func (c *conn) variant(args []string) error {
+	if len(args) != 1 || args[0] != "normal" {
+		return fmt.Errorf("unsupported variant")
+	}
+	return nil
+}
+
+func (c *conn) forceCmd([]string) error {
+	c.stop(true)
+	c.force = true
+	return nil
+}
+
+func (c *conn) goCmd([]string) error {
+	c.stop(true)
+	c.force = false
+	p := c.game.Position()
+	c.color = p.SideToMove()
+	if !c.gameOver() {
+		c.think()
+	}
+	return nil
+}
+
+func (c *conn) playOther([]string) error {
+	c.stop(true)
+	c.force = false
+	p := c.game.Position()
+	c.color = !p.SideToMove()
+	return nil
+}
+
+func (c *conn) level(args []string) error {
+	if len(args) != 3 {
+		return fmt.Errorf("bad level")
+	}
+	mps, err := strconv.Atoi(args[0])
+	if err != nil || mps < 0 {
+		return fmt.Errorf("bad moves per session")
+	}
+	base, err := parseBase(args[1])
+	if err != nil {
+		return err
+	}
+	inc, err := strconv.ParseFloat(args[2], 64)
+	if err != nil || inc < 0 {
+		return fmt.Errorf("bad increment")
+	}
+	c.mps, c.base, c.inc = mps, base, time.Duration(inc*float64(time.Second))
+	c.own, c.opp = c.base, c.base
+	c.st = 0
+	return nil
+}
+
+// parseBase parses a base time in minutes, like "5" or "0:30".
+func parseBase(s string) (time.Duration, error) {
+	mins, secs, found := strings.Cut(s, ":")
+	m, err := strconv.Atoi(mins)
+	if err != nil || m < 0 {
+		return 0, fmt.Errorf("bad base time")
+	}
+	d := time.Duration(m) * time.Minute
+	if found {
+		n, err := strconv.Atoi(secs)
+		if err != nil || n < 0 || n >= 60 {
+			return 0, fmt.Errorf("bad base time")
+		}
+		d += time.Duration(n) * time.Second
+	}
+	return d, nil
+}
+
+func (c *conn) stCmd(args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("bad time")
+	}
+	n, err := strconv.ParseFloat(args[0], 64)
+	if err != nil || n <= 0 {
+		return fmt.Errorf("bad time")
+	}
+	c.st = time.Duration(n * float64(time.Second))
+	return nil
+}
+
+func (c *conn) sdCmd(args []string) error {
+	n, err := intArg(args)
+	if err != nil || n <= 0 {
+		return fmt.Errorf("bad depth")
+	}
+	c.sd = n
+	return nil
+}
+
+func (c *conn) timeCmd(args []string) error {
+	n, err := intArg(args)
+	if err != nil {
+		return fmt.Errorf("bad time")
+	}
+	c.own = centiseconds(n)
+	return nil
+}
+
+func (c *conn) otim(args []string) error {
+	n, err := intArg(args)
+	if err != nil {
+		return fmt.Errorf("bad time")
+	}
+	c.opp = centiseconds(n)
+	return nil
+}
+
+// centiseconds converts a clock reading, which may be negative once a flag
+// falls, to a duration.
+func centiseconds(n int) time.Duration {
+	return time.Duration(max(n, 0)) * 10 * time.Millisecond
+}
+
+func intArg(args []string) (int, error) {
+	if len(args) != 1 {
+		return 0, fmt.Errorf("want 1 argument, got %d", len(args))
+	}
+	return strconv.Atoi(args[0])
+}
+
+func (c *conn) userMove(args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("bad move")
+	}
+	p := c.game.Position()
+	m, err := parseMove(p, args[0])
+	if err != nil {
+		c.printf("Illegal move: %s", args[0])
+		return nil
+	}
+
+	c.stop(true)
+	if err := c.game.Move(m); err != nil {
+		return err
+	}
+	c.sync()
+
+	if c.analyze {
+		return c.restartAnalysis()
+	}
+	if c.gameOver() {
+		return nil
+	}
+	p = c.game.Position()
+	if !c.force && c.color == p.SideToMove() {
+		c.think()
+	}
+	return nil
+}
+
+// parseMove parses a legal move in coordinate notation, like "e7e8q", or
+// leniently in SAN.
+func parseMove(p core.Position, s string) (core.Move, error) {
+	if m, err := core.ParseMove(s); err == nil && p.IsLegal(m) {
+		return m, nil
+	}
+	return san.Decode(p, s)
+}
+
+func (c *conn) moveNow([]string) error {
+	if c.searching && !c.analyze {
+		c.s.Engine.Stop()
+	}
+	return nil
+}
+
+func (c *conn) ping(args []string) error {
+	c.printf("pong %s", strings.Join(args, " "))
+	return nil
+}
+
+func (c *conn) result([]string) error {
+	c.stop(true)
+	c.force = true
+	return nil
+}
+
+func (c *conn) setBoard(args []string) error {
+	p, err := fen.Decode(strings.Join(args, " "))
+	if err != nil {
+		return fmt.Errorf("bad position")
+	}
+	c.stop(true)
+	c.reset(p)
+	return c.restartAnalysis()
+}
+
+func (c *conn) undo([]string) error {
+	return c.takeBack(1)
+}
+
+func (c *conn) remove([]string) error {
+	return c.takeBack(2)
+}
+
+// takeBack takes back n moves.
+func (c *conn) takeBack(n int) error {
+	if len(c.game.Moves()) < n {
+		return fmt.Errorf("no move to take back")
+	}
+	c.stop(true)
+	for range n {
+		c.game.Undo()
+	}
+	c.sync()
+	return c.restartAnalysis()
+}
+
+func (c *conn) postCmd([]string) error {
+	c.post = true
+	return nil
+}
+
+func (c *conn) noPost([]string) error {
+	c.post = false
+	return nil
+}
+
+func (c *conn) analyzeCmd([]string) error {
+	c.stop(true)
+	c.analyze = true
+	return c.restartAnalysis()
+}
+
+func (c *conn) exit([]string) error {
+	c.stop(true)
+	c.analyze = false
+	return nil
+}
+
+// status reports the progress of the analysis, in the "stat01" format.
+func (c *conn) status([]string) error {
+	if !c.analyze {
+		return nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	p := c.game.Position()
+	total := len(p.LegalMoves())
+	left := 0
+	if c.last.CurrMoveNumber > 0 {
+		left = max(total-c.last.CurrMoveNumber, 0)
+	}
+	move := ""
+	if c.last.CurrMove != (core.Move{}) && p.IsLegal(c.last.CurrMove) {
+		move = " " + san.Encode(p, c.last.CurrMove)
+	}
+	c.printfLocked("stat01: %d %d %d %d %d%s",
+		c.last.Time.Milliseconds()/10, c.last.Nodes, c.last.Depth, left, total, move)
+	return nil
+}
+
+func (c *conn) option(args []string) error {
+	name, value, _ := strings.Cut(strings.Join(args, " "), "=")
+	i := slices.IndexFunc(c.s.Options, func(o uci.Option) bool {
+		return strings.EqualFold(o.Name, name)
+	})
+	if i < 0 {
+		return fmt.Errorf("unknown option")
+	}
+	o := c.s.Options[i]
+
+	switch o.Type {
+	case uci.Check:
+		switch value {
+		case "1":
+			value = "true"
+		case "0":
+			value = "false"
+		}
+	case uci.Button:
+		value = ""
+	}
+	if err := o.Validate(value); err != nil {
+		return fmt.Errorf("bad option value")
+	}
+	if o.Type == uci.Combo {
+		// Pass the value as the option spells it.
+		j := slices.IndexFunc(o.Vars, func(v string) bool {
+			return strings.EqualFold(v, value)
+		})
+		value = o.Vars[j]
+	}
+
+	c.stop(true)
+	if err := c.s.Engine.SetOption(o.Name, value); err != nil {
+		return err
+	}
+	return c.restartAnalysis()
+}
+
+// gameOver reports the result and returns true if the game has ended.
+func (c *conn) gameOver() bool {
+	r, t := c.game.Result()
+	if r == core.NoResult {
+		return false
+	}
+
+	var comment string
+	switch t {
+	case core.Checkmate:
+		comment = "White mates"
+		if r == core.BlackWins {
+			comment = "Black mates"
+		}
+	case core.Stalemate:
+		comment = "Stalemate"
+	case core.FiftyMoveRule:
+		comment = "Draw by fifty-move rule"
+	case core.ThreefoldRepetition:
+		comment = "Draw by repetition"
+	case core.InsufficientMaterial:
+		comment = "Insufficient material"
+	}
+	c.printf("%v {%s}", r, comment)
+	return true
+}
@@ -0,0 +1,364 @@
+package xboard
+
+import (
+	"bufio"
+	"io"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/clfs/lento/core"
+	"github.com/clfs/lento/encoding/fen"
+	"github.com/clfs/lento/uci"
+)
+
+// fakeEngine searches by playing the alphabetically first legal move at each
+// ply, reporting one info line per depth. Infinite and fixed-time searches
+// run until stopped.
+type fakeEngine struct {
+	stop chan struct{} // closed by Stop; nil if no search is running
+
+	p        core.Position
+	limits   uci.Limits // of the last search
+	options  []string   // "name=value" for each SetOption call
+	newGames int
+}
+
+func (e *fakeEngine) SetPosition(start core.Position, moves []core.Move) {
+	e.p = start
+	for _, m := range moves {
+		e.p.Move(m)
+	}
+}
+
+func (e *fakeEngine) Go(l uci.Limits, info func(uci.Info)) <-chan uci.Result {
+	stop := make(chan struct{})
+	e.stop = stop
+	e.limits = l
+
+	depth := l.Depth
+	if depth == 0 {
+		depth = 2
+	}
+
+	results := make(chan uci.Result, 1)
+	go func(p core.Position) {
+		var pv []core.Move
+		for d := 1; d <= depth; d++ {
+			moves := p.LegalMoves()
+			if len(moves) == 0 {
+				break
+			}
+			m := slices.MinFunc(moves, func(a, b core.Move) int {
+				return strings.Compare(a.String(), b.String())
+			})
+			pv = append(pv, m)
+			p.Move(m)
+			info(uci.Info{Depth: d, Score: &uci.Score{CP: 10 * d}, Nodes: 100 * d, PV: slices.Clone(pv)})
+		}
+
+		if l.Infinite || l.MoveTime > 0 {
+			<-stop
+		}
+
+		var r uci.Result
+		if len(pv) > 0 {
+			r.BestMove = pv[0]
+		}
+		results <- r
+	}(e.p)
+	return results
+}
+
+func (e *fakeEngine) Stop() {
+	if e.stop != nil {
+		close(e.stop)
+		e.stop = nil
+	}
+}
+
+func (e *fakeEngine) SetOption(name, value string) error {
+	e.options = append(e.options, name+"="+value)
+	return nil
+}
+
+func (e *fakeEngine) NewGame() {
+	e.newGames++
+}
+
+var testOptions = []uci.Option{
+	{Name: "Hash", Type: uci.Spin, Default: "16", Min: 1, Max: 1024},
+	{Name: "Ponder", Type: uci.Check, Default: "false"},
+	{Name: "Style", Type: uci.Combo, Default: "Normal", Vars: []string{"Solid", "Normal", "Risky"}},
+	{Name: "Clear Hash", Type: uci.Button},
+	{Name: "Book File", Type: uci.String},
+}
+
+// runTranscript serves a fake engine with a scripted transcript. Lines
+// starting with "> " are sent to the server, and lines starting with "< " are
+// the expected responses, in order. After the transcript, the input is closed
+// and the server must not write anything else.
+func runTranscript(t *testing.T, transcript string) *fakeEngine {
+	t.Helper()
+
+	e := new(fakeEngine)
+	s := &Server{Engine: e, Name: "Fake 1.0", Options: testOptions}
+
+	inR, inW := io.Pipe()
+	outR, outW := io.Pipe()
+	errc := make(chan error, 1)
+	go func() {
+		err := s.Serve(inR, outW)
+		outW.Close()
+		errc <- err
+	}()
+
+	out := bufio.NewReader(outR)
+	for _, line := range strings.Split(strings.TrimSpace(transcript), "\n") {
+		line = strings.TrimLeft(line, " \t")
+		switch {
+		case strings.HasPrefix(line, "> "):
+			if _, err := io.WriteString(inW, line[2:]+"\n"); err != nil {
+				t.Fatalf("write %q: %v", line[2:], err)
+			}
+		case strings.HasPrefix(line, "< "):
+			got, err := out.ReadString('\n')
+			if err != nil {
+				t.Fatalf("want %q, got error: %v", line[2:], err)
+			}
+			if got = strings.TrimSuffix(got, "\n"); got != line[2:] {
+				t.Fatalf("want %q, got %q", line[2:], got)
+			}
+		default:
+			t.Fatalf("bad transcript line: %q", line)
+		}
+	}
+
+	inW.Close()
+	rest, _ := io.ReadAll(out)
+	if err := <-errc; err != nil {
+		t.Fatal(err)
+	}
+	if len(rest) > 0 {
+		t.Fatalf("unexpected output: %q", rest)
+	}
+	return e
+}
+
+func TestServer_Handshake(t *testing.T) {
+	runTranscript(t, `
+		> xboard
+		> protover 2
+		< feature myname="Fake 1.0" setboard=1 usermove=1 ping=1 playother=1 analyze=1 time=1 draw=0 sigint=0 sigterm=0 reuse=1 colors=0 san=0 variants="normal" option="Hash -spin 16 1 1024" option="Ponder -check 0" option="Style -combo Solid /// *Normal /// Risky" option="Clear Hash -button" option="Book File -string "
+		< feature done=1
+		> accepted usermove
+		> ping 1
+		< pong 1
+	`)
+}
+
+func TestServer_Game(t *testing.T) {
+	e := runTranscript(t, `
+		> new
+		> level 40 5 2
+		> post
+		> time 12000
+		> otim 9000
+		> usermove e2e4
+		< 1 10 0 100 a5
+		< 2 20 0 200 a5 a3
+		< move a7a5
+		> usermove e2e5
+		< Illegal move: e2e5
+		> nopost
+		> usermove Nf3
+		< move a5a4
+		> result 1/2-1/2 {Draw}
+		> usermove d2d4
+		> ping 2
+		< pong 2
+	`)
+	if e.newGames != 1 {
+		t.Errorf("want 1 new game, got %d", e.newGames)
+	}
+	want := uci.Limits{
+		WTime: 90 * time.Second, BTime: 120 * time.Second,
+		WInc: 2 * time.Second, BInc: 2 * time.Second,
+		MovesToGo: 39,
+	}
+	if !reflect.DeepEqual(want, e.limits) {
+		t.Errorf("want %+v, got %+v", want, e.limits)
+	}
+	if got, want := fen.Encode(e.p), "rnbqkbnr/1ppppppp/8/8/p2PP3/5N2/PPP2PPP/RNBQKB1R b KQkq d3 0 3"; want != got {
+		t.Errorf("want %q, got %q", want, got)
+	}
+}
+
+// After setboard with Black to move, White has made as many moves as Black.
+func TestServer_MovesToGoAfterSetBoard(t *testing.T) {
+	e := runTranscript(t, `
+		> new
+		> level 40 5 0
+		> force
+		> setboard rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq - 0 1
+		> usermove e7e5
+		> go
+		< move a2a3
+		> ping 1
+		< pong 1
+	`)
+	if e.limits.MovesToGo != 39 {
+		t.Errorf("want 39 moves to go, got %d", e.limits.MovesToGo)
+	}
+}
+
+func TestServer_ForceAndPlayOther(t *testing.T) {
+	runTranscript(t, `
+		> new
+		> force
+		> usermove e2e4
+		> usermove e7e5
+		> go
+		< move a2a3
+		> playother
+		> usermove a7a6
+		< move a1a2
+		> force
+		> usermove a6a5
+		> ping 1
+		< pong 1
+	`)
+}
+
+func TestServer_TakeBack(t *testing.T) {
+	e := runTranscript(t, `
+		> new
+		> force
+		> usermove e2e4
+		> usermove e7e5
+		> usermove g1f3
+		> undo
+		> remove
+		> undo
+		< Error (no move to take back): undo
+	`)
+	if got := fen.Encode(e.p); got != fen.Encode(core.NewPosition()) {
+		t.Errorf("want start position, got %q", got)
+	}
+}
+
+func TestServer_SetBoard(t *testing.T) {
+	runTranscript(t, `
+		> new
+		> setboard 7k/8/5K2/8/8/8/8/6Q1 w - - 0 1
+		> usermove g1g7
+		< 1-0 {White mates}
+		> setboard 7k/8/5K2/8/8/8/8/6Q1 w - - 0 1
+		> usermove Qg1-g2
+		< Illegal move: Qg1-g2
+		> usermove Qf2
+		< move h8g8
+		> setboard 7k/8/8/8/8/8/8/K7 b - - 0 1
+		> go
+		< 1/2-1/2 {Insufficient material}
+	`)
+}
+
+func TestServer_Analyze(t *testing.T) {
+	runTranscript(t, `
+		> new
+		> force
+		> setboard 7k/8/8/8/8/8/8/K5R1 w - - 0 1
+		> analyze
+		< 1 10 0 100 Ka2
+		< 2 20 0 200 Ka2 Kh7
+		> .
+		< stat01: 0 200 2 0 16
+		> usermove a1b1
+		< 1 10 0 100 Kh7
+		< 2 20 0 200 Kh7 Ka1
+		> undo
+		< 1 10 0 100 Ka2
+		< 2 20 0 200 Ka2 Kh7
+		> exit
+		> .
+		> ping 1
+		< pong 1
+	`)
+}
+
+func TestServer_MoveNow(t *testing.T) {
+	e := runTranscript(t, `
+		> new
+		> st 30
+		> sd 1
+		> force
+		> go
+		> ?
+		< move a2a3
+	`)
+	want := uci.Limits{MoveTime: 30 * time.Second, Depth: 1}
+	if !reflect.DeepEqual(want, e.limits) {
+		t.Errorf("want %+v, got %+v", want, e.limits)
+	}
+}
+
+func TestServer_Option(t *testing.T) {
+	e := runTranscript(t, `
+		> option Hash=64
+		> option style=risky
+		> option Ponder=1
+		> option Clear Hash
+		> option Book File=/tmp/my book.bin
+		> option Hash=0
+		< Error (bad option value): option Hash=0
+		> option Threads=2
+		< Error (unknown option): option Threads=2
+	`)
+	want := []string{"Hash=64", "Style=Risky", "Ponder=true", "Clear Hash=", "Book File=/tmp/my book.bin"}
+	if !slices.Equal(want, e.options) {
+		t.Errorf("want %q, got %q", want, e.options)
+	}
+}
+
+func TestServer_Malformed(t *testing.T) {
+	runTranscript(t, `
+		> 
+		> foo
+		< Error (unknown command): foo
+		> level 40
+		< Error (bad level): level 40
+		> level 40 x 0
+		< Error (bad base time): level 40 x 0
+		> sd x
+		< Error (bad depth): sd x
+		> time
+		< Error (bad time): time
+		> setboard junk
+		< Error (bad position): setboard junk
+		> variant crazyhouse
+		< Error (unsupported variant): variant crazyhouse
+		> usermove
+		< Error (bad move): usermove
+	`)
+}
+
+func TestParseBase(t *testing.T) {
+	for s, want := range map[string]time.Duration{
+		"5":    5 * time.Minute,
+		"0:30": 30 * time.Second,
+		"2:05": 2*time.Minute + 5*time.Second,
+	} {
+		got, err := parseBase(s)
+		if err != nil || got != want {
+			t.Errorf("%q: want %v, got %v, %v", s, want, got, err)
+		}
+	}
+	for _, s := range []string{"", "x", "1:60", "-1"} {
+		if _, err := parseBase(s); err == nil {
+			t.Errorf("%q: no error", s)
+		}
+	}
+}